@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/input"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// exchangeFlags là danh sách exchange truyền qua flag -exchange name=path,
+// có thể lặp lại nhiều lần.
+type exchangeFlags []string
+
+func (f *exchangeFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *exchangeFlags) Set(value string) error {
+	if name, path, ok := strings.Cut(value, "="); !ok || name == "" || path == "" {
+		return fmt.Errorf("expected name=path, got %q", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "địa chỉ lắng nghe của HTTP server")
+	timeout := flag.Duration("timeout", 2*time.Second,
+		"thời gian tìm đường tối đa của mỗi request, hết thời gian thì trả về "+
+			"route tốt nhất tìm được tới lúc đó, 0 là không giới hạn")
+	var exchanges exchangeFlags
+	flag.Var(&exchanges, "exchange",
+		"exchange và file order book theo định dạng của expanded problem, "+
+			"dạng name=path, có thể lặp lại (mặc định local=test/expanded_input.txt)")
+	flag.Parse()
+	if len(exchanges) == 0 {
+		exchanges = exchangeFlags{"local=test/expanded_input.txt"}
+	}
+
+	// Mỗi exchange được tải ở goroutine riêng, query không chờ exchange
+	// đang tải
+	registry := route.NewRegistry()
+	for _, exchange := range exchanges {
+		name, path, _ := strings.Cut(exchange, "=")
+		registry.Register(name, route.NewGraph())
+		go func() {
+			graph, err := ReadOrderBooks(path, name)
+			if err != nil {
+				log.Printf("Lỗi đọc file %s của exchange %s: %v", path, name, err)
+				return
+			}
+			registry.Register(name, graph)
+			registry.SetReady(name, true)
+			log.Printf("exchange %s loaded from %s", name, path)
+		}()
+	}
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, newServer(registry, *timeout).routes()))
+}
+
+// ReadOrderBooks đọc các cặp giao dịch từ file (định dạng theo phần mở rộng,
+// xem input.Read) và tạo đồ thị, các cạnh được gắn với exchange venue nếu cặp
+// không có venue riêng. Query trong file được bỏ qua, query được gửi qua API.
+func ReadOrderBooks(filePath, venue string) (route.Graph, error) {
+	in, err := input.Read(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return route.NewGraphWithEdges(in.Edges(venue)), nil
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// swapSide là route và giá tốt nhất của một chiều giao dịch trong response.
+// Giá là chuỗi số thập phân chính xác, không làm tròn thành float. Partial là
+// true nếu route được tìm chưa xong khi hết thời gian tìm đường. Error khác
+// nil, và các trường còn lại rỗng, nếu chiều này không tìm được route trong
+// khi chiều còn lại tìm được.
+type swapSide struct {
+	Exchange string           `json:"exchange,omitempty"`
+	Route    []string         `json:"route,omitempty"`
+	Price    *decimal.Decimal `json:"price,omitempty"`
+	Partial  bool             `json:"partial,omitempty"`
+	Error    *errorBody       `json:"error,omitempty"`
+}
+
+// exchangeSide là kết quả một chiều giao dịch trên một exchange, có Error nếu
+// exchange không tìm được route hoặc còn đang tải.
+type exchangeSide struct {
+	Route   []string         `json:"route,omitempty"`
+	Price   *decimal.Decimal `json:"price,omitempty"`
+	Partial bool             `json:"partial,omitempty"`
+	Error   *errorBody       `json:"error,omitempty"`
+}
+
+// exchangeQuote là kết quả của một exchange, dùng để so sánh giữa các exchange.
+type exchangeQuote struct {
+	Exchange string       `json:"exchange"`
+	Bid      exchangeSide `json:"bid"`
+	Ask      exchangeSide `json:"ask"`
+}
+
+// bestSwapResponse là response của GET /best-swap. Bid, Ask là kết quả tốt
+// nhất trên tất cả exchange, Exchanges là kết quả của từng exchange.
+type bestSwapResponse struct {
+	Bid       swapSide        `json:"bid"`
+	Ask       swapSide        `json:"ask"`
+	Exchanges []exchangeQuote `json:"exchanges"`
+}
+
+// errorBody là nội dung lỗi trả về cho client.
+//   - Code: mã lỗi ổn định để client xử lý, ví dụ no_route
+//   - Message: mô tả lỗi
+//   - Cycle: chu trình token khi Code là arbitrage_loop, nếu truy vết được
+//   - MaxAmount, Route: lượng token tối đa giao dịch được và route tương ứng
+//     khi Code là insufficient_liquidity, nếu tính được trước khi hết thời gian
+type errorBody struct {
+	Code      string           `json:"code"`
+	Message   string           `json:"message"`
+	Cycle     []string         `json:"cycle,omitempty"`
+	MaxAmount *decimal.Decimal `json:"max_amount,omitempty"`
+	Route     []string         `json:"route,omitempty"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+// server phục vụ Trade Route API từ đồ thị của các exchange trong bộ nhớ.
+// timeout là thời gian tìm đường tối đa của mỗi request, 0 là không giới hạn.
+type server struct {
+	registry *route.Registry
+	timeout  time.Duration
+}
+
+func newServer(registry *route.Registry, timeout time.Duration) *server {
+	return &server{registry: registry, timeout: timeout}
+}
+
+// routes trả về handler của các endpoint.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/best-swap", s.handleBestSwap)
+	return mux
+}
+
+// handleBestSwap xử lý GET /best-swap?starting_token=KNC&target_token=ETH&amount=100,
+// trả về route và giá tốt nhất khi bán (bid) và mua (ask) amount starting
+// token theo target token trên tất cả exchange, kèm kết quả của từng exchange.
+// Các tham số không bắt buộc giới hạn route, xem queryOptions.
+// Việc tìm đường dừng lại khi client ngắt kết nối hoặc hết s.timeout, khi đó
+// route tốt nhất tìm được tới lúc đó được trả về với partial = true.
+//
+// Hai chiều được tìm độc lập: nếu chỉ một chiều lỗi, response vẫn có status
+// 200 với route của chiều còn lại và lỗi của chiều bị lỗi trong trường error
+// của chiều đó (cùng mã lỗi như bên dưới). Status lỗi chỉ được trả về khi cả
+// hai chiều đều lỗi, với lỗi của chiều bid.
+//
+// Mã lỗi:
+//   - 400 invalid_request: thiếu hoặc sai tham số
+//   - 404 no_route: không có route đủ thanh khoản
+//   - 422 insufficient_liquidity: có route nhưng không đủ thanh khoản cho
+//     amount, kèm lượng token tối đa giao dịch được nếu tính được
+//   - 409 arbitrage_loop: dữ liệu order book tạo ra arbitrage loop
+//   - 500 invalid_route: route tìm được không mô phỏng lại được đúng giá và
+//     không sửa được
+//   - 405 method_not_allowed: không phải GET
+//   - 503 exchange_loading: tất cả exchange đều đang tải order book
+//   - 504 timeout: hết thời gian mà chưa tìm được route nào
+func (s *server) handleBestSwap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, errorBody{
+			Code:    "method_not_allowed",
+			Message: "only GET is supported",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	base := query.Get("starting_token")
+	quote := query.Get("target_token")
+	if base == "" || quote == "" {
+		writeError(w, http.StatusBadRequest, errorBody{
+			Code:    "invalid_request",
+			Message: "starting_token and target_token are required",
+		})
+		return
+	}
+	if base == quote {
+		writeError(w, http.StatusBadRequest, errorBody{
+			Code:    "invalid_request",
+			Message: "starting_token and target_token must be different",
+		})
+		return
+	}
+	amount, err := decimal.NewFromString(query.Get("amount"))
+	if err != nil || !amount.IsPositive() {
+		writeError(w, http.StatusBadRequest, errorBody{
+			Code:    "invalid_request",
+			Message: "amount must be a positive decimal number",
+		})
+		return
+	}
+
+	opts, err := queryOptions(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorBody{
+			Code:    "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := r.Context()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	opts = append(opts, route.WithContext(ctx))
+	bid, bidErr := s.registry.BestBidRoute(base, quote, amount, opts...)
+	ask, askErr := s.registry.BestAskRoute(base, quote, amount, opts...)
+	if bidErr != nil && askErr != nil {
+		writeRouteError(ctx, w, bidErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bestSwapResponse{
+		Bid:       newSwapSide(ctx, bid, bidErr),
+		Ask:       newSwapSide(ctx, ask, askErr),
+		Exchanges: compareExchanges(bid.Results, ask.Results),
+	})
+}
+
+// queryOptions đọc các tham số giới hạn route của request:
+//   - max_hops: số chặng giao dịch tối đa, số nguyên dương
+//   - exclude_tokens: các token không được đi qua, ví dụ USDC,DAI
+//   - exclude_pairs: các trading pair không được dùng dạng BASE/QUOTE hoặc
+//     BASE/QUOTE@exchange, ví dụ KNC/USDT,ETH/USDC@binance
+//   - required_tokens: các token trung gian route phải đi qua
+//   - venues: các exchange được dùng
+//
+// Các danh sách phân tách bằng dấu phẩy.
+func queryOptions(query url.Values) ([]route.QueryOption, error) {
+	opts := []route.QueryOption{route.WithPartialResult()}
+	if value := query.Get("max_hops"); value != "" {
+		maxHops, err := strconv.Atoi(value)
+		if err != nil || maxHops < 1 {
+			return nil, errors.New("max_hops must be a positive integer")
+		}
+		opts = append(opts, route.WithMaxHops(maxHops))
+	}
+	if tokens := listParam(query, "exclude_tokens"); len(tokens) > 0 {
+		opts = append(opts, route.WithExcludedTokens(tokens...))
+	}
+	if tokens := listParam(query, "required_tokens"); len(tokens) > 0 {
+		opts = append(opts, route.WithRequiredTokens(tokens...))
+	}
+	if venues := listParam(query, "venues"); len(venues) > 0 {
+		opts = append(opts, route.WithAllowedVenues(venues...))
+	}
+	for _, value := range listParam(query, "exclude_pairs") {
+		pair, venue, _ := strings.Cut(value, "@")
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("exclude_pairs: expected BASE/QUOTE, got %q", value)
+		}
+		opts = append(opts, route.WithExcludedPairs(
+			route.PairKey{From: from, To: to, Venue: venue}))
+	}
+	return opts, nil
+}
+
+// listParam trả về các phần tử khác rỗng của tham số dạng danh sách phân
+// tách bằng dấu phẩy.
+func listParam(query url.Values, name string) []string {
+	values := []string{}
+	for _, value := range strings.Split(query.Get(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// newSwapSide trả về kết quả tốt nhất của một chiều, hoặc lỗi err của chiều
+// đó nếu khác nil, xem routeErrorBody.
+func newSwapSide(ctx context.Context, result route.MultiResult, err error) swapSide {
+	if err != nil {
+		_, body := routeErrorBody(ctx, err)
+		return swapSide{Error: &body}
+	}
+	return swapSide{
+		Exchange: result.Exchange,
+		Route:    result.Best.Route,
+		Price:    &result.Best.Price,
+		Partial:  result.Best.Partial,
+	}
+}
+
+// compareExchanges ghép kết quả bid và ask của từng exchange. Hai danh sách
+// cùng được sắp xếp theo tên exchange.
+func compareExchanges(bids, asks []route.ExchangeResult) []exchangeQuote {
+	quotes := make([]exchangeQuote, 0, len(bids))
+	asksByExchange := make(map[string]route.ExchangeResult, len(asks))
+	for _, ask := range asks {
+		asksByExchange[ask.Exchange] = ask
+	}
+	for _, bid := range bids {
+		quotes = append(quotes, exchangeQuote{
+			Exchange: bid.Exchange,
+			Bid:      newExchangeSide(bid),
+			Ask:      newExchangeSide(asksByExchange[bid.Exchange]),
+		})
+	}
+	return quotes
+}
+
+func newExchangeSide(result route.ExchangeResult) exchangeSide {
+	if result.Err != nil {
+		_, body := routeError(result.Err)
+		return exchangeSide{Error: &body}
+	}
+	return exchangeSide{
+		Route:   result.Result.Route,
+		Price:   &result.Result.Price,
+		Partial: result.Result.Partial,
+	}
+}
+
+// writeRouteError ghi lỗi tìm đường với HTTP status và error body của
+// routeErrorBody.
+func writeRouteError(ctx context.Context, w http.ResponseWriter, err error) {
+	status, body := routeErrorBody(ctx, err)
+	writeError(w, status, body)
+}
+
+// routeErrorBody giống routeError nhưng với *LiquidityError, lượng token tối
+// đa và route tương ứng được tính trong thời gian còn lại của ctx, bỏ qua nếu
+// không tính được.
+func routeErrorBody(ctx context.Context, err error) (int, errorBody) {
+	status, body := routeError(err)
+	var liqErr *route.LiquidityError
+	if errors.As(err, &liqErr) {
+		if maxAmount, maxRoute, err := liqErr.Max(ctx); err == nil {
+			body.MaxAmount, body.Route = &maxAmount, maxRoute
+		}
+	}
+	return status, body
+}
+
+// routeError trả về HTTP status và error body tương ứng với lỗi tìm đường,
+// không gồm lượng token tối đa của *LiquidityError, xem routeErrorBody.
+func routeError(err error) (int, errorBody) {
+	var arbErr *route.ArbitrageError
+	switch {
+	case errors.As(err, &arbErr):
+		return http.StatusConflict, errorBody{
+			Code:    "arbitrage_loop",
+			Message: route.ErrArbitrageLoop.Error(),
+			Cycle:   arbErr.Cycle,
+		}
+	case errors.Is(err, route.ErrArbitrageLoop):
+		return http.StatusConflict, errorBody{
+			Code:    "arbitrage_loop",
+			Message: route.ErrArbitrageLoop.Error(),
+		}
+	case errors.Is(err, route.ErrInsufficientLiquidity):
+		return http.StatusUnprocessableEntity, errorBody{
+			Code:    "insufficient_liquidity",
+			Message: route.ErrInsufficientLiquidity.Error(),
+		}
+	case errors.Is(err, route.ErrNoRoute):
+		return http.StatusNotFound, errorBody{
+			Code:    "no_route",
+			Message: route.ErrNoRoute.Error(),
+		}
+	case errors.Is(err, route.ErrInvalidRoute):
+		return http.StatusInternalServerError, errorBody{
+			Code:    "invalid_route",
+			Message: err.Error(),
+		}
+	case errors.Is(err, route.ErrExchangeLoading):
+		return http.StatusServiceUnavailable, errorBody{
+			Code:    "exchange_loading",
+			Message: route.ErrExchangeLoading.Error(),
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, errorBody{
+			Code:    "timeout",
+			Message: "route search timed out",
+		}
+	default:
+		return http.StatusInternalServerError, errorBody{
+			Code:    "internal_error",
+			Message: "internal server error",
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, body errorBody) {
+	writeJSON(w, status, errorResponse{Error: body})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
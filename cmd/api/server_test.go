@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func newTestGraph(kncBid, kncAsk string) route.Graph {
+	kncUSDT := route.SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d(kncBid), AskPrice: d(kncAsk)}
+	ethUSDT := route.SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("355"), AskPrice: d("360")}
+	loop := route.SimpleEdge{BaseToken: "LUNA", QuoteToken: "UST", BidPrice: d("2"), AskPrice: d("0.5")}
+	return route.NewGraphWithEdges([]route.Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+		loop, loop.GetReverseEdge(),
+	})
+}
+
+// newTestServer tạo server với hai exchange: binance cho giá bid tốt hơn,
+// kraken cho giá ask tốt hơn, và okx còn đang tải.
+func newTestServer() http.Handler {
+	registry := route.NewRegistry()
+	registry.Register("binance", newTestGraph("0.9", "1.2"))
+	registry.Register("kraken", newTestGraph("0.8", "1.1"))
+	registry.Register("okx", route.NewGraph())
+	registry.SetReady("binance", true)
+	registry.SetReady("kraken", true)
+	return newServer(registry, time.Second).routes()
+}
+
+func TestBestSwap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet,
+		"/best-swap?starting_token=KNC&target_token=ETH&amount=100", nil)
+	newTestServer().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body %s", rec.Code, rec.Body)
+	}
+
+	// Giá được trả về dưới dạng chuỗi
+	type side struct {
+		Exchange string   `json:"exchange"`
+		Route    []string `json:"route"`
+		Price    string   `json:"price"`
+		Error    *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	var raw struct {
+		Bid       side `json:"bid"`
+		Ask       side `json:"ask"`
+		Exchanges []struct {
+			Exchange string `json:"exchange"`
+			Bid      side   `json:"bid"`
+			Ask      side   `json:"ask"`
+		} `json:"exchanges"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("invalid JSON %s: %v", rec.Body, err)
+	}
+	if !slices.Equal(raw.Bid.Route, []string{"KNC", "USDT", "ETH"}) {
+		t.Errorf("bid route = %v, want KNC->USDT->ETH", raw.Bid.Route)
+	}
+	if !slices.Equal(raw.Ask.Route, []string{"ETH", "USDT", "KNC"}) {
+		t.Errorf("ask route = %v, want ETH->USDT->KNC", raw.Ask.Route)
+	}
+	// 0.9 / 360 = 0.0025, giá nghịch đảo 1/360 của cạnh USDT->ETH bị làm
+	// tròn xuống
+	if raw.Bid.Price != "0.002499999999999999" {
+		t.Errorf("bid price = %s, want 0.002499999999999999", raw.Bid.Price)
+	}
+	if raw.Ask.Price == "" {
+		t.Errorf("ask price is empty")
+	}
+	if raw.Bid.Exchange != "binance" || raw.Ask.Exchange != "kraken" {
+		t.Errorf("best exchanges = (%s, %s), want (binance, kraken)",
+			raw.Bid.Exchange, raw.Ask.Exchange)
+	}
+
+	if len(raw.Exchanges) != 3 {
+		t.Fatalf("got %d exchanges, want 3", len(raw.Exchanges))
+	}
+	if kraken := raw.Exchanges[1]; kraken.Exchange != "kraken" || kraken.Bid.Price == "" {
+		t.Errorf("exchanges[1] = %+v, want kraken with bid price", kraken)
+	}
+	if okx := raw.Exchanges[2]; okx.Bid.Error == nil || okx.Bid.Error.Code != "exchange_loading" {
+		t.Errorf("exchanges[2] = %+v, want okx loading", okx)
+	}
+}
+
+func TestBestSwap_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "Missing token",
+			url:      "/best-swap?starting_token=KNC&amount=1",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Same token",
+			url:      "/best-swap?starting_token=KNC&target_token=KNC&amount=1",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Invalid amount",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=abc",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Non-positive amount",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=0",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Invalid max hops",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=1&max_hops=0",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Route longer than max hops",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=1&max_hops=1",
+			wantCode: http.StatusNotFound,
+			wantErr:  "no_route",
+		},
+		{
+			name:     "Invalid excluded pair",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=1&exclude_pairs=KNCUSDT",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_request",
+		},
+		{
+			name:     "Excluded token",
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=1&exclude_tokens=USDT",
+			wantCode: http.StatusNotFound,
+			wantErr:  "no_route",
+		},
+		{
+			name:     "No route",
+			url:      "/best-swap?starting_token=KNC&target_token=BTC&amount=1",
+			wantCode: http.StatusNotFound,
+			wantErr:  "no_route",
+		},
+		{
+			name:     "Arbitrage loop",
+			url:      "/best-swap?starting_token=LUNA&target_token=UST&amount=1",
+			wantCode: http.StatusConflict,
+			wantErr:  "arbitrage_loop",
+		},
+		{
+			name:     "Method not allowed",
+			method:   http.MethodPost,
+			url:      "/best-swap?starting_token=KNC&target_token=ETH&amount=1",
+			wantCode: http.StatusMethodNotAllowed,
+			wantErr:  "method_not_allowed",
+		},
+	}
+
+	handler := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, tt.url, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var body errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON %s: %v", rec.Body, err)
+			}
+			if body.Error.Code != tt.wantErr {
+				t.Errorf("error code = %q, want %q", body.Error.Code, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteRouteError_InsufficientLiquidity(t *testing.T) {
+	err := route.NewLiquidityError(d("500"), d("400"), []string{"KNC", "USDT"}, true)
+	rec := httptest.NewRecorder()
+	writeRouteError(context.Background(), rec, err)
+
+	var body errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || body.Error.Code != "insufficient_liquidity" {
+		t.Errorf("writeRouteError() = %d %s, want 422 insufficient_liquidity", rec.Code, body.Error.Code)
+	}
+	if body.Error.MaxAmount == nil || !body.Error.MaxAmount.Equal(d("400")) ||
+		!slices.Equal(body.Error.Route, []string{"KNC", "USDT"}) {
+		t.Errorf("body = %+v, want max_amount 400 via KNC->USDT", body.Error)
+	}
+}
+
+func TestBestSwap_OneSideFails(t *testing.T) {
+	// Order book chỉ có 10 KNC để mua: bán 50 KNC được, mua 50 KNC thì không
+	kncETH := route.OrderEdge{
+		BaseToken: "KNC", QuoteToken: "ETH",
+		BidOrders: []route.Order{{Price: d("0.002"), Quantity: d("1000")}},
+		AskOrders: []route.Order{{Price: d("0.003"), Quantity: d("10")}},
+	}
+	registry := route.NewRegistry()
+	registry.Register("binance", route.NewGraphWithEdges([]route.Edge{kncETH, kncETH.GetReverseEdge()}))
+	registry.SetReady("binance", true)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet,
+		"/best-swap?starting_token=KNC&target_token=ETH&amount=50", nil)
+	newServer(registry, time.Second).routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body %s", rec.Code, rec.Body)
+	}
+	var body bestSwapResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %s: %v", rec.Body, err)
+	}
+	if body.Bid.Error != nil || body.Bid.Price == nil || !body.Bid.Price.Equal(d("0.002")) {
+		t.Errorf("bid = %+v, want price 0.002", body.Bid)
+	}
+	if body.Ask.Error == nil || body.Ask.Error.Code != "insufficient_liquidity" ||
+		body.Ask.Error.MaxAmount == nil || !body.Ask.Error.MaxAmount.Equal(d("10")) {
+		t.Errorf("ask = %+v, want insufficient_liquidity with max_amount 10", body.Ask)
+	}
+	if body.Ask.Price != nil || body.Ask.Route != nil {
+		t.Errorf("ask = %+v, want no route and price", body.Ask)
+	}
+}
@@ -1,128 +1,90 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
 
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/input"
+	"github.com/nkngn/kyber-homework/internal/report"
 	"github.com/nkngn/kyber-homework/internal/route"
 )
 
 func main() {
+	verbose := flag.Bool("v", false, "in chi tiết khớp lệnh từng chặng và price impact của route")
+	exactQuote := flag.Bool("exact-quote", false,
+		"amount là lượng quote token: bid tìm lượng base cần bán, ask tìm lượng base mua được")
+	curveTo := flag.String("curve-to", "",
+		"in giá và route tốt nhất cho các mức amount từ amount của input tới giá trị này")
+	curveSteps := flag.Int("curve-steps", 10, "số mức amount của -curve-to")
+	curveLog := flag.Bool("curve-log", false, "chia các mức amount của -curve-to theo thang log")
+	top := flag.Int("top", 1, "in thêm các route dự phòng, tổng cộng tối đa top route mỗi chiều")
+	inputPath := flag.String("input", "test/expanded_input.txt",
+		"file input, định dạng theo phần mở rộng: .json, .yaml/.yml hoặc định dạng dòng")
+	flag.Parse()
+
 	// read input from file, build graph
-	base, quote, amount, graph, err := ReadExpandedInput("test/expanded_input.txt")
+	in, err := input.Read(*inputPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Lỗi đọc file expanded_input.txt: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Lỗi đọc file input: %v\n", err)
 		os.Exit(1)
 	}
+	graph := route.NewGraphWithEdges(in.Edges(""))
 
-	// find best ask price
-	bestAskPrice, bestAskRoute, err := graph.BestAskPrice(base, quote, amount)
-	if err != nil {
-		switch err {
-		case route.ErrArbitrageLoop:
-			fmt.Printf("Cannot find best ask price %s->%s, arbitrage loop detected.\n", quote, base)
-		case route.ErrNoRoute:
-			fmt.Printf("Cannot find best ask price %s->%s, no route.\n", quote, base)
-		}
-	} else {
-		fmt.Println(strings.Join(bestAskRoute, "->"))
-		fmt.Printf("%.6f\n", bestAskPrice)
+	options := report.Options{Verbose: *verbose, Top: *top}
+	if *exactQuote {
+		options.QueryOptions = append(options.QueryOptions, route.WithExactQuote())
 	}
 
-	// find best bid price
-	bestBidPrice, bestBidRoute, err := graph.BestBidPrice(base, quote, amount)
-	if err != nil {
-		switch err {
-		case route.ErrArbitrageLoop:
-			fmt.Printf("Cannot find best bid price %s->%s, arbitrage loop detected.\n", quote, base)
-		case route.ErrNoRoute:
-			fmt.Printf("Cannot find best bid price %s->%s, no route.\n", quote, base)
+	var curve *curveOptions
+	if *curveTo != "" {
+		to, err := decimal.NewFromString(*curveTo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Giá trị -curve-to không hợp lệ: %v\n", err)
+			os.Exit(1)
+		}
+		curve = &curveOptions{to: to, steps: *curveSteps, spacing: route.LinearSpacing}
+		if *curveLog {
+			curve.spacing = route.LogSpacing
 		}
-	} else {
-		fmt.Println(strings.Join(bestBidRoute, "->"))
-		fmt.Printf("%.6f\n", bestBidPrice)
 	}
-}
 
-func ReadExpandedInput(filePath string) (string, string, float64, route.Graph, error) {
-	// Open file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", "", 0, nil, err
+	for i, query := range in.Queries {
+		if len(in.Queries) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s %s %s\n", query.Base, query.Quote, query.Amount)
+		}
+		printQuery(graph, query, curve, options)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-
-	// Dòng 1: base_currency quote_currency amount
-	// KNC ETH 100
-	scanner.Scan()
-	line1 := scanner.Text()
-	parts := strings.Fields(line1)
-	baseCurrency := parts[0]
-	quoteCurrency := parts[1]
-	amount, _ := strconv.ParseFloat(parts[2], 64)
-
-	// Dòng 2: n - số cặp giao dịch
-	// 2
-	scanner.Scan()
-	n, _ := strconv.Atoi(scanner.Text())
-
-	// n block tiếp theo: mỗi block thể hiện một cặp giao dịch, đi cùng order book tương ứng
-	// KNC USDT
-	// 2
-	// 1.1 150
-	// 1.2 200
-	// 2
-	// 0.9 100
-	// 0.8 300
-	edges := make([]route.Edge, 0, n*2)
-	for range n {
-		// Tên cặp
-		scanner.Scan()
-		pairLine := scanner.Text()
-		pairFields := strings.Fields(pairLine)
-		base := pairFields[0]
-		quote := pairFields[1]
+}
 
-		// Số lượng ask orders
-		scanner.Scan()
-		nAsk, _ := strconv.Atoi(scanner.Text())
-		askOrders := make([]route.Order, 0, nAsk)
-		for range nAsk {
-			scanner.Scan()
-			askFields := strings.Fields(scanner.Text())
-			price, _ := strconv.ParseFloat(askFields[0], 64)
-			qty, _ := strconv.ParseFloat(askFields[1], 64)
-			askOrders = append(askOrders, route.Order{Price: price, Quantity: qty})
-		}
+// curveOptions là các tham số của -curve-to.
+type curveOptions struct {
+	to      decimal.Decimal
+	steps   int
+	spacing route.Spacing
+}
 
-		// Số lượng bid orders
-		scanner.Scan()
-		nBid, _ := strconv.Atoi(scanner.Text())
-		bidOrders := make([]route.Order, 0, nBid)
-		for range nBid {
-			scanner.Scan()
-			bidFields := strings.Fields(scanner.Text())
-			price, _ := strconv.ParseFloat(bidFields[0], 64)
-			qty, _ := strconv.ParseFloat(bidFields[1], 64)
-			bidOrders = append(bidOrders, route.Order{Price: price, Quantity: qty})
-		}
+// printQuery in route và giá ask, bid tốt nhất của query, xem report.Query.
+// Nếu curve khác nil, in đường giá theo amount thay vào đó.
+func printQuery(graph route.Graph, query input.Query, curve *curveOptions, options report.Options) {
+	base, quote, amount := query.Base, query.Quote, query.Amount
+	if curve == nil {
+		report.Query(os.Stdout, graph, base, quote, amount, options)
+		return
+	}
 
-		// Tạo OrderEdge và reverse edge
-		edge := route.OrderEdge{
-			BaseToken:  base,
-			QuoteToken: quote,
-			AskOrders:  askOrders,
-			BidOrders:  bidOrders,
-		}
-		edges = append(edges, edge)
-		edges = append(edges, edge.GetReverseEdge())
+	amounts, err := route.Ladder(amount, curve.to, curve.steps, curve.spacing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Không tạo được các mức amount: %v\n", err)
+		os.Exit(1)
 	}
 
-	g := route.NewGraphWithEdges(edges)
-	return baseCurrency, quoteCurrency, amount, g, nil
+	askCurve, err := graph.AskCurve(base, quote, amounts, options.QueryOptions...)
+	report.Curve(os.Stdout, "ask", base, quote, askCurve, err)
+	bidCurve, err := graph.BidCurve(base, quote, amounts, options.QueryOptions...)
+	report.Curve(os.Stdout, "bid", base, quote, bidCurve, err)
 }
@@ -1,97 +1,45 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
 
+	"github.com/nkngn/kyber-homework/internal/input"
+	"github.com/nkngn/kyber-homework/internal/report"
 	"github.com/nkngn/kyber-homework/internal/route"
 )
 
 func main() {
+	verbose := flag.Bool("v", false, "in chi tiết khớp lệnh từng chặng và price impact của route")
+	exactQuote := flag.Bool("exact-quote", false,
+		"amount là lượng quote token: bid tìm lượng base cần bán, ask tìm lượng base mua được")
+	top := flag.Int("top", 1, "in thêm các route dự phòng, tổng cộng tối đa top route mỗi chiều")
+	inputPath := flag.String("input", "test/simple_input.txt",
+		"file input, định dạng theo phần mở rộng: .json, .yaml/.yml hoặc định dạng dòng")
+	flag.Parse()
+
 	// read input from file, build graph
-	base, quote, graph, err := ReadSimpleInput("test/simple_input.txt")
+	in, err := input.Read(*inputPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Lỗi đọc file input.txt: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Lỗi đọc file input: %v\n", err)
 		os.Exit(1)
 	}
+	graph := route.NewGraphWithEdges(in.Edges(""))
 
-	// Đối với simple problem, lượng base token cần bán/mua luôn là 1 đơn vị
-	// find best ask price
-	bestAskPrice, bestAskRoute, err := graph.BestAskPrice(base, quote, 1.0)
-	if err != nil {
-		switch err {
-		case route.ErrArbitrageLoop:
-			fmt.Printf("Cannot find best ask price %s->%s, arbitrage loop detected.\n", quote, base)
-		case route.ErrNoRoute:
-			fmt.Printf("Cannot find best ask price %s->%s, no route.\n", quote, base)
-		}
-	} else {
-		fmt.Println(strings.Join(bestAskRoute, "->"))
-		fmt.Printf("%.6f\n", bestAskPrice)
-	}
-
-	// find best bid price
-	bestBidPrice, bestBidRoute, err := graph.BestBidPrice(base, quote, 1.0)
-	if err != nil {
-		switch err {
-		case route.ErrArbitrageLoop:
-			fmt.Printf("Cannot find best bid price %s->%s, arbitrage loop detected.\n", quote, base)
-		case route.ErrNoRoute:
-			fmt.Printf("Cannot find best bid price %s->%s, no route.\n", quote, base)
-		}
-	} else {
-		fmt.Println(strings.Join(bestBidRoute, "->"))
-		fmt.Printf("%.6f\n", bestBidPrice)
-	}
-}
-
-func ReadSimpleInput(filePath string) (string, string, route.Graph, error) {
-	// Open file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", "", nil, err
+	// Đối với simple problem, lượng base token cần bán/mua mặc định là 1 đơn vị
+	options := report.Options{Verbose: *verbose, Top: *top}
+	if *exactQuote {
+		options.QueryOptions = append(options.QueryOptions, route.WithExactQuote())
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
 
-	// Dòng 1: base_currency quote_currency
-	// KNC ETH
-	scanner.Scan()
-	line1 := scanner.Text()
-	parts := strings.Fields(line1)
-	baseCurrency := parts[0]
-	quoteCurrency := parts[1]
-
-	// Dòng 2: n - số cặp giao dịch
-	// 2
-	scanner.Scan()
-	n, _ := strconv.Atoi(scanner.Text())
-
-	// n dòng tiếp theo: các cặp giao dịch, mỗi cặp tương ứng hai cạnh trong đồ thị
-	// KNC USDT 1.1 0.9
-	// ETH USDT 360 355
-	edges := make([]route.Edge, 0, n*2)
-	for range n {
-		scanner.Scan()
-		fields := strings.Fields(scanner.Text())
-		ask, _ := strconv.ParseFloat(fields[2], 64)
-		bid, _ := strconv.ParseFloat(fields[3], 64)
-		edge := route.SimpleEdge{
-			BaseToken:  fields[0],
-			QuoteToken: fields[1],
-			AskPrice:   ask,
-			BidPrice:   bid,
+	for i, query := range in.Queries {
+		if len(in.Queries) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s %s %s\n", query.Base, query.Quote, query.Amount)
 		}
-		edges = append(edges, edge)
-		edges = append(edges, edge.GetReverseEdge())
+		report.Query(os.Stdout, graph, query.Base, query.Quote, query.Amount, options)
 	}
-
-	// Tạo đồ thị từ danh sách các cạnh
-	g := route.NewGraphWithEdges(edges)
-
-	return baseCurrency, quoteCurrency, g, nil
 }
@@ -0,0 +1,352 @@
+// Package decimal cài đặt kiểu số thập phân fixed-point dùng cho giá và khối
+// lượng trong order book. Khác với float64, mọi phép cộng trừ là chính xác,
+// phép nhân chia được làm tròn theo RoundingMode chỉ định, nên kết quả tính
+// toán luôn tái lập được tới chữ số cuối cùng.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale là số chữ số thập phân được lưu trữ nội bộ. Mọi Decimal đều có đúng
+// Scale chữ số sau dấu phẩy, đủ cho precision của các token phổ biến (ETH có
+// 18 decimals).
+const Scale = 18
+
+// RoundingMode quy định cách làm tròn khi kết quả có nhiều chữ số thập phân
+// hơn số chữ số được giữ lại.
+type RoundingMode int
+
+const (
+	// RoundDown làm tròn về phía 0 (cắt bỏ phần thừa).
+	RoundDown RoundingMode = iota
+	// RoundUp làm tròn ra xa 0.
+	RoundUp
+	// RoundHalfUp làm tròn về giá trị gần nhất, nếu cách đều thì ra xa 0.
+	RoundHalfUp
+	// RoundHalfEven làm tròn về giá trị gần nhất, nếu cách đều thì về số
+	// chẵn (banker's rounding).
+	RoundHalfEven
+)
+
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// maxExponent giới hạn số mũ khi parse để tránh tạo ra số quá lớn.
+const maxExponent = 1000
+
+var (
+	ten         = big.NewInt(10)
+	scaleFactor = new(big.Int).Exp(ten, big.NewInt(Scale), nil)
+
+	Zero = Decimal{}
+	One  = NewFromInt(1)
+
+	// Ulp là số dương nhỏ nhất biểu diễn được, 10^-Scale.
+	Ulp = Decimal{v: big.NewInt(1)}
+)
+
+// Decimal là số thập phân có giá trị bằng v / 10^Scale. Giá trị zero của
+// Decimal là số 0 và sẵn sàng để sử dụng. Decimal là immutable, các phép
+// toán luôn trả về giá trị mới.
+type Decimal struct {
+	v *big.Int
+}
+
+// NewFromInt tạo Decimal từ một số nguyên.
+func NewFromInt(i int64) Decimal {
+	return Decimal{v: new(big.Int).Mul(big.NewInt(i), scaleFactor)}
+}
+
+// NewFromString parse chuỗi số thập phân dạng "123", "-0.5", "1.2e-3". Các
+// chữ số vượt quá Scale được làm tròn RoundHalfEven.
+func NewFromString(s string) (Decimal, error) {
+	original := s
+	if s == "" {
+		return Decimal{}, fmt.Errorf("%w: empty string", ErrInvalidDecimal)
+	}
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > maxExponent || e < -maxExponent {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, original)
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, original)
+	}
+	digits := intPart + fracPart
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, original)
+		}
+	}
+	if digits == "" {
+		digits = "0"
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		unscaled.Neg(unscaled)
+	}
+
+	// Giá trị thực = unscaled * 10^(exp - len(fracPart)), đưa về Scale
+	shift := exp - len(fracPart) + Scale
+	if shift >= 0 {
+		unscaled.Mul(unscaled, pow10(shift))
+		return Decimal{v: unscaled}, nil
+	}
+	return Decimal{v: divRound(unscaled, pow10(-shift), RoundHalfEven)}, nil
+}
+
+// RequireFromString giống NewFromString nhưng panic nếu chuỗi không hợp lệ.
+// Chỉ nên dùng với hằng số hoặc trong test.
+func RequireFromString(s string) Decimal {
+	d, err := NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewFromFloat tạo Decimal từ float64, sử dụng biểu diễn thập phân ngắn nhất
+// của f (ví dụ 0.1 cho ra đúng 0.1). Panic nếu f là NaN hoặc Inf.
+func NewFromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("decimal: cannot convert %v to Decimal", f))
+	}
+	return RequireFromString(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// Add trả về d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	return Decimal{v: new(big.Int).Add(d.value(), o.value())}
+}
+
+// Sub trả về d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	return Decimal{v: new(big.Int).Sub(d.value(), o.value())}
+}
+
+// Neg trả về -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{v: new(big.Int).Neg(d.value())}
+}
+
+// Abs trả về |d|.
+func (d Decimal) Abs() Decimal {
+	return Decimal{v: new(big.Int).Abs(d.value())}
+}
+
+// Mul trả về d * o, làm tròn RoundHalfEven.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return d.MulRound(o, RoundHalfEven)
+}
+
+// MulRound trả về d * o, làm tròn theo mode.
+func (d Decimal) MulRound(o Decimal, mode RoundingMode) Decimal {
+	product := new(big.Int).Mul(d.value(), o.value())
+	return Decimal{v: divRound(product, scaleFactor, mode)}
+}
+
+// Quo trả về d / o, làm tròn RoundHalfEven. Panic nếu o bằng 0.
+func (d Decimal) Quo(o Decimal) Decimal {
+	return d.QuoRound(o, RoundHalfEven)
+}
+
+// QuoRound trả về d / o, làm tròn theo mode. Panic nếu o bằng 0.
+func (d Decimal) QuoRound(o Decimal, mode RoundingMode) Decimal {
+	if o.IsZero() {
+		panic("decimal: division by zero")
+	}
+	numerator := new(big.Int).Mul(d.value(), scaleFactor)
+	return Decimal{v: divRound(numerator, o.value(), mode)}
+}
+
+// Round làm tròn d còn places chữ số thập phân theo mode. places >= Scale
+// không làm thay đổi giá trị.
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	if places >= Scale {
+		return d
+	}
+	factor := pow10(Scale - int(places))
+	q := divRound(d.value(), factor, mode)
+	return Decimal{v: q.Mul(q, factor)}
+}
+
+// Cmp so sánh d với o, trả về -1, 0, 1 tương ứng d < o, d == o, d > o.
+func (d Decimal) Cmp(o Decimal) int {
+	return d.value().Cmp(o.value())
+}
+
+func (d Decimal) Equal(o Decimal) bool              { return d.Cmp(o) == 0 }
+func (d Decimal) LessThan(o Decimal) bool           { return d.Cmp(o) < 0 }
+func (d Decimal) LessThanOrEqual(o Decimal) bool    { return d.Cmp(o) <= 0 }
+func (d Decimal) GreaterThan(o Decimal) bool        { return d.Cmp(o) > 0 }
+func (d Decimal) GreaterThanOrEqual(o Decimal) bool { return d.Cmp(o) >= 0 }
+
+// Sign trả về -1, 0, 1 tương ứng d < 0, d == 0, d > 0.
+func (d Decimal) Sign() int { return d.value().Sign() }
+
+func (d Decimal) IsZero() bool     { return d.Sign() == 0 }
+func (d Decimal) IsPositive() bool { return d.Sign() > 0 }
+func (d Decimal) IsNegative() bool { return d.Sign() < 0 }
+
+// Min trả về giá trị nhỏ nhất trong các Decimal truyền vào.
+func Min(first Decimal, rest ...Decimal) Decimal {
+	m := first
+	for _, d := range rest {
+		if d.LessThan(m) {
+			m = d
+		}
+	}
+	return m
+}
+
+// Max trả về giá trị lớn nhất trong các Decimal truyền vào.
+func Max(first Decimal, rest ...Decimal) Decimal {
+	m := first
+	for _, d := range rest {
+		if d.GreaterThan(m) {
+			m = d
+		}
+	}
+	return m
+}
+
+// Float64 trả về giá trị float64 gần nhất với d. Chỉ nên dùng để hiển thị
+// hoặc ước lượng, không dùng để tính toán tiếp.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.value(), scaleFactor).Float64()
+	return f
+}
+
+// String trả về biểu diễn thập phân của d, bỏ các số 0 thừa ở cuối.
+func (d Decimal) String() string {
+	s := d.format(Scale)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	return s
+}
+
+// StringFixed trả về biểu diễn thập phân của d với đúng places chữ số sau
+// dấu phẩy, làm tròn RoundHalfEven.
+func (d Decimal) StringFixed(places int32) string {
+	places = min(max(places, 0), Scale)
+	return d.Round(places, RoundHalfEven).format(int(places))
+}
+
+// format in d với places chữ số thập phân, giả định các chữ số bị bỏ đi đều
+// bằng 0.
+func (d Decimal) format(places int) string {
+	abs := new(big.Int).Abs(d.value())
+	digits := abs.String()
+	if len(digits) <= Scale {
+		digits = strings.Repeat("0", Scale-len(digits)+1) + digits
+	}
+	intPart := digits[:len(digits)-Scale]
+	fracPart := digits[len(digits)-Scale:][:places]
+
+	s := intPart
+	if places > 0 {
+		s += "." + fracPart
+	}
+	if d.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON encode Decimal thành JSON string để không mất precision.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON chấp nhận cả JSON string lẫn JSON number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := NewFromString(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalText cài đặt encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText cài đặt encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := NewFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// value trả về giá trị unscaled, nil được hiểu là 0. Không được sửa đổi giá
+// trị trả về.
+func (d Decimal) value() *big.Int {
+	if d.v == nil {
+		return new(big.Int)
+	}
+	return d.v
+}
+
+// divRound trả về num / den làm tròn theo mode.
+func divRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// Dấu của kết quả chính xác, dùng để làm tròn ra xa 0
+	sign := num.Sign() * den.Sign()
+	awayFromZero := false
+	switch mode {
+	case RoundDown:
+	case RoundUp:
+		awayFromZero = true
+	case RoundHalfUp, RoundHalfEven:
+		twiceRem := new(big.Int).Abs(r)
+		twiceRem.Lsh(twiceRem, 1)
+		c := twiceRem.Cmp(new(big.Int).Abs(den))
+		awayFromZero = c > 0 || (c == 0 && (mode == RoundHalfUp || q.Bit(0) == 1))
+	}
+
+	if awayFromZero {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,162 @@
+package decimal
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNewFromString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0", "0"},
+		{"1", "1"},
+		{"-1.50", "-1.5"},
+		{"+0.001", "0.001"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{"1.2e3", "1200"},
+		{"1.2E-3", "0.0012"},
+		{"0.1234567890123456789", "0.123456789012345679"},
+		{"0.0000000000000000005", "0"},
+		{"0.0000000000000000015", "0.000000000000000002"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NewFromString(tt.in)
+			if err != nil {
+				t.Fatalf("NewFromString(%q) error = %v", tt.in, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("NewFromString(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFromString_Invalid(t *testing.T) {
+	for _, in := range []string{"", ".", "-", "abc", "1.2.3", "1e", "1e5000", " 1"} {
+		if _, err := NewFromString(in); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("NewFromString(%q) error = %v, want %v", in, err, ErrInvalidDecimal)
+		}
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	a := RequireFromString("1.1")
+	b := RequireFromString("0.2")
+
+	if got := a.Add(b).String(); got != "1.3" {
+		t.Errorf("Add = %s, want 1.3", got)
+	}
+	if got := a.Sub(b).String(); got != "0.9" {
+		t.Errorf("Sub = %s, want 0.9", got)
+	}
+	if got := a.Mul(b).String(); got != "0.22" {
+		t.Errorf("Mul = %s, want 0.22", got)
+	}
+	if got := a.Quo(b).String(); got != "5.5" {
+		t.Errorf("Quo = %s, want 5.5", got)
+	}
+}
+
+func TestDecimal_QuoRound(t *testing.T) {
+	one, three := NewFromInt(1), NewFromInt(3)
+	two := NewFromInt(2)
+
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"1/3 down", one.QuoRound(three, RoundDown), "0.333333333333333333"},
+		{"1/3 up", one.QuoRound(three, RoundUp), "0.333333333333333334"},
+		{"2/3 half even", two.QuoRound(three, RoundHalfEven), "0.666666666666666667"},
+		{"-1/3 down", one.Neg().QuoRound(three, RoundDown), "-0.333333333333333333"},
+		{"-1/3 up", one.Neg().QuoRound(three, RoundUp), "-0.333333333333333334"},
+	}
+
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_Round(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"1.25", 1, RoundDown, "1.2"},
+		{"1.21", 1, RoundUp, "1.3"},
+		{"1.25", 1, RoundHalfUp, "1.3"},
+		{"1.25", 1, RoundHalfEven, "1.2"},
+		{"1.35", 1, RoundHalfEven, "1.4"},
+		{"-1.25", 1, RoundHalfUp, "-1.3"},
+		{"123.456", 0, RoundDown, "123"},
+		{"0.001", 2, RoundUp, "0.01"},
+	}
+
+	for _, tt := range tests {
+		got := RequireFromString(tt.in).Round(tt.places, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("Round(%s, %d, %d) = %s, want %s", tt.in, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_StringFixed(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		want   string
+	}{
+		{"0.0025", 6, "0.002500"},
+		{"0.0030985915", 6, "0.003099"},
+		{"-2", 2, "-2.00"},
+		{"7.5", 0, "8"},
+	}
+
+	for _, tt := range tests {
+		if got := RequireFromString(tt.in).StringFixed(tt.places); got != tt.want {
+			t.Errorf("StringFixed(%s, %d) = %s, want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	var v struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":"1.10","b":2.5}`), &v); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if v.A.String() != "1.1" || v.B.String() != "2.5" {
+		t.Errorf("Unmarshal = %s, %s, want 1.1, 2.5", v.A, v.B)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	if string(data) != `{"a":"1.1","b":"2.5"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
+
+func TestDecimal_ZeroValue(t *testing.T) {
+	var d Decimal
+	if !d.IsZero() || d.String() != "0" || !d.Equal(Zero) {
+		t.Errorf("zero value = %s, want 0", d)
+	}
+	if got := d.Add(One); !got.Equal(One) {
+		t.Errorf("0 + 1 = %s, want 1", got)
+	}
+}
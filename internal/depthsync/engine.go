@@ -0,0 +1,337 @@
+// Package depthsync đồng bộ order book của các symbol từ snapshot và luồng
+// diff event của exchange, sau đó cập nhật các cạnh tương ứng trong
+// route.Graph. Thuật toán theo mục "Order Book Fetcher Service" trong
+// docs/system_design_problem.md:
+//   - buffer diff event của từng symbol, tối đa DefaultBufferSize event
+//   - fetch snapshot, nếu snapshot cũ hơn event đầu tiên trong buffer thì
+//     fetch lại
+//   - bỏ các event có update id không mới hơn snapshot.LastUpdateID, apply
+//     các event còn lại rồi tiếp tục apply các event mới nhận được
+//
+// Các event đã nằm sẵn trong channel được apply thành một batch, mỗi symbol
+// thay đổi trong batch chỉ được publish vào graph một lần, nên khi event đến
+// nhanh hơn tốc độ xử lý, chi phí tạo cạnh được chia cho cả batch.
+package depthsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nkngn/kyber-homework/internal/orderbook"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// DefaultBufferSize là số diff event tối đa được buffer cho mỗi symbol trong
+// khi chờ snapshot.
+const DefaultBufferSize = 1000
+
+// maxSnapshotAttempts là số lần fetch snapshot liên tiếp tối đa cho một lần
+// đồng bộ. Nếu vẫn không được, symbol chờ event tiếp theo để thử lại.
+const maxSnapshotAttempts = 3
+
+var (
+	ErrUnknownSymbol  = errors.New("depthsync: unknown symbol")
+	ErrSnapshotTooOld = errors.New("depthsync: snapshot older than buffered events")
+)
+
+// Symbol là một trading pair cần đồng bộ.
+//   - Name: tên symbol trên exchange, dùng để fetch snapshot và nhận event,
+//     ví dụ KNCUSDT
+//   - Base, Quote: token của trading pair, cạnh Base->Quote và cạnh đảo ngược
+//     được cập nhật vào graph
+//   - Fee, Precisions, Venue: gắn vào cạnh, xem route.OrderEdge
+type Symbol struct {
+	Name       string
+	Base       string
+	Quote      string
+	Fee        route.Fee
+	Precisions route.Precisions
+	Venue      string
+}
+
+// pairKey trả về PairKey của cạnh Base->Quote trên exchange Venue, dùng để chỉ
+// thay các cạnh của symbol mà không đụng tới cạnh của cùng trading pair trên
+// exchange khác.
+func (s Symbol) pairKey() route.PairKey {
+	return route.PairKey{From: s.Base, To: s.Quote, Venue: s.Venue}
+}
+
+// Event là diff event của một symbol nhận được từ stream.
+type Event struct {
+	Symbol string
+	orderbook.DiffEvent
+}
+
+// SnapshotSource lấy snapshot order book của một symbol, thường là API depth
+// của exchange.
+type SnapshotSource interface {
+	Snapshot(ctx context.Context, symbol string) (orderbook.Snapshot, error)
+}
+
+// Option thay đổi cấu hình của Engine.
+type Option func(*Engine)
+
+// WithBufferSize đặt số event tối đa được buffer cho mỗi symbol, mặc định
+// DefaultBufferSize. Khi buffer đầy, event cũ nhất bị bỏ.
+func WithBufferSize(size int) Option {
+	return func(e *Engine) {
+		if size > 0 {
+			e.bufferSize = size
+		}
+	}
+}
+
+// WithErrorHandler đặt hàm nhận các lỗi trong quá trình đồng bộ, ví dụ lỗi
+// fetch snapshot hoặc event bị mất. Các lỗi này không làm Engine dừng lại.
+func WithErrorHandler(handler func(symbol string, err error)) Option {
+	return func(e *Engine) {
+		e.onError = handler
+	}
+}
+
+// Engine đồng bộ order book của các symbol và cập nhật vào graph. Các hàm của
+// Engine chỉ được gọi từ một goroutine, việc fetch snapshot chạy ở goroutine
+// riêng và không chặn việc nhận event.
+type Engine struct {
+	graph      route.Graph
+	source     SnapshotSource
+	bufferSize int
+	onError    func(symbol string, err error)
+
+	states    map[string]*symbolState
+	snapshots chan snapshotResult
+
+	// dirty là các symbol đã thay đổi trong batch hiện tại, chưa publish
+	dirty []*symbolState
+}
+
+// symbolState là trạng thái đồng bộ của một symbol.
+//   - book: order book đã đồng bộ, nil khi đang chờ snapshot
+//   - buffer: các event nhận được trong khi chờ snapshot
+//   - fetching: đang có một lần fetch snapshot chưa trả về
+//   - attempts: số lần fetch snapshot liên tiếp của lần đồng bộ hiện tại
+//   - dirty: order book đã thay đổi trong batch hiện tại, chưa publish
+type symbolState struct {
+	symbol   Symbol
+	book     *orderbook.OrderBook
+	buffer   []orderbook.DiffEvent
+	fetching bool
+	attempts int
+	dirty    bool
+}
+
+// snapshotResult là kết quả fetch snapshot của một symbol.
+type snapshotResult struct {
+	symbol   string
+	snapshot orderbook.Snapshot
+	err      error
+}
+
+// NewEngine tạo Engine đồng bộ các symbols vào graph, snapshot lấy từ source.
+// Symbol có Fee không hợp lệ (xem route.Fee.Validate) được báo qua error
+// handler và bỏ qua, event của nó sau đó có lỗi ErrUnknownSymbol.
+func NewEngine(graph route.Graph, source SnapshotSource, symbols []Symbol,
+	opts ...Option) *Engine {
+	e := &Engine{
+		graph:      graph,
+		source:     source,
+		bufferSize: DefaultBufferSize,
+		onError:    func(string, error) {},
+		states:     make(map[string]*symbolState, len(symbols)),
+		// Mỗi symbol có tối đa một lần fetch đang chạy nên goroutine fetch
+		// không bao giờ bị chặn khi gửi kết quả
+		snapshots: make(chan snapshotResult, len(symbols)),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	for _, symbol := range symbols {
+		if err := symbol.Fee.Validate(); err != nil {
+			e.onError(symbol.Name, err)
+			continue
+		}
+		e.states[symbol.Name] = &symbolState{symbol: symbol}
+	}
+	return e
+}
+
+// Run nhận event từ events cho tới khi events bị đóng hoặc ctx kết thúc. Symbol
+// bắt đầu được đồng bộ khi nhận event đầu tiên của nó. Sau mỗi event, các event
+// đã có sẵn trong events (tối đa bằng buffer size) được apply tiếp, rồi mỗi
+// symbol thay đổi được publish một lần.
+func (e *Engine) Run(ctx context.Context, events <-chan Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			e.handleEvent(ctx, event)
+			closed := e.drain(ctx, events)
+			e.flush()
+			if closed {
+				return nil
+			}
+		case result := <-e.snapshots:
+			e.handleSnapshot(ctx, result)
+		}
+	}
+}
+
+// drain apply các event đã có sẵn trong events mà không chờ, tối đa
+// bufferSize - 1 event để độ trễ publish có giới hạn. Trả về true nếu events
+// đã bị đóng.
+func (e *Engine) drain(ctx context.Context, events <-chan Event) bool {
+	for range e.bufferSize - 1 {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return true
+			}
+			e.handleEvent(ctx, event)
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// flush publish các symbol đã thay đổi trong batch. Symbol đang đồng bộ lại
+// đã bị xóa khỏi graph nên được bỏ qua.
+func (e *Engine) flush() {
+	for _, state := range e.dirty {
+		state.dirty = false
+		if state.book != nil {
+			e.publish(state)
+		}
+	}
+	e.dirty = e.dirty[:0]
+}
+
+// handleEvent apply event vào order book nếu symbol đã đồng bộ, ngược lại
+// buffer event và bắt đầu fetch snapshot nếu chưa có lần fetch nào đang chạy.
+func (e *Engine) handleEvent(ctx context.Context, event Event) {
+	state, ok := e.states[event.Symbol]
+	if !ok {
+		e.onError(event.Symbol, ErrUnknownSymbol)
+		return
+	}
+
+	if state.book != nil {
+		err := state.book.Apply(event.DiffEvent)
+		switch {
+		case err == nil:
+			if !state.dirty {
+				state.dirty = true
+				e.dirty = append(e.dirty, state)
+			}
+		case errors.Is(err, orderbook.ErrStaleEvent):
+		default:
+			// Order book không còn đúng, đồng bộ lại từ event này
+			e.onError(event.Symbol, err)
+			e.resync(ctx, state)
+			state.buffer = append(state.buffer, event.DiffEvent)
+		}
+		return
+	}
+
+	if len(state.buffer) >= e.bufferSize {
+		state.buffer = append(state.buffer[:0], state.buffer[1:]...)
+	}
+	state.buffer = append(state.buffer, event.DiffEvent)
+	if !state.fetching {
+		state.attempts = 0
+		e.fetch(ctx, state)
+	}
+}
+
+// handleSnapshot kiểm tra snapshot so với buffer, apply các event trong buffer
+// và publish order book vào graph.
+func (e *Engine) handleSnapshot(ctx context.Context, result snapshotResult) {
+	state := e.states[result.symbol]
+	state.fetching = false
+	if result.err != nil {
+		e.onError(result.symbol, result.err)
+		e.retry(ctx, state)
+		return
+	}
+
+	snapshot := result.snapshot
+	if len(state.buffer) > 0 && snapshot.LastUpdateID+1 < state.buffer[0].First() {
+		e.onError(result.symbol, fmt.Errorf("%w: snapshot %d, first event %d",
+			ErrSnapshotTooOld, snapshot.LastUpdateID, state.buffer[0].First()))
+		e.retry(ctx, state)
+		return
+	}
+
+	book, err := orderbook.New(snapshot)
+	if err != nil {
+		e.onError(result.symbol, err)
+		e.retry(ctx, state)
+		return
+	}
+
+	for _, event := range state.buffer {
+		// Event không mới hơn snapshot đã được phản ánh trong snapshot
+		if event.FinalUpdateID <= snapshot.LastUpdateID {
+			continue
+		}
+		if err := book.Apply(event); err != nil {
+			e.onError(result.symbol, err)
+			e.retry(ctx, state)
+			return
+		}
+	}
+
+	state.book = book
+	state.buffer = nil
+	state.attempts = 0
+	e.publish(state)
+}
+
+// fetch bắt đầu fetch snapshot của symbol ở goroutine riêng.
+func (e *Engine) fetch(ctx context.Context, state *symbolState) {
+	state.fetching = true
+	state.attempts++
+	name := state.symbol.Name
+	go func() {
+		snapshot, err := e.source.Snapshot(ctx, name)
+		e.snapshots <- snapshotResult{symbol: name, snapshot: snapshot, err: err}
+	}()
+}
+
+// retry fetch lại snapshot nếu chưa vượt quá maxSnapshotAttempts, ngược lại
+// chờ event tiếp theo của symbol để thử lại.
+func (e *Engine) retry(ctx context.Context, state *symbolState) {
+	if state.attempts < maxSnapshotAttempts && ctx.Err() == nil {
+		e.fetch(ctx, state)
+	}
+}
+
+// resync bỏ order book hiện tại của symbol, xóa các cạnh của symbol khỏi
+// graph để không route qua order book sai, rồi fetch snapshot mới.
+func (e *Engine) resync(ctx context.Context, state *symbolState) {
+	state.book = nil
+	state.buffer = nil
+	e.graph.ReplacePair(state.symbol.pairKey())
+	if !state.fetching {
+		state.attempts = 0
+		e.fetch(ctx, state)
+	}
+}
+
+// publish cập nhật cạnh của symbol và cạnh đảo ngược vào graph trong một lần
+// thay thế. Cả hai cạnh dùng trực tiếp view của order book, cạnh đảo ngược
+// dùng các order đảo ngược được order book cập nhật theo từng price level.
+func (e *Engine) publish(state *symbolState) {
+	symbol := state.symbol
+	view := state.book.View()
+	edge := view.OrderEdge(symbol.Base, symbol.Quote)
+	edge.Fee = symbol.Fee
+	edge.Precisions = symbol.Precisions
+	edge.Venue = symbol.Venue
+	e.graph.ReplacePair(symbol.pairKey(), edge, view.ReverseEdge(edge))
+}
@@ -0,0 +1,341 @@
+package depthsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/orderbook"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func level(price, quantity string) []route.Order {
+	return []route.Order{{Price: d(price), Quantity: d(quantity)}}
+}
+
+// fakeSource trả về lần lượt các snapshot được gửi vào channel snapshots,
+// mỗi lần gọi Snapshot chờ cho tới khi test gửi snapshot tiếp theo.
+type fakeSource struct {
+	snapshots chan orderbook.Snapshot
+
+	mu    sync.Mutex
+	calls int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{snapshots: make(chan orderbook.Snapshot)}
+}
+
+func (s *fakeSource) Snapshot(ctx context.Context, symbol string) (orderbook.Snapshot, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	select {
+	case snapshot := <-s.snapshots:
+		return snapshot, nil
+	case <-ctx.Done():
+		return orderbook.Snapshot{}, ctx.Err()
+	}
+}
+
+func (s *fakeSource) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// notifyGraph báo lại mỗi lần ReplacePair được gọi, dùng để chờ Engine xử lý
+// xong mà không cần sleep.
+type notifyGraph struct {
+	route.Graph
+	replaced chan int
+}
+
+func (g *notifyGraph) ReplacePair(key route.PairKey, edges ...route.Edge) (uint64, error) {
+	version, err := g.Graph.ReplacePair(key, edges...)
+	g.replaced <- len(edges)
+	return version, err
+}
+
+// engineTest chạy Engine với một symbol KNCUSDT trong goroutine riêng.
+type engineTest struct {
+	t      *testing.T
+	graph  *notifyGraph
+	source *fakeSource
+	events chan Event
+	errs   chan error
+}
+
+func startEngine(t *testing.T) *engineTest {
+	t.Helper()
+	et := &engineTest{
+		t:      t,
+		graph:  &notifyGraph{Graph: route.NewGraph(), replaced: make(chan int, 100)},
+		source: newFakeSource(),
+		events: make(chan Event),
+		errs:   make(chan error, 100),
+	}
+	engine := NewEngine(et.graph, et.source,
+		[]Symbol{{Name: "KNCUSDT", Base: "KNC", Quote: "USDT"}},
+		WithErrorHandler(func(symbol string, err error) { et.errs <- err }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.Run(ctx, et.events)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return et
+}
+
+func (et *engineTest) send(first, final uint64, bids, asks []route.Order) {
+	et.events <- Event{Symbol: "KNCUSDT", DiffEvent: orderbook.DiffEvent{
+		FirstUpdateID: first, FinalUpdateID: final, Bids: bids, Asks: asks,
+	}}
+}
+
+// waitReplaced chờ lần ReplacePair tiếp theo và trả về số cạnh được thay.
+func (et *engineTest) waitReplaced() int {
+	et.t.Helper()
+	select {
+	case n := <-et.graph.replaced:
+		return n
+	case <-time.After(time.Second):
+		et.t.Fatal("timeout waiting for graph update")
+		return 0
+	}
+}
+
+func (et *engineTest) waitError() error {
+	et.t.Helper()
+	select {
+	case err := <-et.errs:
+		return err
+	case <-time.After(time.Second):
+		et.t.Fatal("timeout waiting for sync error")
+		return nil
+	}
+}
+
+func (et *engineTest) assertBestBid(want string) {
+	et.t.Helper()
+	price, _, err := et.graph.BestBidPrice("KNC", "USDT", d("1"))
+	if err != nil || !price.Equal(d(want)) {
+		et.t.Errorf("BestBidPrice() = (%v, %v), want %s", price, err, want)
+	}
+}
+
+func TestEngine_BuffersUntilSnapshot(t *testing.T) {
+	et := startEngine(t)
+
+	// Các event đến trước snapshot được buffer
+	et.send(99, 100, level("1.0", "5"), nil)
+	et.send(101, 102, level("1.1", "5"), level("1.5", "5"))
+	et.send(103, 103, level("1.2", "5"), nil)
+
+	// Event 99-100 đã có trong snapshot, event 101-102 và 103 được apply
+	et.source.snapshots <- orderbook.Snapshot{
+		LastUpdateID: 100,
+		Bids:         level("1.0", "5"),
+		Asks:         level("2.0", "5"),
+	}
+	if n := et.waitReplaced(); n != 2 {
+		t.Fatalf("ReplacePair() with %d edges, want 2", n)
+	}
+	et.assertBestBid("1.2")
+
+	// Event mới được apply trực tiếp
+	et.send(104, 104, level("1.2", "0"), nil)
+	et.waitReplaced()
+	et.assertBestBid("1.1")
+}
+
+func TestEngine_RefetchesTooOldSnapshot(t *testing.T) {
+	et := startEngine(t)
+
+	et.send(50, 51, level("1.1", "5"), level("1.5", "5"))
+	et.source.snapshots <- orderbook.Snapshot{LastUpdateID: 10, Bids: level("1.0", "5")}
+	if err := et.waitError(); !errors.Is(err, ErrSnapshotTooOld) {
+		t.Fatalf("error = %v, want ErrSnapshotTooOld", err)
+	}
+
+	et.source.snapshots <- orderbook.Snapshot{LastUpdateID: 49, Bids: level("1.0", "5")}
+	et.waitReplaced()
+	et.assertBestBid("1.1")
+	if calls := et.source.Calls(); calls != 2 {
+		t.Errorf("Snapshot() called %d times, want 2", calls)
+	}
+}
+
+func TestEngine_ResyncsOnGap(t *testing.T) {
+	et := startEngine(t)
+
+	et.send(11, 11, nil, nil)
+	et.source.snapshots <- orderbook.Snapshot{LastUpdateID: 10, Bids: level("1.0", "5"), Asks: level("2.0", "5")}
+	et.waitReplaced()
+
+	// Mất event 12-19, cạnh bị xóa khỏi graph cho tới khi đồng bộ lại
+	et.send(20, 21, level("1.3", "5"), nil)
+	if err := et.waitError(); !errors.Is(err, orderbook.ErrOutOfSequence) {
+		t.Fatalf("error = %v, want ErrOutOfSequence", err)
+	}
+	if n := et.waitReplaced(); n != 0 {
+		t.Fatalf("ReplacePair() with %d edges, want 0", n)
+	}
+	if _, _, err := et.graph.BestBidPrice("KNC", "USDT", d("1")); !errors.Is(err, route.ErrNoRoute) {
+		t.Errorf("BestBidPrice() error = %v, want ErrNoRoute", err)
+	}
+
+	et.source.snapshots <- orderbook.Snapshot{LastUpdateID: 19, Bids: level("1.0", "5"), Asks: level("2.0", "5")}
+	et.waitReplaced()
+	et.assertBestBid("1.3")
+}
+
+func TestEngine_BufferIsBounded(t *testing.T) {
+	graph := route.NewGraph()
+	engine := NewEngine(graph, newFakeSource(),
+		[]Symbol{{Name: "KNCUSDT", Base: "KNC", Quote: "USDT"}}, WithBufferSize(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for id := uint64(1); id <= 5; id++ {
+		engine.handleEvent(ctx, Event{Symbol: "KNCUSDT",
+			DiffEvent: orderbook.DiffEvent{FinalUpdateID: id}})
+	}
+
+	buffer := engine.states["KNCUSDT"].buffer
+	if len(buffer) != 2 || buffer[0].FinalUpdateID != 4 || buffer[1].FinalUpdateID != 5 {
+		t.Errorf("buffer = %+v, want events 4 and 5", buffer)
+	}
+}
+
+func TestEngine_RejectsInvalidFee(t *testing.T) {
+	var errs []error
+	engine := NewEngine(route.NewGraph(), newFakeSource(),
+		[]Symbol{
+			{Name: "KNCUSDT", Base: "KNC", Quote: "USDT", Fee: route.Fee{Bps: d("10000")}},
+			{Name: "ETHUSDT", Base: "ETH", Quote: "USDT", Fee: route.Fee{Bps: d("10")}},
+		},
+		WithErrorHandler(func(symbol string, err error) { errs = append(errs, err) }))
+
+	if len(errs) != 1 || !errors.Is(errs[0], route.ErrInvalidFee) {
+		t.Errorf("errors = %v, want one %v", errs, route.ErrInvalidFee)
+	}
+	if _, ok := engine.states["KNCUSDT"]; ok {
+		t.Errorf("symbol KNCUSDT with invalid fee was added")
+	}
+	if _, ok := engine.states["ETHUSDT"]; !ok {
+		t.Errorf("symbol ETHUSDT was not added")
+	}
+}
+
+// syncedEngine tạo Engine có symbol KNCUSDT đã đồng bộ từ snapshot, không chạy
+// Run, dùng để gọi trực tiếp các bước xử lý event.
+func syncedEngine(t testing.TB, graph route.Graph, snapshot orderbook.Snapshot) *Engine {
+	t.Helper()
+	engine := NewEngine(graph, newFakeSource(),
+		[]Symbol{{Name: "KNCUSDT", Base: "KNC", Quote: "USDT", Venue: "binance"}})
+	book, err := orderbook.New(snapshot)
+	if err != nil {
+		t.Fatalf("orderbook.New() error = %v", err)
+	}
+	engine.states["KNCUSDT"].book = book
+	return engine
+}
+
+func TestEngine_PublishesOncePerBatch(t *testing.T) {
+	graph := &notifyGraph{Graph: route.NewGraph(), replaced: make(chan int, 10)}
+	engine := syncedEngine(t, graph, orderbook.Snapshot{
+		LastUpdateID: 10, Bids: level("1.0", "5"), Asks: level("2.0", "5"),
+	})
+
+	// Các event đã nằm sẵn trong channel được apply thành một batch
+	events := make(chan Event, 3)
+	for id, bid := range []string{"1.1", "1.2", "1.3"} {
+		events <- Event{Symbol: "KNCUSDT", DiffEvent: orderbook.DiffEvent{
+			FinalUpdateID: uint64(11 + id), Bids: level(bid, "5"),
+		}}
+	}
+	close(events)
+	if err := engine.Run(context.Background(), events); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if n := len(graph.replaced); n != 1 {
+		t.Fatalf("ReplacePair() called %d times, want 1", n)
+	}
+	price, _, err := graph.BestBidPrice("KNC", "USDT", d("1"))
+	if err != nil || !price.Equal(d("1.3")) {
+		t.Errorf("BestBidPrice() = (%v, %v), want 1.3", price, err)
+	}
+
+	// Cạnh đảo ngược dùng order đảo ngược của order book, giống GetReverseEdge
+	var forward, reverse route.OrderEdge
+	for _, e := range graph.Neighbors("KNC") {
+		forward = e.(route.OrderEdge)
+	}
+	for _, e := range graph.Neighbors("USDT") {
+		reverse = e.(route.OrderEdge)
+	}
+	if want := forward.GetReverseEdge(); !reflect.DeepEqual(reverse, want) {
+		t.Errorf("reverse edge = %+v, want %+v", reverse, want)
+	}
+}
+
+// BenchmarkEngine_Event đo chi phí của mỗi diff event, gồm cả phần publish
+// được chia đều cho batch DefaultBufferSize event, với order book có độ sâu
+// khác nhau. Event cập nhật, xóa hoặc thêm lại price level ở gần top of book
+// như luồng depth thực tế. Chi phí mỗi event không tăng tuyến tính theo độ sâu,
+// chỉ binary search tăng theo log của độ sâu.
+func BenchmarkEngine_Event(b *testing.B) {
+	for _, depth := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			// Bids 1000, 999, ..., asks 1001, 1002, ... với bước giá 0.01
+			snapshot := orderbook.Snapshot{LastUpdateID: 1}
+			for i := range depth {
+				snapshot.Bids = append(snapshot.Bids, route.Order{
+					Price: decimal.NewFromInt(int64(100000 - i)).Quo(decimal.NewFromInt(100)), Quantity: d("5"),
+				})
+				snapshot.Asks = append(snapshot.Asks, route.Order{
+					Price: decimal.NewFromInt(int64(100100 + i)).Quo(decimal.NewFromInt(100)), Quantity: d("5"),
+				})
+			}
+			engine := syncedEngine(b, route.NewGraph(), snapshot)
+			ctx := context.Background()
+
+			rng := rand.New(rand.NewPCG(1, 2))
+			quantities := []decimal.Decimal{decimal.Zero, d("1"), d("2.5"), d("7")}
+			b.ResetTimer()
+			for i := range b.N {
+				// Một trong 20 price level tốt nhất, khối lượng 0 là xóa level
+				level := []route.Order{{
+					Price:    snapshot.Bids[rng.IntN(20)].Price,
+					Quantity: quantities[rng.IntN(len(quantities))],
+				}}
+				event := orderbook.DiffEvent{FinalUpdateID: uint64(i + 2), Bids: level}
+				if i%2 == 1 {
+					level[0].Price = snapshot.Asks[rng.IntN(20)].Price
+					event.Bids, event.Asks = nil, level
+				}
+				engine.handleEvent(ctx, Event{Symbol: "KNCUSDT", DiffEvent: event})
+				if (i+1)%DefaultBufferSize == 0 {
+					engine.flush()
+				}
+			}
+			engine.flush()
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package input đọc file input của simple problem và expanded problem, như mô
+// tả trong docs/simple_n_expanded_problem.md, và tạo các cạnh của đồ thị
+// route.
+//
+// Input được đọc theo dòng, bỏ qua dòng trống và comment bắt đầu bằng #. Số
+// cặp giao dịch, số orders, giá, khối lượng và tên token đều được kiểm tra,
+// lỗi trả về là *SyntaxError cho biết dòng và cột bị sai.
+//
+// Scenario có thể đọc từ file JSON hoặc YAML, gồm nhiều query và các cặp giao
+// dịch có phí và exchange, xem ParseJSON và Read.
+package input
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// ErrSyntax trả về khi input sai định dạng, xem SyntaxError.
+var ErrSyntax = errors.New("input: syntax error")
+
+// SyntaxError cho biết vị trí sai định dạng trong input.
+//   - File: đường dẫn file, rỗng nếu input không đọc từ file
+//   - Line, Column: dòng và cột (tính theo ký tự) bị sai, bắt đầu từ 1, là 0
+//     nếu không xác định được (lỗi cú pháp YAML không có cột)
+//   - Msg: mô tả lỗi
+//
+// errors.Is(err, ErrSyntax) trả về true với lỗi này.
+type SyntaxError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	var parts []string
+	if e.File != "" {
+		parts = append(parts, e.File)
+	}
+	if e.Line > 0 {
+		parts = append(parts, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			parts = append(parts, strconv.Itoa(e.Column))
+		}
+	}
+	if len(parts) == 0 {
+		return e.Msg
+	}
+	return strings.Join(parts, ":") + ": " + e.Msg
+}
+
+// Is cho phép so sánh errors.Is(err, ErrSyntax).
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+// Query là query của input: tìm giá bán và mua Amount Base token lấy Quote
+// token.
+type Query struct {
+	Base   string
+	Quote  string
+	Amount decimal.Decimal
+}
+
+// SimplePair là một cặp giao dịch của simple problem, giá ask và bid của một
+// Base token tính bằng Quote token.
+type SimplePair struct {
+	Base  string
+	Quote string
+	Ask   decimal.Decimal
+	Bid   decimal.Decimal
+}
+
+// Simple là input của simple problem. Query.Amount luôn là 1.
+type Simple struct {
+	Query Query
+	Pairs []SimplePair
+}
+
+// Edges trả về các cạnh SimpleEdge của các cặp giao dịch, mỗi cặp gồm cạnh gốc
+// và cạnh đảo ngược.
+func (s Simple) Edges() []route.Edge {
+	edges := make([]route.Edge, 0, 2*len(s.Pairs))
+	for _, pair := range s.Pairs {
+		edge := route.SimpleEdge{
+			BaseToken:  pair.Base,
+			QuoteToken: pair.Quote,
+			AskPrice:   pair.Ask,
+			BidPrice:   pair.Bid,
+		}
+		edges = append(edges, edge, edge.GetReverseEdge())
+	}
+	return edges
+}
+
+// OrderBook là order book của một cặp giao dịch của expanded problem, theo
+// thứ tự trong input.
+type OrderBook struct {
+	Base  string
+	Quote string
+	Asks  []route.Order
+	Bids  []route.Order
+}
+
+// Expanded là input của expanded problem.
+type Expanded struct {
+	Query Query
+	Books []OrderBook
+}
+
+// Edges trả về các cạnh OrderEdge của các order book gắn với exchange venue
+// (rỗng nếu không phân biệt exchange), mỗi order book gồm cạnh gốc và cạnh
+// đảo ngược.
+func (e Expanded) Edges(venue string) []route.Edge {
+	edges := make([]route.Edge, 0, 2*len(e.Books))
+	for _, book := range e.Books {
+		edge := route.OrderEdge{
+			BaseToken:  book.Base,
+			QuoteToken: book.Quote,
+			AskOrders:  book.Asks,
+			BidOrders:  book.Bids,
+			Venue:      venue,
+		}
+		edges = append(edges, edge, edge.GetReverseEdge())
+	}
+	return edges
+}
+
+// ReadSimple đọc input của simple problem từ file, xem ParseSimple.
+func ReadSimple(path string) (Simple, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Simple{}, err
+	}
+	defer file.Close()
+
+	s, err := ParseSimple(file)
+	return s, withFile(err, path)
+}
+
+// ReadExpanded đọc input của expanded problem từ file, xem ParseExpanded.
+func ReadExpanded(path string) (Expanded, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Expanded{}, err
+	}
+	defer file.Close()
+
+	e, err := ParseExpanded(file)
+	return e, withFile(err, path)
+}
+
+// withFile gắn đường dẫn file vào SyntaxError.
+func withFile(err error, path string) error {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		syntaxErr.File = path
+	}
+	return err
+}
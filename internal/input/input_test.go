@@ -0,0 +1,233 @@
+package input
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+const simpleInput = `KNC ETH
+2
+KNC USDT 1.1 0.9
+ETH USDT 360 355
+`
+
+const expandedInput = `KNC ETH 100
+2
+KNC USDT
+2
+1.1 150
+1.2 200
+2
+0.9 100
+0.8 300
+ETH USDT
+2
+360 1000
+365 500
+2
+355 800
+350 600
+`
+
+func TestParseSimple(t *testing.T) {
+	got, err := ParseSimple(strings.NewReader(simpleInput))
+	if err != nil {
+		t.Fatalf("ParseSimple() error = %v", err)
+	}
+	if got.Query.Base != "KNC" || got.Query.Quote != "ETH" || !got.Query.Amount.Equal(decimal.One) {
+		t.Errorf("Query = %+v, want KNC ETH 1", got.Query)
+	}
+	if len(got.Pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2", len(got.Pairs))
+	}
+	pair := got.Pairs[1]
+	if pair.Base != "ETH" || pair.Quote != "USDT" || !pair.Ask.Equal(d("360")) || !pair.Bid.Equal(d("355")) {
+		t.Errorf("Pairs[1] = %+v, want ETH USDT 360 355", pair)
+	}
+	if edges := got.Edges(); len(edges) != 4 || edges[1].From() != "USDT" {
+		t.Errorf("Edges() = %v, want 4 edges with reverse edges", edges)
+	}
+}
+
+func TestParseExpanded(t *testing.T) {
+	// Comment, dòng trống và khoảng trắng thừa được bỏ qua
+	input := "# query\n\n  KNC   ETH 100  # amount\n" + strings.TrimPrefix(expandedInput, "KNC ETH 100\n")
+	got, err := ParseExpanded(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseExpanded() error = %v", err)
+	}
+	if got.Query.Base != "KNC" || got.Query.Quote != "ETH" || !got.Query.Amount.Equal(d("100")) {
+		t.Errorf("Query = %+v, want KNC ETH 100", got.Query)
+	}
+	if len(got.Books) != 2 {
+		t.Fatalf("got %d books, want 2", len(got.Books))
+	}
+	book := got.Books[0]
+	if len(book.Asks) != 2 || len(book.Bids) != 2 || !book.Asks[1].Price.Equal(d("1.2")) ||
+		!book.Bids[1].Quantity.Equal(d("300")) {
+		t.Errorf("Books[0] = %+v", book)
+	}
+	if edges := got.Edges("binance"); len(edges) != 4 {
+		t.Errorf("Edges() returned %d edges, want 4", len(edges))
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		expanded bool
+		input    string
+		wantLine int
+		wantCol  int
+		wantMsg  string
+	}{
+		{name: "Empty", input: "", wantLine: 1, wantCol: 1,
+			wantMsg: "unexpected end of input, expected base quote"},
+		{name: "Missing quote", input: "KNC\n", wantLine: 1, wantCol: 4, wantMsg: "missing quote"},
+		{name: "Extra field", input: "KNC ETH 1\n", wantLine: 1, wantCol: 9,
+			wantMsg: `unexpected "1" after quote`},
+		{name: "Same base and quote", input: "KNC KNC\n", wantLine: 1, wantCol: 5,
+			wantMsg: `quote token must differ from base token "KNC"`},
+		{name: "Invalid token", input: "KNC E$H\n", wantLine: 1, wantCol: 5,
+			wantMsg: `invalid quote token "E$H"`},
+		{name: "Invalid pair count", input: "KNC ETH\n-1\n", wantLine: 2, wantCol: 1,
+			wantMsg: `invalid pair count "-1", expected a non-negative integer`},
+		{name: "Too many pairs", input: "KNC ETH\n99999999999999999999\n", wantLine: 2, wantCol: 1,
+			wantMsg: "pair count 99999999999999999999 exceeds 1000000"},
+		{name: "Missing pair", input: "KNC ETH\n2\nKNC USDT 1.1 0.9\n", wantLine: 4, wantCol: 1,
+			wantMsg: "unexpected end of input, expected base quote ask bid"},
+		{name: "Missing bid", input: "KNC ETH\n1\n\n# pair\nKNC USDT 1.1\n", wantLine: 5, wantCol: 13,
+			wantMsg: "missing bid"},
+		{name: "Invalid price", input: "KNC ETH\n1\nKNC USDT 1.1x 0.9\n", wantLine: 3, wantCol: 10,
+			wantMsg: `invalid ask price "1.1x"`},
+		{name: "Zero price", input: "KNC ETH\n1\nKNC USDT 1.1 0\n", wantLine: 3, wantCol: 14,
+			wantMsg: `bid price must be positive, got "0"`},
+		{name: "Duplicate pair", input: "KNC ETH\n2\nKNC USDT 1.1 0.9\nUSDT KNC 1.2 0.8\n",
+			wantLine: 4, wantCol: 1, wantMsg: "duplicate pair USDT/KNC, first defined on line 3"},
+		{name: "Extra pair", input: "KNC ETH\n1\nKNC USDT 1.1 0.9\nETH USDT 360 355\n",
+			wantLine: 4, wantCol: 1, wantMsg: `unexpected "ETH" after last pair`},
+		{name: "Invalid UTF-8", input: "KNC ETH\n\xff\n", wantLine: 2, wantCol: 1, wantMsg: "invalid UTF-8"},
+		{name: "Column counts characters", input: "KNC ETH\n1\nKNC USDT 1.1 0.9 ví dụ\n",
+			wantLine: 3, wantCol: 18, wantMsg: `unexpected "ví" after bid`},
+		{name: "Missing amount", expanded: true, input: "KNC ETH\n", wantLine: 1, wantCol: 8,
+			wantMsg: "missing amount"},
+		{name: "Negative amount", expanded: true, input: "KNC ETH -5\n", wantLine: 1, wantCol: 9,
+			wantMsg: `amount must be positive, got "-5"`},
+		{name: "Missing ask count", expanded: true, input: "KNC ETH 1\n1\nKNC USDT\n", wantLine: 4,
+			wantCol: 1, wantMsg: "unexpected end of input, expected ask count"},
+		{name: "Missing bids", expanded: true, input: "KNC ETH 1\n1\nKNC USDT\n1\n1.1 150\n2\n0.9 100\n",
+			wantLine: 8, wantCol: 1, wantMsg: "unexpected end of input, expected bid price bid quantity"},
+		{name: "Invalid quantity", expanded: true, input: "KNC ETH 1\n1\nKNC USDT\n1\n1.1 abc\n0\n",
+			wantLine: 5, wantCol: 5, wantMsg: `invalid ask quantity "abc"`},
+		{name: "Order count as pair", expanded: true, input: "KNC ETH 1\n1\nKNC USDT\n1 2\n",
+			wantLine: 4, wantCol: 3, wantMsg: `unexpected "2" after ask count`},
+		{name: "Long line", input: "KNC ETH\n1\n" + strings.Repeat("x", maxLineLength+1) + "\n",
+			wantLine: 3, wantCol: 1, wantMsg: "line too long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.expanded {
+				_, err = ParseExpanded(strings.NewReader(tt.input))
+			} else {
+				_, err = ParseSimple(strings.NewReader(tt.input))
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrSyntax) {
+				t.Fatalf("error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Line != tt.wantLine || syntaxErr.Column != tt.wantCol || syntaxErr.Msg != tt.wantMsg {
+				t.Errorf("error = %d:%d: %s, want %d:%d: %s", syntaxErr.Line, syntaxErr.Column,
+					syntaxErr.Msg, tt.wantLine, tt.wantCol, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestReadExpanded_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("KNC ETH 100\nx\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadExpanded(path)
+	want := path + `:2:1: invalid pair count "x", expected a non-negative integer`
+	if err == nil || err.Error() != want {
+		t.Errorf("ReadExpanded() error = %v, want %s", err, want)
+	}
+
+	if _, err := ReadExpanded(filepath.Join(t.TempDir(), "missing.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadExpanded(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+// checkParseError kiểm tra lỗi của parser là *SyntaxError có vị trí nằm trong
+// input.
+func checkParseError(t *testing.T, input string, err error) {
+	t.Helper()
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("error = %v, want *SyntaxError", err)
+	}
+	lines := strings.Count(input, "\n") + 1
+	if syntaxErr.Line < 1 || syntaxErr.Line > lines+1 || syntaxErr.Column < 1 {
+		t.Errorf("error position %d:%d outside input of %d lines", syntaxErr.Line, syntaxErr.Column, lines)
+	}
+}
+
+func FuzzParseSimple(f *testing.F) {
+	f.Add(simpleInput)
+	f.Add("KNC ETH\n1 # one pair\n\nKNC USDT 1.1e0 .9\n")
+	f.Add("KNC ETH\n1\nKNC USDT 1.1\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		got, err := ParseSimple(strings.NewReader(input))
+		if err != nil {
+			checkParseError(t, input, err)
+			return
+		}
+		for _, pair := range got.Pairs {
+			if pair.Base == pair.Quote || !pair.Ask.IsPositive() || !pair.Bid.IsPositive() {
+				t.Errorf("invalid pair %+v accepted", pair)
+			}
+		}
+		if len(got.Edges()) != 2*len(got.Pairs) {
+			t.Errorf("Edges() returned %d edges for %d pairs", len(got.Edges()), len(got.Pairs))
+		}
+	})
+}
+
+func FuzzParseExpanded(f *testing.F) {
+	f.Add(expandedInput)
+	f.Add("KNC ETH 1\n1\nKNC USDT\n0\n0\n")
+	f.Add("KNC ETH 1\n1\nKNC USDT\n1\n1.1 150\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		got, err := ParseExpanded(strings.NewReader(input))
+		if err != nil {
+			checkParseError(t, input, err)
+			return
+		}
+		if !got.Query.Amount.IsPositive() {
+			t.Errorf("amount %s accepted", got.Query.Amount)
+		}
+		for _, book := range got.Books {
+			for _, order := range append(book.Asks, book.Bids...) {
+				if !order.Price.IsPositive() || !order.Quantity.IsPositive() {
+					t.Errorf("invalid order %+v accepted", order)
+				}
+			}
+		}
+		if len(got.Edges("")) != 2*len(got.Books) {
+			t.Errorf("Edges() returned %d edges for %d books", len(got.Edges("")), len(got.Books))
+		}
+	})
+}
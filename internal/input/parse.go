@@ -0,0 +1,204 @@
+package input
+
+import (
+	"io"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// ParseSimple đọc input của simple problem:
+//
+//	KNC ETH            # base quote
+//	2                  # số cặp giao dịch n
+//	KNC USDT 1.1 0.9   # n dòng: base quote ask bid
+//	ETH USDT 360 355
+//
+// Giá ask và bid phải dương, mỗi cặp token chỉ xuất hiện một lần (theo cả
+// hai chiều).
+func ParseSimple(r io.Reader) (Simple, error) {
+	s := newLineScanner(r)
+	query, err := parseQuery(s, false)
+	if err != nil {
+		return Simple{}, err
+	}
+	n, err := parsePairCount(s)
+	if err != nil {
+		return Simple{}, err
+	}
+
+	pairs := pairSet{}
+	var result []SimplePair
+	for range n {
+		l, err := s.expect("base", "quote", "ask", "bid")
+		if err != nil {
+			return Simple{}, err
+		}
+		base, quote, err := pairs.add(l)
+		if err != nil {
+			return Simple{}, err
+		}
+		ask, err := parsePositive(l, l.fields[2], "ask price")
+		if err != nil {
+			return Simple{}, err
+		}
+		bid, err := parsePositive(l, l.fields[3], "bid price")
+		if err != nil {
+			return Simple{}, err
+		}
+		result = append(result, SimplePair{Base: base, Quote: quote, Ask: ask, Bid: bid})
+	}
+	if err := s.end(); err != nil {
+		return Simple{}, err
+	}
+	return Simple{Query: query, Pairs: result}, nil
+}
+
+// ParseExpanded đọc input của expanded problem:
+//
+//	KNC ETH 100   # base quote amount
+//	2             # số cặp giao dịch n
+//	KNC USDT      # n block, mỗi block: base quote
+//	2             #   số ask orders
+//	1.1 150       #   mỗi dòng: price quantity
+//	1.2 200
+//	1             #   số bid orders
+//	0.9 100
+//	...
+//
+// Amount, giá và khối lượng phải dương, mỗi cặp token chỉ xuất hiện một lần
+// (theo cả hai chiều). Một phía của order book có thể không có order nào.
+func ParseExpanded(r io.Reader) (Expanded, error) {
+	s := newLineScanner(r)
+	query, err := parseQuery(s, true)
+	if err != nil {
+		return Expanded{}, err
+	}
+	n, err := parsePairCount(s)
+	if err != nil {
+		return Expanded{}, err
+	}
+
+	pairs := pairSet{}
+	var books []OrderBook
+	for range n {
+		l, err := s.expect("base", "quote")
+		if err != nil {
+			return Expanded{}, err
+		}
+		base, quote, err := pairs.add(l)
+		if err != nil {
+			return Expanded{}, err
+		}
+		asks, err := parseOrders(s, "ask")
+		if err != nil {
+			return Expanded{}, err
+		}
+		bids, err := parseOrders(s, "bid")
+		if err != nil {
+			return Expanded{}, err
+		}
+		books = append(books, OrderBook{Base: base, Quote: quote, Asks: asks, Bids: bids})
+	}
+	if err := s.end(); err != nil {
+		return Expanded{}, err
+	}
+	return Expanded{Query: query, Books: books}, nil
+}
+
+// parseQuery đọc dòng query "base quote" hoặc "base quote amount" nếu
+// withAmount, amount mặc định là 1.
+func parseQuery(s *lineScanner, withAmount bool) (Query, error) {
+	names := []string{"base", "quote"}
+	if withAmount {
+		names = append(names, "amount")
+	}
+	l, err := s.expect(names...)
+	if err != nil {
+		return Query{}, err
+	}
+
+	query := Query{Amount: decimal.One}
+	if query.Base, err = parseToken(l, l.fields[0], "base token"); err != nil {
+		return Query{}, err
+	}
+	if query.Quote, err = parseToken(l, l.fields[1], "quote token"); err != nil {
+		return Query{}, err
+	}
+	if query.Base == query.Quote {
+		return Query{}, errorAt(l, l.fields[1], "quote token must differ from base token %q", query.Base)
+	}
+	if withAmount {
+		if query.Amount, err = parsePositive(l, l.fields[2], "amount"); err != nil {
+			return Query{}, err
+		}
+	}
+	return query, nil
+}
+
+// parsePairCount đọc dòng số cặp giao dịch.
+func parsePairCount(s *lineScanner) (int, error) {
+	l, err := s.expect("pair count")
+	if err != nil {
+		return 0, err
+	}
+	return parseCount(l, l.fields[0], "pair count")
+}
+
+// parseOrders đọc số orders của một phía order book (side là "ask" hoặc
+// "bid") và các dòng "price quantity" tiếp theo.
+func parseOrders(s *lineScanner, side string) ([]route.Order, error) {
+	l, err := s.expect(side + " count")
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseCount(l, l.fields[0], side+" count")
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []route.Order
+	for range n {
+		l, err := s.expect(side+" price", side+" quantity")
+		if err != nil {
+			return nil, err
+		}
+		price, err := parsePositive(l, l.fields[0], side+" price")
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := parsePositive(l, l.fields[1], side+" quantity")
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, route.Order{Price: price, Quantity: quantity})
+	}
+	return orders, nil
+}
+
+// pairSet lưu các cặp token đã đọc và dòng định nghĩa chúng, để phát hiện cặp
+// bị lặp lại.
+type pairSet map[[2]string]int
+
+// add đọc tên base và quote token ở hai trường đầu của l và thêm cặp vào set.
+func (p pairSet) add(l line) (string, string, error) {
+	base, err := parseToken(l, l.fields[0], "base token")
+	if err != nil {
+		return "", "", err
+	}
+	quote, err := parseToken(l, l.fields[1], "quote token")
+	if err != nil {
+		return "", "", err
+	}
+	if base == quote {
+		return "", "", errorAt(l, l.fields[1], "quote token must differ from base token %q", base)
+	}
+
+	key := [2]string{min(base, quote), max(base, quote)}
+	if first, ok := p[key]; ok {
+		return "", "", errorAt(l, l.fields[0], "duplicate pair %s/%s, first defined on line %d",
+			base, quote, first)
+	}
+	p[key] = l.number
+	return base, quote, nil
+}
@@ -0,0 +1,204 @@
+package input
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+const (
+	// maxLineLength là độ dài tối đa của một dòng input.
+	maxLineLength = 1 << 20
+
+	// maxCount là giá trị tối đa của số cặp giao dịch và số orders, tránh cấp
+	// phát bộ nhớ quá lớn với input sai.
+	maxCount = 1_000_000
+
+	// maxTokenLength là độ dài tối đa của tên token.
+	maxTokenLength = 32
+)
+
+// field là một trường của dòng input cùng vị trí cột (tính theo ký tự, bắt
+// đầu từ 1).
+type field struct {
+	text   string
+	column int
+}
+
+// line là một dòng input có dữ liệu, đã bỏ comment.
+//   - number: số thứ tự dòng trong file, bắt đầu từ 1
+//   - end: cột ngay sau trường cuối cùng, dùng để báo lỗi thiếu trường
+type line struct {
+	number int
+	fields []field
+	end    int
+}
+
+// lineScanner đọc lần lượt các dòng có dữ liệu của input, bỏ qua dòng trống
+// và comment (từ ký tự # tới cuối dòng).
+type lineScanner struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+func newLineScanner(r io.Reader) *lineScanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+	return &lineScanner{scanner: scanner}
+}
+
+// next trả về dòng có dữ liệu tiếp theo, ok = false khi hết input.
+func (s *lineScanner) next() (line, bool, error) {
+	for s.scanner.Scan() {
+		s.number++
+		text := s.scanner.Text()
+		if !utf8.ValidString(text) {
+			return line{}, false, &SyntaxError{Line: s.number, Column: 1, Msg: "invalid UTF-8"}
+		}
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if l := splitLine(s.number, text); len(l.fields) > 0 {
+			return l, true, nil
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return line{}, false, &SyntaxError{Line: s.number + 1, Column: 1, Msg: "line too long"}
+		}
+		return line{}, false, err
+	}
+	return line{}, false, nil
+}
+
+// expect đọc dòng có dữ liệu tiếp theo với đúng các trường names, ví dụ
+// "base", "quote". Lỗi nếu hết input hoặc số trường khác len(names).
+func (s *lineScanner) expect(names ...string) (line, error) {
+	l, ok, err := s.next()
+	if err != nil {
+		return line{}, err
+	}
+	if !ok {
+		return line{}, &SyntaxError{Line: s.number + 1, Column: 1,
+			Msg: "unexpected end of input, expected " + strings.Join(names, " ")}
+	}
+	if len(l.fields) < len(names) {
+		return line{}, &SyntaxError{Line: l.number, Column: l.end,
+			Msg: "missing " + names[len(l.fields)]}
+	}
+	if len(l.fields) > len(names) {
+		extra := l.fields[len(names)]
+		return line{}, &SyntaxError{Line: l.number, Column: extra.column,
+			Msg: fmt.Sprintf("unexpected %q after %s", extra.text, names[len(names)-1])}
+	}
+	return l, nil
+}
+
+// end kiểm tra input không còn dòng có dữ liệu nào.
+func (s *lineScanner) end() error {
+	l, ok, err := s.next()
+	if err != nil || !ok {
+		return err
+	}
+	return &SyntaxError{Line: l.number, Column: l.fields[0].column,
+		Msg: fmt.Sprintf("unexpected %q after last pair", l.fields[0].text)}
+}
+
+// splitLine tách text thành các trường phân cách bởi khoảng trắng.
+func splitLine(number int, text string) line {
+	l := line{number: number, end: 1}
+	column, start := 1, -1
+	for i, r := range text {
+		space := r == ' ' || r == '\t' || r == '\r' || r == '\v' || r == '\f'
+		switch {
+		case space && start >= 0:
+			l.fields = append(l.fields, field{text: text[start:i],
+				column: column - utf8.RuneCountInString(text[start:i])})
+			l.end = column
+			start = -1
+		case !space && start < 0:
+			start = i
+		}
+		column++
+	}
+	if start >= 0 {
+		l.fields = append(l.fields, field{text: text[start:],
+			column: column - utf8.RuneCountInString(text[start:])})
+		l.end = column
+	}
+	return l
+}
+
+// errorAt trả về SyntaxError tại trường f của dòng l.
+func errorAt(l line, f field, format string, args ...any) error {
+	return &SyntaxError{Line: l.number, Column: f.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseToken kiểm tra tên token ở trường f, xem checkToken.
+func parseToken(l line, f field, what string) (string, error) {
+	if msg := checkToken(f.text, what); msg != "" {
+		return "", errorAt(l, f, "%s", msg)
+	}
+	return f.text, nil
+}
+
+// checkToken kiểm tra tên token: bắt đầu bằng chữ cái hoặc chữ số, chỉ gồm
+// chữ cái, chữ số và các ký tự . _ - (ASCII), tối đa maxTokenLength ký tự.
+// Trả về mô tả lỗi, rỗng nếu hợp lệ.
+func checkToken(text, what string) string {
+	if text == "" {
+		return "missing " + what
+	}
+	if len(text) > maxTokenLength {
+		return fmt.Sprintf("%s %q is longer than %d characters", what, text, maxTokenLength)
+	}
+	for i, r := range text {
+		alnum := r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+		if !alnum && (i == 0 || (r != '.' && r != '_' && r != '-')) {
+			return fmt.Sprintf("invalid %s %q", what, text)
+		}
+	}
+	return ""
+}
+
+// parseCount đọc số nguyên không âm, không vượt quá maxCount.
+func parseCount(l line, f field, what string) (int, error) {
+	for _, r := range f.text {
+		if r < '0' || r > '9' {
+			return 0, errorAt(l, f, "invalid %s %q, expected a non-negative integer", what, f.text)
+		}
+	}
+	n, err := strconv.Atoi(f.text)
+	if err != nil || n > maxCount {
+		return 0, errorAt(l, f, "%s %s exceeds %d", what, f.text, maxCount)
+	}
+	return n, nil
+}
+
+// parsePositive đọc số thập phân dương ở trường f, xem checkPositive.
+func parsePositive(l line, f field, what string) (decimal.Decimal, error) {
+	value, msg := checkPositive(f.text, what)
+	if msg != "" {
+		return decimal.Zero, errorAt(l, f, "%s", msg)
+	}
+	return value, nil
+}
+
+// checkPositive đọc số thập phân dương từ text, trả về mô tả lỗi nếu text
+// không hợp lệ.
+func checkPositive(text, what string) (decimal.Decimal, string) {
+	value, err := decimal.NewFromString(text)
+	if err != nil {
+		return decimal.Zero, fmt.Sprintf("invalid %s %q", what, text)
+	}
+	if !value.IsPositive() {
+		return decimal.Zero, fmt.Sprintf("%s must be positive, got %q", what, text)
+	}
+	return value, ""
+}
@@ -0,0 +1,136 @@
+package input
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// Pair là một cặp giao dịch của Scenario.
+//   - Base, Quote: trading pair Base/Quote
+//   - Venue: exchange của pair, rỗng nếu không phân biệt
+//   - Fee: phí taker khi giao dịch qua pair
+//   - Ask, Bid: giá của pair dạng SimpleEdge, dùng khi Book là false
+//   - Book: pair có order book, tạo OrderEdge từ Asks và Bids
+//   - Asks, Bids: các order của order book, theo thứ tự trong input
+type Pair struct {
+	Base  string
+	Quote string
+	Venue string
+	Fee   route.Fee
+	Ask   decimal.Decimal
+	Bid   decimal.Decimal
+	Book  bool
+	Asks  []route.Order
+	Bids  []route.Order
+}
+
+// Edge trả về cạnh Base->Quote của pair, gắn với exchange venue nếu pair
+// không có Venue.
+func (p Pair) Edge(venue string) route.Edge {
+	if p.Venue != "" {
+		venue = p.Venue
+	}
+	if p.Book {
+		return route.OrderEdge{
+			BaseToken:  p.Base,
+			QuoteToken: p.Quote,
+			AskOrders:  p.Asks,
+			BidOrders:  p.Bids,
+			Fee:        p.Fee,
+			Venue:      venue,
+		}
+	}
+	return route.SimpleEdge{
+		BaseToken:  p.Base,
+		QuoteToken: p.Quote,
+		AskPrice:   p.Ask,
+		BidPrice:   p.Bid,
+		Fee:        p.Fee,
+		Venue:      venue,
+	}
+}
+
+// Scenario là input gồm các cặp giao dịch và một hoặc nhiều query, đọc từ
+// file JSON, YAML hoặc định dạng dòng của simple/expanded problem, xem Read.
+type Scenario struct {
+	Queries []Query
+	Pairs   []Pair
+}
+
+// Edges trả về các cạnh của các cặp giao dịch, mỗi cặp gồm cạnh gốc và cạnh
+// đảo ngược. Pair không có Venue được gắn với exchange venue.
+func (s Scenario) Edges(venue string) []route.Edge {
+	edges := make([]route.Edge, 0, 2*len(s.Pairs))
+	for _, pair := range s.Pairs {
+		edge := pair.Edge(venue)
+		edges = append(edges, edge, edge.GetReverseEdge())
+	}
+	return edges
+}
+
+// Scenario chuyển input của simple problem thành Scenario.
+func (s Simple) Scenario() Scenario {
+	pairs := make([]Pair, 0, len(s.Pairs))
+	for _, pair := range s.Pairs {
+		pairs = append(pairs, Pair{Base: pair.Base, Quote: pair.Quote, Ask: pair.Ask, Bid: pair.Bid})
+	}
+	return Scenario{Queries: []Query{s.Query}, Pairs: pairs}
+}
+
+// Scenario chuyển input của expanded problem thành Scenario.
+func (e Expanded) Scenario() Scenario {
+	pairs := make([]Pair, 0, len(e.Books))
+	for _, book := range e.Books {
+		pairs = append(pairs, Pair{Base: book.Base, Quote: book.Quote, Book: true,
+			Asks: book.Asks, Bids: book.Bids})
+	}
+	return Scenario{Queries: []Query{e.Query}, Pairs: pairs}
+}
+
+// Read đọc Scenario từ file, định dạng được chọn theo phần mở rộng:
+//   - .json: JSON, xem ParseJSON
+//   - .yaml, .yml: YAML, xem ParseYAML
+//   - còn lại: định dạng dòng, là expanded problem nếu dòng query có amount,
+//     ngược lại là simple problem
+//
+// Lỗi định dạng là *SyntaxError có File là path.
+func Read(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	var scenario Scenario
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		scenario, err = ParseJSON(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		scenario, err = ParseYAML(bytes.NewReader(data))
+	default:
+		scenario, err = parseText(data)
+	}
+	return scenario, withFile(err, path)
+}
+
+// parseText đọc định dạng dòng của simple hoặc expanded problem, phân biệt
+// theo số trường của dòng có dữ liệu đầu tiên.
+func parseText(data []byte) (Scenario, error) {
+	first, _, _ := newLineScanner(bytes.NewReader(data)).next()
+	if len(first.fields) == 2 {
+		s, err := ParseSimple(bytes.NewReader(data))
+		if err != nil {
+			return Scenario{}, err
+		}
+		return s.Scenario(), nil
+	}
+	e, err := ParseExpanded(bytes.NewReader(data))
+	if err != nil {
+		return Scenario{}, err
+	}
+	return e.Scenario(), nil
+}
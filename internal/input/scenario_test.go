@@ -0,0 +1,297 @@
+package input
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+const scenarioJSON = `{
+	"queries": [
+		{"base": "KNC", "quote": "ETH", "amount": "100"},
+		{"base": "ETH", "quote": "USDT"}
+	],
+	"pairs": [
+		{"base": "KNC", "quote": "USDT", "ask": 1.1, "bid": "0.9"},
+		{"base": "ETH", "quote": "USDT", "venue": "binance",
+		 "fee": {"bps": "10", "side": "base", "fixed": "0.001"},
+		 "asks": [{"price": "360", "quantity": "1000"}, {"price": "365", "quantity": "500"}],
+		 "bids": []}
+	]
+}`
+
+const scenarioYAML = `# cùng scenario với scenarioJSON
+queries:
+  - {base: KNC, quote: ETH, amount: 100}
+  - base: ETH
+    quote: USDT
+pairs:
+  - {base: KNC, quote: USDT, ask: 1.1, bid: "0.9"}
+  - base: ETH
+    quote: USDT
+    venue: binance
+    fee: {bps: 10, side: base, fixed: 0.001}
+    asks:
+      - {price: 360, quantity: 1000}
+      - {price: 365, quantity: 500}
+    bids: []
+`
+
+func TestParseJSON(t *testing.T) {
+	got, err := ParseJSON(strings.NewReader(scenarioJSON))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	if len(got.Queries) != 2 || !got.Queries[0].Amount.Equal(d("100")) || !got.Queries[1].Amount.Equal(d("1")) {
+		t.Errorf("Queries = %+v, want KNC/ETH 100 and ETH/USDT 1", got.Queries)
+	}
+	if len(got.Pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2", len(got.Pairs))
+	}
+	simple := got.Pairs[0]
+	if simple.Book || !simple.Ask.Equal(d("1.1")) || !simple.Bid.Equal(d("0.9")) || simple.Venue != "" {
+		t.Errorf("Pairs[0] = %+v, want KNC USDT 1.1 0.9", simple)
+	}
+	book := got.Pairs[1]
+	if !book.Book || len(book.Asks) != 2 || len(book.Bids) != 0 || book.Venue != "binance" {
+		t.Errorf("Pairs[1] = %+v, want order book on binance with 2 asks", book)
+	}
+	if !book.Fee.Bps.Equal(d("10")) || book.Fee.Side != route.FeeInBase || !book.Fee.Fixed.Equal(d("0.001")) {
+		t.Errorf("Pairs[1].Fee = %+v, want 10 bps in base, fixed 0.001", book.Fee)
+	}
+}
+
+func TestParseYAML_MatchesJSON(t *testing.T) {
+	want, err := ParseJSON(strings.NewReader(scenarioJSON))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	got, err := ParseYAML(strings.NewReader(scenarioYAML))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() = %+v, want %+v", got, want)
+	}
+}
+
+func TestScenario_Edges(t *testing.T) {
+	scenario, err := ParseJSON(strings.NewReader(scenarioJSON))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+
+	edges := scenario.Edges("local")
+	if len(edges) != 4 {
+		t.Fatalf("Edges() returned %d edges, want 4", len(edges))
+	}
+	simple, ok := edges[0].(route.SimpleEdge)
+	if !ok || simple.Venue != "local" || edges[1].From() != "USDT" {
+		t.Errorf("edges[0:2] = %v, want KNC/USDT and reverse on local", edges[:2])
+	}
+	book, ok := edges[2].(route.OrderEdge)
+	if !ok || book.Venue != "binance" || !book.Fee.Bps.Equal(d("10")) {
+		t.Errorf("edges[2] = %+v, want ETH/USDT order book on binance with fee", edges[2])
+	}
+
+	graph := route.NewGraphWithEdges(edges)
+	if _, err := graph.BestAskRoute("KNC", "ETH", d("1")); !errors.Is(err, route.ErrNoRoute) {
+		t.Errorf("BestAskRoute() error = %v, want ErrNoRoute (ETH/USDT has no bids)", err)
+	}
+	if _, err := graph.BestBidRoute("KNC", "ETH", d("1")); err != nil {
+		t.Errorf("BestBidRoute() error = %v", err)
+	}
+}
+
+func TestParseStructured_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     bool
+		input    string
+		wantLine int
+		wantCol  int
+		wantMsg  string
+	}{
+		{name: "Empty JSON", input: "", wantLine: 1, wantCol: 1, wantMsg: "unexpected end of JSON input"},
+		{name: "JSON syntax", input: "{\n  \"queries\": [}\n", wantLine: 2, wantCol: 15,
+			wantMsg: "invalid character '}' looking for beginning of value"},
+		{name: "Trailing data", input: `{"queries": [{"base": "KNC", "quote": "ETH"}]} {}`, wantLine: 1,
+			wantCol: 49, wantMsg: "unexpected data after top-level value"},
+		{name: "Not an object", input: "[]", wantLine: 1, wantCol: 1, wantMsg: "document must be an object"},
+		{name: "Missing queries", input: `{"pairs": []}`, wantLine: 1, wantCol: 1,
+			wantMsg: `missing field "queries" in document`},
+		{name: "No queries", input: "{\n\t\"queries\": []\n}", wantLine: 2, wantCol: 13,
+			wantMsg: "queries must contain at least one query"},
+		{name: "Unknown field", input: `{"queries": [{"base": "KNC", "qoute": "ETH"}]}`, wantLine: 1,
+			wantCol: 30, wantMsg: `unknown field "qoute" in queries[0]`},
+		{name: "Duplicate field", input: `{"queries": [{"base": "KNC", "base": "ETH"}]}`, wantLine: 1,
+			wantCol: 30, wantMsg: `duplicate field "base" in queries[0]`},
+		{name: "Same base and quote", input: `{"queries": [{"base": "KNC", "quote": "KNC"}]}`, wantLine: 1,
+			wantCol: 39, wantMsg: `queries[0].quote must differ from base token "KNC"`},
+		{name: "Column counts characters", input: `{"queries": [{"base": "ví", "quote": "ETH"}]}`,
+			wantLine: 1, wantCol: 23, wantMsg: `invalid queries[0].base "ví"`},
+		{name: "Zero amount", input: `{"queries": [{"base": "KNC", "quote": "ETH", "amount": 0}]}`,
+			wantLine: 1, wantCol: 56, wantMsg: `queries[0].amount must be positive, got "0"`},
+		{name: "Object as amount", input: `{"queries": [{"base": "KNC", "quote": "ETH", "amount": {}}]}`,
+			wantLine: 1, wantCol: 56, wantMsg: "queries[0].amount must be a string or number"},
+		{name: "YAML syntax", yaml: true, input: "queries:\n\t- x\n", wantLine: 2,
+			wantMsg: "found character that cannot start any token"},
+		{name: "Empty YAML", yaml: true, input: "", wantLine: 1, wantCol: 1, wantMsg: "empty document"},
+		{name: "Alias", yaml: true, input: "queries: &q\n  - {base: KNC, quote: ETH}\npairs: *q\n",
+			wantLine: 3, wantCol: 8, wantMsg: "pairs: aliases are not supported"},
+		{name: "Missing bid", yaml: true, input: "queries: [{base: KNC, quote: ETH}]\npairs:\n  - {base: KNC, quote: USDT, ask: 1}\n",
+			wantLine: 3, wantCol: 5, wantMsg: "missing pairs[0].bid, or asks and bids"},
+		{name: "Mixed prices and book", yaml: true,
+			input:    "queries: [{base: KNC, quote: ETH}]\npairs:\n  - {base: KNC, quote: USDT, ask: 1, asks: []}\n",
+			wantLine: 3, wantCol: 35, wantMsg: "pairs[0].ask cannot be combined with asks and bids"},
+		{name: "Invalid price", yaml: true,
+			input:    "queries: [{base: KNC, quote: ETH}]\npairs:\n  - base: KNC\n    quote: USDT\n    bids:\n      - {price: 1.1x, quantity: 1}\n",
+			wantLine: 6, wantCol: 17, wantMsg: `invalid pairs[0].bids[0].price "1.1x"`},
+		{name: "Fee too high", yaml: true,
+			input:    "queries: [{base: KNC, quote: ETH}]\npairs:\n  - {base: KNC, quote: USDT, ask: 1, bid: 1, fee: {bps: 10000}}\n",
+			wantLine: 3, wantCol: 57, wantMsg: `pairs[0].fee.bps must be less than 10000, got "10000"`},
+		{name: "Invalid fee side", yaml: true,
+			input:    "queries: [{base: KNC, quote: ETH}]\npairs:\n  - {base: KNC, quote: USDT, ask: 1, bid: 1, fee: {side: both}}\n",
+			wantLine: 3, wantCol: 58, wantMsg: `pairs[0].fee.side must be "quote" or "base", got "both"`},
+		{name: "Duplicate pair", yaml: true,
+			input:    "queries: [{base: KNC, quote: ETH}]\npairs:\n  - {base: KNC, quote: USDT, ask: 1, bid: 1}\n  - {base: USDT, quote: KNC, ask: 1, bid: 1}\n",
+			wantLine: 4, wantCol: 5, wantMsg: "duplicate pair USDT/KNC, first defined on line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.yaml {
+				_, err = ParseYAML(strings.NewReader(tt.input))
+			} else {
+				_, err = ParseJSON(strings.NewReader(tt.input))
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrSyntax) {
+				t.Fatalf("error = %v, want *SyntaxError", err)
+			}
+			if syntaxErr.Line != tt.wantLine || syntaxErr.Column != tt.wantCol || syntaxErr.Msg != tt.wantMsg {
+				t.Errorf("error = %d:%d: %s, want %d:%d: %s", syntaxErr.Line, syntaxErr.Column,
+					syntaxErr.Msg, tt.wantLine, tt.wantCol, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRead_Extension(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"scenario.json": scenarioJSON,
+		"scenario.YML":  scenarioYAML,
+		"simple.txt":    simpleInput,
+		"expanded":      expandedInput,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file      string
+		wantQuery Query
+		wantPairs int
+		wantBook  bool
+	}{
+		{file: "scenario.json", wantQuery: Query{Base: "KNC", Quote: "ETH", Amount: d("100")}, wantPairs: 2, wantBook: true},
+		{file: "scenario.YML", wantQuery: Query{Base: "KNC", Quote: "ETH", Amount: d("100")}, wantPairs: 2, wantBook: true},
+		{file: "simple.txt", wantQuery: Query{Base: "KNC", Quote: "ETH", Amount: d("1")}, wantPairs: 2},
+		{file: "expanded", wantQuery: Query{Base: "KNC", Quote: "ETH", Amount: d("100")}, wantPairs: 2,
+			wantBook: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := Read(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			query := got.Queries[0]
+			if query.Base != tt.wantQuery.Base || query.Quote != tt.wantQuery.Quote ||
+				!query.Amount.Equal(tt.wantQuery.Amount) {
+				t.Errorf("Queries[0] = %+v, want %+v", query, tt.wantQuery)
+			}
+			if len(got.Pairs) != tt.wantPairs || got.Pairs[len(got.Pairs)-1].Book != tt.wantBook {
+				t.Errorf("Pairs = %+v, want %d pairs with Book %v", got.Pairs, tt.wantPairs, tt.wantBook)
+			}
+		})
+	}
+
+	// JSON trong file .yaml vẫn là YAML hợp lệ, nhưng YAML trong file .json
+	// là lỗi cú pháp JSON có tên file
+	path := filepath.Join(dir, "wrong.json")
+	if err := os.WriteFile(path, []byte(scenarioYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Read(path)
+	if want := path + ":1:1: invalid character '#' looking for beginning of value"; err == nil ||
+		err.Error() != want {
+		t.Errorf("Read(wrong.json) error = %v, want %s", err, want)
+	}
+}
+
+func FuzzParseJSON(f *testing.F) {
+	f.Add(scenarioJSON)
+	f.Add(`{"queries": [{"base": "A", "quote": "B", "amount": 1e-3}], "pairs": null}`)
+	f.Add(`{"queries": [{"base": "A", "quote": "B"}], "pairs": [{"base": "A", "quote": "B", "asks": [{}]}]}`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		checkScenario(t, input, ParseJSON)
+	})
+}
+
+func FuzzParseYAML(f *testing.F) {
+	f.Add(scenarioYAML)
+	f.Add("queries: [{base: A, quote: B}]\npairs: [{base: A, quote: B, ask: 1, bid: 1, fee: {}}]\n")
+	f.Add("queries: &a [{base: A, quote: B}]\npairs: *a\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		checkScenario(t, input, ParseYAML)
+	})
+}
+
+// checkScenario kiểm tra parse trả về SyntaxError có vị trí nằm trong input
+// hoặc Scenario hợp lệ.
+func checkScenario(t *testing.T, input string, parse func(io.Reader) (Scenario, error)) {
+	t.Helper()
+	got, err := parse(strings.NewReader(input))
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("error = %v, want *SyntaxError", err)
+		}
+		if lines := strings.Count(input, "\n") + 1; syntaxErr.Line < 0 || syntaxErr.Line > lines+1 {
+			t.Errorf("error line %d outside input of %d lines", syntaxErr.Line, lines)
+		}
+		return
+	}
+	if len(got.Queries) == 0 {
+		t.Errorf("scenario without queries accepted")
+	}
+	for _, query := range got.Queries {
+		if query.Base == query.Quote || !query.Amount.IsPositive() {
+			t.Errorf("invalid query %+v accepted", query)
+		}
+	}
+	for _, pair := range got.Pairs {
+		if pair.Base == pair.Quote || pair.Fee.Bps.IsNegative() || pair.Fee.Fixed.IsNegative() {
+			t.Errorf("invalid pair %+v accepted", pair)
+		}
+		if !pair.Book && (!pair.Ask.IsPositive() || !pair.Bid.IsPositive()) {
+			t.Errorf("invalid prices %+v accepted", pair)
+		}
+	}
+	if len(got.Edges("")) != 2*len(got.Pairs) {
+		t.Errorf("Edges() returned %d edges for %d pairs", len(got.Edges("")), len(got.Pairs))
+	}
+}
@@ -0,0 +1,512 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// ParseJSON đọc Scenario từ JSON:
+//
+//	{
+//	  "queries": [{"base": "KNC", "quote": "ETH", "amount": "100"}],
+//	  "pairs": [
+//	    {"base": "KNC", "quote": "USDT", "ask": "1.1", "bid": "0.9"},
+//	    {"base": "ETH", "quote": "USDT", "venue": "binance",
+//	     "fee": {"bps": "10", "side": "quote"},
+//	     "asks": [{"price": "360", "quantity": "1000"}],
+//	     "bids": [{"price": "355", "quantity": "800"}]}
+//	  ]
+//	}
+//
+// Các trường:
+//   - queries: ít nhất một query, amount mặc định là 1
+//   - pairs: các cặp giao dịch, không bắt buộc. Mỗi pair có giá ask và bid
+//     (SimpleEdge), hoặc order book asks và bids (OrderEdge, một phía có thể
+//     rỗng hoặc bỏ trống), không được dùng cả hai
+//   - venue: exchange của pair, không bắt buộc
+//   - fee: phí taker, không bắt buộc, gồm bps (0 tới dưới 10000), side
+//     ("quote" mặc định hoặc "base") và fixed, xem route.Fee
+//
+// Số có thể viết dạng string hoặc number, string được khuyến khích để không
+// mất độ chính xác ở các công cụ tạo input. Trường không có trong schema và
+// pair bị lặp lại (trên cùng venue, theo cả hai chiều) là lỗi. Lỗi trả về là
+// *SyntaxError cho biết dòng và cột.
+func ParseJSON(r io.Reader) (Scenario, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Scenario{}, err
+	}
+	root, err := jsonNode(data)
+	if err != nil {
+		return Scenario{}, err
+	}
+	return parseScenario(root)
+}
+
+// ParseYAML đọc Scenario từ YAML với cùng schema như ParseJSON:
+//
+//	queries:
+//	  - {base: KNC, quote: ETH, amount: 100}
+//	pairs:
+//	  - {base: KNC, quote: USDT, ask: 1.1, bid: 0.9}
+//	  - base: ETH
+//	    quote: USDT
+//	    venue: binance
+//	    fee: {bps: 10, side: quote}
+//	    asks:
+//	      - {price: 360, quantity: 1000}
+//	    bids:
+//	      - {price: 355, quantity: 800}
+//
+// Số được đọc từ nguyên văn trong file nên không mất độ chính xác. Alias
+// (*anchor) không được hỗ trợ. Chỉ document đầu tiên được đọc.
+func ParseYAML(r io.Reader) (Scenario, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Scenario{}, &SyntaxError{Line: 1, Column: 1, Msg: "empty document"}
+		}
+		return Scenario{}, yamlError(err)
+	}
+	return parseScenario(doc.Content[0])
+}
+
+// yamlErrorPattern khớp lỗi cú pháp của yaml.v3, ví dụ "yaml: line 3: did not
+// find expected key".
+var yamlErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlError chuyển lỗi cú pháp của yaml.v3 thành *SyntaxError. yaml.v3 không
+// cho biết cột nên Column là 0.
+func yamlError(err error) error {
+	match := yamlErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return &SyntaxError{Msg: err.Error()}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &SyntaxError{Line: line, Msg: match[2]}
+}
+
+// parseScenario đọc Scenario từ node gốc của JSON hoặc YAML.
+func parseScenario(root *yaml.Node) (Scenario, error) {
+	fields, err := mappingFields(root, "document", []string{"queries"}, []string{"pairs"})
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	queries, err := sequenceItems(fields["queries"], "queries")
+	if err != nil {
+		return Scenario{}, err
+	}
+	if len(queries) == 0 {
+		return Scenario{}, nodeError(fields["queries"], "queries must contain at least one query")
+	}
+	var scenario Scenario
+	for i, node := range queries {
+		query, err := parseQueryNode(node, fmt.Sprintf("queries[%d]", i))
+		if err != nil {
+			return Scenario{}, err
+		}
+		scenario.Queries = append(scenario.Queries, query)
+	}
+
+	var pairs []*yaml.Node
+	if node, ok := fields["pairs"]; ok {
+		if pairs, err = sequenceItems(node, "pairs"); err != nil {
+			return Scenario{}, err
+		}
+	}
+	seen := map[[3]string]int{}
+	for i, node := range pairs {
+		pair, err := parsePairNode(node, fmt.Sprintf("pairs[%d]", i))
+		if err != nil {
+			return Scenario{}, err
+		}
+		key := [3]string{pair.Venue, min(pair.Base, pair.Quote), max(pair.Base, pair.Quote)}
+		if first, ok := seen[key]; ok {
+			return Scenario{}, nodeError(node, "duplicate pair %s/%s, first defined on line %d",
+				pair.Base, pair.Quote, first)
+		}
+		seen[key] = node.Line
+		scenario.Pairs = append(scenario.Pairs, pair)
+	}
+	return scenario, nil
+}
+
+// parseQueryNode đọc một query {base, quote, amount}.
+func parseQueryNode(node *yaml.Node, path string) (Query, error) {
+	fields, err := mappingFields(node, path, []string{"base", "quote"}, []string{"amount"})
+	if err != nil {
+		return Query{}, err
+	}
+
+	query := Query{Amount: decimal.One}
+	if query.Base, err = tokenNode(fields["base"], path+".base"); err != nil {
+		return Query{}, err
+	}
+	if query.Quote, err = tokenNode(fields["quote"], path+".quote"); err != nil {
+		return Query{}, err
+	}
+	if query.Base == query.Quote {
+		return Query{}, nodeError(fields["quote"], "%s.quote must differ from base token %q",
+			path, query.Base)
+	}
+	if amount, ok := fields["amount"]; ok {
+		if query.Amount, err = positiveNode(amount, path+".amount"); err != nil {
+			return Query{}, err
+		}
+	}
+	return query, nil
+}
+
+// parsePairNode đọc một pair, xem ParseJSON.
+func parsePairNode(node *yaml.Node, path string) (Pair, error) {
+	fields, err := mappingFields(node, path, []string{"base", "quote"},
+		[]string{"venue", "fee", "ask", "bid", "asks", "bids"})
+	if err != nil {
+		return Pair{}, err
+	}
+
+	var pair Pair
+	if pair.Base, err = tokenNode(fields["base"], path+".base"); err != nil {
+		return Pair{}, err
+	}
+	if pair.Quote, err = tokenNode(fields["quote"], path+".quote"); err != nil {
+		return Pair{}, err
+	}
+	if pair.Base == pair.Quote {
+		return Pair{}, nodeError(fields["quote"], "%s.quote must differ from base token %q",
+			path, pair.Base)
+	}
+	if venue, ok := fields["venue"]; ok {
+		if pair.Venue, err = scalarNode(venue, path+".venue"); err != nil {
+			return Pair{}, err
+		}
+		if pair.Venue == "" {
+			return Pair{}, nodeError(venue, "%s.venue must not be empty", path)
+		}
+	}
+	if fee, ok := fields["fee"]; ok {
+		if pair.Fee, err = parseFeeNode(fee, path+".fee"); err != nil {
+			return Pair{}, err
+		}
+	}
+
+	asks, hasAsks := fields["asks"]
+	bids, hasBids := fields["bids"]
+	if pair.Book = hasAsks || hasBids; pair.Book {
+		for _, name := range []string{"ask", "bid"} {
+			if simple, ok := fields[name]; ok {
+				return Pair{}, nodeError(simple, "%s.%s cannot be combined with asks and bids", path, name)
+			}
+		}
+		if hasAsks {
+			if pair.Asks, err = parseOrderNodes(asks, path+".asks"); err != nil {
+				return Pair{}, err
+			}
+		}
+		if hasBids {
+			if pair.Bids, err = parseOrderNodes(bids, path+".bids"); err != nil {
+				return Pair{}, err
+			}
+		}
+		return pair, nil
+	}
+
+	for _, name := range []string{"ask", "bid"} {
+		if _, ok := fields[name]; !ok {
+			return Pair{}, nodeError(node, "missing %s.%s, or asks and bids", path, name)
+		}
+	}
+	if pair.Ask, err = positiveNode(fields["ask"], path+".ask"); err != nil {
+		return Pair{}, err
+	}
+	if pair.Bid, err = positiveNode(fields["bid"], path+".bid"); err != nil {
+		return Pair{}, err
+	}
+	return pair, nil
+}
+
+// parseFeeNode đọc phí {bps, side, fixed}.
+func parseFeeNode(node *yaml.Node, path string) (route.Fee, error) {
+	fields, err := mappingFields(node, path, nil, []string{"bps", "side", "fixed"})
+	if err != nil {
+		return route.Fee{}, err
+	}
+
+	var fee route.Fee
+	if bps, ok := fields["bps"]; ok {
+		if fee.Bps, err = nonNegativeNode(bps, path+".bps"); err != nil {
+			return route.Fee{}, err
+		}
+		// Bps không âm nên route.Fee chỉ còn từ chối bps từ 10000 trở lên
+		if (route.Fee{Bps: fee.Bps}).Validate() != nil {
+			return route.Fee{}, nodeError(bps, "%s must be less than 10000, got %q", path+".bps", bps.Value)
+		}
+	}
+	if fixed, ok := fields["fixed"]; ok {
+		if fee.Fixed, err = nonNegativeNode(fixed, path+".fixed"); err != nil {
+			return route.Fee{}, err
+		}
+	}
+	if side, ok := fields["side"]; ok {
+		text, err := scalarNode(side, path+".side")
+		if err != nil {
+			return route.Fee{}, err
+		}
+		switch text {
+		case "quote":
+			fee.Side = route.FeeInQuote
+		case "base":
+			fee.Side = route.FeeInBase
+		default:
+			return route.Fee{}, nodeError(side, "%s must be \"quote\" or \"base\", got %q", path+".side", text)
+		}
+	}
+	return fee, nil
+}
+
+// parseOrderNodes đọc danh sách order [{price, quantity}].
+func parseOrderNodes(node *yaml.Node, path string) ([]route.Order, error) {
+	items, err := sequenceItems(node, path)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]route.Order, 0, len(items))
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		fields, err := mappingFields(item, itemPath, []string{"price", "quantity"}, nil)
+		if err != nil {
+			return nil, err
+		}
+		price, err := positiveNode(fields["price"], itemPath+".price")
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := positiveNode(fields["quantity"], itemPath+".quantity")
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, route.Order{Price: price, Quantity: quantity})
+	}
+	return orders, nil
+}
+
+// nodeError trả về SyntaxError tại vị trí của node.
+func nodeError(node *yaml.Node, format string, args ...any) error {
+	return &SyntaxError{Line: node.Line, Column: node.Column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// checkNode kiểm tra node không phải alias, alias không được hỗ trợ.
+func checkNode(node *yaml.Node, path string) error {
+	if node.Kind == yaml.AliasNode {
+		return nodeError(node, "%s: aliases are not supported", path)
+	}
+	return nil
+}
+
+// mappingFields trả về các trường của mapping node theo tên. Trường không có
+// trong required và optional, trường bị lặp lại và thiếu trường required đều
+// là lỗi. Trường có giá trị null được coi như không có.
+func mappingFields(node *yaml.Node, path string, required, optional []string) (
+	map[string]*yaml.Node, error) {
+	if err := checkNode(node, path); err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, nodeError(node, "%s must be an object", path)
+	}
+
+	allowed := map[string]bool{}
+	for _, name := range append(required, optional...) {
+		allowed[name] = true
+	}
+	fields := map[string]*yaml.Node{}
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode || !allowed[key.Value] {
+			return nil, nodeError(key, "unknown field %q in %s", key.Value, path)
+		}
+		if seen[key.Value] {
+			return nil, nodeError(key, "duplicate field %q in %s", key.Value, path)
+		}
+		seen[key.Value] = true
+		if value.Kind != yaml.ScalarNode || value.Tag != "!!null" {
+			fields[key.Value] = value
+		}
+	}
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			return nil, nodeError(node, "missing field %q in %s", name, path)
+		}
+	}
+	return fields, nil
+}
+
+// sequenceItems trả về các phần tử của sequence node.
+func sequenceItems(node *yaml.Node, path string) ([]*yaml.Node, error) {
+	if err := checkNode(node, path); err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, nodeError(node, "%s must be a list", path)
+	}
+	return node.Content, nil
+}
+
+// scalarNode trả về nguyên văn giá trị của scalar node.
+func scalarNode(node *yaml.Node, path string) (string, error) {
+	if err := checkNode(node, path); err != nil {
+		return "", err
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", nodeError(node, "%s must be a string or number", path)
+	}
+	return node.Value, nil
+}
+
+// tokenNode đọc tên token, xem checkToken.
+func tokenNode(node *yaml.Node, path string) (string, error) {
+	text, err := scalarNode(node, path)
+	if err != nil {
+		return "", err
+	}
+	if msg := checkToken(text, path); msg != "" {
+		return "", nodeError(node, "%s", msg)
+	}
+	return text, nil
+}
+
+// positiveNode đọc số thập phân dương, xem checkPositive.
+func positiveNode(node *yaml.Node, path string) (decimal.Decimal, error) {
+	text, err := scalarNode(node, path)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	value, msg := checkPositive(text, path)
+	if msg != "" {
+		return decimal.Zero, nodeError(node, "%s", msg)
+	}
+	return value, nil
+}
+
+// nonNegativeNode đọc số thập phân không âm.
+func nonNegativeNode(node *yaml.Node, path string) (decimal.Decimal, error) {
+	text, err := scalarNode(node, path)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	value, err := decimal.NewFromString(text)
+	if err != nil {
+		return decimal.Zero, nodeError(node, "invalid %s %q", path, text)
+	}
+	if value.IsNegative() {
+		return decimal.Zero, nodeError(node, "%s must not be negative, got %q", path, text)
+	}
+	return value, nil
+}
+
+// jsonNode đọc JSON thành cây yaml.Node có vị trí dòng và cột của từng giá
+// trị, để JSON và YAML dùng chung một bộ kiểm tra schema.
+func jsonNode(data []byte) (*yaml.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	root, err := decodeJSONValue(decoder, data)
+	if err == nil {
+		if _, err = decoder.Token(); err == nil {
+			return nil, jsonError(data, decoder.InputOffset(), "unexpected data after top-level value")
+		}
+		if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset là số byte đã đọc, gồm cả ký tự bị sai
+		return nil, jsonError(data, syntaxErr.Offset-1, syntaxErr.Error())
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return nil, jsonError(data, int64(len(data)), "unexpected end of JSON input")
+	}
+	return nil, err
+}
+
+// decodeJSONValue đọc giá trị JSON tiếp theo của decoder.
+func decodeJSONValue(decoder *json.Decoder, data []byte) (*yaml.Node, error) {
+	// InputOffset là vị trí ngay sau token trước, giá trị bắt đầu sau các
+	// khoảng trắng và dấu phân cách
+	start := decoder.InputOffset()
+	for start < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,:"), data[start]) >= 0 {
+		start++
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	line, column := position(data, start)
+	node := &yaml.Node{Line: line, Column: column}
+
+	switch token := token.(type) {
+	case json.Delim:
+		node.Kind = yaml.SequenceNode
+		if token == '{' {
+			node.Kind = yaml.MappingNode
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decodeJSONValue(decoder, data)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, key)
+			}
+			value, err := decodeJSONValue(decoder, data)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		// Dấu đóng } hoặc ]
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!str", token
+	case json.Number:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!float", token.String()
+	case bool:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!bool", strconv.FormatBool(token)
+	case nil:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!null", "null"
+	}
+	return node, nil
+}
+
+// jsonError trả về SyntaxError tại vị trí offset (byte) của data.
+func jsonError(data []byte, offset int64, msg string) error {
+	line, column := position(data, offset)
+	return &SyntaxError{Line: line, Column: column, Msg: msg}
+}
+
+// position chuyển vị trí offset (byte) của data thành dòng và cột (tính theo
+// ký tự), bắt đầu từ 1.
+func position(data []byte, offset int64) (int, int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
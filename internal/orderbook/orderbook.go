@@ -0,0 +1,323 @@
+// Package orderbook lưu order book của một trading pair và cập nhật nó từ các
+// diff event (depth update) theo lastUpdateId, như mô tả trong
+// docs/system_design_problem.md.
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+var (
+	// ErrStaleEvent trả về khi event đã được phản ánh trong order book, tức
+	// FinalUpdateID <= LastUpdateID. Event này có thể bỏ qua an toàn.
+	ErrStaleEvent = errors.New("orderbook: stale event")
+
+	// ErrOutOfSequence trả về khi giữa order book và event có update bị mất.
+	// Order book không còn đúng, cần đồng bộ lại từ snapshot mới.
+	ErrOutOfSequence = errors.New("orderbook: event out of sequence")
+
+	// ErrInvalidLevel trả về khi event có price level giá không dương hoặc
+	// khối lượng âm.
+	ErrInvalidLevel = errors.New("orderbook: invalid price level")
+)
+
+// Snapshot là order book đầy đủ lấy từ API depth của exchange.
+//   - LastUpdateID: update id của event cuối cùng đã được phản ánh trong
+//     snapshot
+//   - Bids, Asks: các price level, không cần sắp xếp, level có khối lượng 0
+//     bị bỏ qua
+type Snapshot struct {
+	LastUpdateID uint64
+	Bids         []route.Order
+	Asks         []route.Order
+}
+
+// DiffEvent là một depth update của order book.
+//   - FirstUpdateID, FinalUpdateID: khoảng update id mà event bao gồm. Với
+//     exchange chỉ gửi một update id cho mỗi event, để FirstUpdateID bằng 0
+//     hoặc bằng FinalUpdateID
+//   - Bids, Asks: khối lượng mới của các price level thay đổi, khối lượng 0
+//     nghĩa là xóa price level đó
+type DiffEvent struct {
+	FirstUpdateID uint64
+	FinalUpdateID uint64
+	Bids          []route.Order
+	Asks          []route.Order
+}
+
+// First trả về update id đầu tiên của event.
+func (e DiffEvent) First() uint64 {
+	if e.FirstUpdateID == 0 {
+		return e.FinalUpdateID
+	}
+	return e.FirstUpdateID
+}
+
+// OrderBook lưu các price level đã sắp xếp của một trading pair: bids giảm
+// dần, asks tăng dần theo giá. Mỗi price level lưu kèm order tương ứng của cạnh
+// đảo ngược (xem route.ReverseOrder), được cập nhật cùng với level đó.
+//
+// OrderBook không an toàn khi ghi đồng thời, thường chỉ có một goroutine
+// apply event. View trả về dữ liệu bất biến nên có thể chia sẻ cho nhiều
+// goroutine đọc.
+type OrderBook struct {
+	lastUpdateID uint64
+	bids         side
+	asks         side
+}
+
+// New tạo OrderBook từ snapshot. Các price level được sao chép và sắp xếp lại,
+// level có khối lượng 0 bị bỏ qua. Trả về ErrInvalidLevel nếu snapshot có
+// price level không hợp lệ.
+func New(snapshot Snapshot) (*OrderBook, error) {
+	b := &OrderBook{
+		lastUpdateID: snapshot.LastUpdateID,
+		bids:         side{bids: true},
+		asks:         side{bids: false},
+	}
+	if err := validate(snapshot.Bids, snapshot.Asks); err != nil {
+		return nil, err
+	}
+	for _, order := range snapshot.Bids {
+		b.bids.set(order)
+	}
+	for _, order := range snapshot.Asks {
+		b.asks.set(order)
+	}
+	return b, nil
+}
+
+// LastUpdateID trả về update id của event cuối cùng đã được apply.
+func (b *OrderBook) LastUpdateID() uint64 {
+	return b.lastUpdateID
+}
+
+// Apply cập nhật order book theo event:
+//   - price level chưa có được thêm vào, đã có thì cập nhật khối lượng, khối
+//     lượng 0 thì xóa price level
+//   - LastUpdateID được cập nhật bằng event.FinalUpdateID
+//
+// Event phải nối tiếp order book, tức FirstUpdateID <= LastUpdateID + 1 <=
+// FinalUpdateID. Trả về ErrStaleEvent nếu event đã cũ, ErrOutOfSequence nếu có
+// update bị mất và ErrInvalidLevel nếu event có price level không hợp lệ. Khi
+// có lỗi, order book không bị thay đổi.
+func (b *OrderBook) Apply(event DiffEvent) error {
+	if event.FinalUpdateID <= b.lastUpdateID {
+		return ErrStaleEvent
+	}
+	if event.First() > b.lastUpdateID+1 {
+		return fmt.Errorf("%w: last update id %d, event starts at %d",
+			ErrOutOfSequence, b.lastUpdateID, event.First())
+	}
+	if err := validate(event.Bids, event.Asks); err != nil {
+		return err
+	}
+
+	for _, order := range event.Bids {
+		b.bids.set(order)
+	}
+	for _, order := range event.Asks {
+		b.asks.set(order)
+	}
+	b.lastUpdateID = event.FinalUpdateID
+	return nil
+}
+
+// BestBid trả về price level bid cao nhất, false nếu không có bid.
+func (b *OrderBook) BestBid() (route.Order, bool) {
+	return b.bids.best()
+}
+
+// BestAsk trả về price level ask thấp nhất, false nếu không có ask.
+func (b *OrderBook) BestAsk() (route.Order, bool) {
+	return b.asks.best()
+}
+
+// View trả về trạng thái hiện tại của order book. Các price level được lưu
+// thành các chunk không bị thay đổi sau khi tạo (xem route.Levels), View chỉ
+// sao chép danh sách chunk của mỗi phía, không sao chép price level nào. Sau
+// mỗi thay đổi, chỉ chunk chứa price level thay đổi được tạo lại, các chunk
+// khác và phía không thay đổi được dùng chung với các View trước. Các lần
+// Apply sau đó không thay đổi View đã trả về.
+func (b *OrderBook) View() View {
+	bids, reverseAsks := b.bids.view()
+	asks, reverseBids := b.asks.view()
+	return View{
+		LastUpdateID: b.lastUpdateID,
+		Bids:         bids,
+		Asks:         asks,
+		ReverseAsks:  reverseAsks,
+		ReverseBids:  reverseBids,
+	}
+}
+
+// View là trạng thái bất biến của OrderBook tại LastUpdateID. Bids giảm dần,
+// Asks tăng dần theo giá. ReverseAsks và ReverseBids là ask và bid orders của
+// cạnh đảo ngược, tạo từ Bids và Asks bằng route.ReverseOrder theo cùng thứ
+// tự và cùng cách chia chunk. Không được thay đổi các Levels này.
+type View struct {
+	LastUpdateID uint64
+	Bids         route.Levels
+	Asks         route.Levels
+	ReverseAsks  route.Levels
+	ReverseBids  route.Levels
+}
+
+// OrderEdge trả về cạnh base->quote dùng trực tiếp các chunk price level của
+// view, không sao chép order book.
+func (v View) OrderEdge(base, quote string) route.OrderEdge {
+	return route.OrderEdge{
+		BaseToken:  base,
+		QuoteToken: quote,
+		BidLevels:  v.Bids,
+		AskLevels:  v.Asks,
+	}
+}
+
+// ReverseEdge trả về cạnh đảo ngược của edge (thường là OrderEdge của view
+// sau khi gắn Fee, Precisions và Venue) dùng các order đảo ngược của view, kết
+// quả giống edge.GetReverseEdge() nhưng không phải đảo lại từng price level.
+func (v View) ReverseEdge(edge route.OrderEdge) route.OrderEdge {
+	return edge.ReverseWithLevels(v.ReverseAsks, v.ReverseBids)
+}
+
+// chunkSize là độ dài mục tiêu của mỗi chunk price level: chunk dài quá
+// 2*chunkSize bị tách đôi, chunk ngắn hơn chunkSize/2 được gộp với chunk tiếp
+// theo nếu không quá 2*chunkSize. Mỗi thay đổi tạo lại một chunk nên tốn
+// O(chunkSize), thay vì O(độ sâu) nếu sao chép cả phía.
+const chunkSize = 64
+
+// side là một phía (bids hoặc asks) của order book.
+//   - orders, reverse: các price level theo thứ tự từ tốt tới kém và các order
+//     đảo ngược tương ứng, chia thành các chunk cùng độ dài ở cùng vị trí.
+//     Chunk không bao giờ bị thay đổi sau khi tạo, thay đổi một price level
+//     tạo chunk mới thay cho chunk chứa level đó
+//   - bids: true nếu là phía bids, giá tốt nhất là giá cao nhất
+//   - shared: true nếu danh sách chunk đang được dùng bởi một View, lần thay
+//     đổi tiếp theo phải sao chép danh sách chunk (không sao chép các chunk)
+type side struct {
+	orders  route.Levels
+	reverse route.Levels
+	bids    bool
+	shared  bool
+}
+
+// better kiểm tra giá a có tốt hơn giá b ở phía này hay không.
+func (s *side) better(a, b decimal.Decimal) bool {
+	if s.bids {
+		return a.GreaterThan(b)
+	}
+	return a.LessThan(b)
+}
+
+// find trả về vị trí của price level có giá price: chunk c và vị trí i trong
+// chunk, true nếu level đã có. Nếu chưa có, (c, i) là vị trí cần chèn.
+func (s *side) find(price decimal.Decimal) (int, int, bool) {
+	if len(s.orders) == 0 {
+		return 0, 0, false
+	}
+	// Chunk đầu tiên có level kém nhất không tốt hơn price, hoặc chunk cuối
+	c, _ := slices.BinarySearchFunc(s.orders, price, func(chunk []route.Order, price decimal.Decimal) int {
+		if s.better(chunk[len(chunk)-1].Price, price) {
+			return -1
+		}
+		return 1
+	})
+	c = min(c, len(s.orders)-1)
+	i, found := slices.BinarySearchFunc(s.orders[c], price, func(order route.Order, price decimal.Decimal) int {
+		switch {
+		case order.Price.Equal(price):
+			return 0
+		case s.better(order.Price, price):
+			return -1
+		}
+		return 1
+	})
+	return c, i, found
+}
+
+// set thêm, cập nhật hoặc xóa (khối lượng 0) price level, tìm vị trí bằng
+// binary search và tạo lại chunk chứa level đó.
+func (s *side) set(order route.Order) {
+	c, i, found := s.find(order.Price)
+	if !found && order.Quantity.IsZero() {
+		return
+	}
+
+	if s.shared {
+		s.orders, s.reverse, s.shared = slices.Clone(s.orders), slices.Clone(s.reverse), false
+	}
+	if len(s.orders) == 0 {
+		s.orders = route.Levels{{order}}
+		s.reverse = route.Levels{{route.ReverseOrder(order, s.bids)}}
+		return
+	}
+
+	orders, reverse := s.orders[c], s.reverse[c]
+	switch {
+	case found && order.Quantity.IsZero():
+		orders, reverse = slices.Concat(orders[:i], orders[i+1:]), slices.Concat(reverse[:i], reverse[i+1:])
+	case found:
+		orders, reverse = slices.Clone(orders), slices.Clone(reverse)
+		orders[i], reverse[i] = order, route.ReverseOrder(order, s.bids)
+	default:
+		orders = slices.Concat(orders[:i], []route.Order{order}, orders[i:])
+		reverse = slices.Concat(reverse[:i], []route.Order{route.ReverseOrder(order, s.bids)}, reverse[i:])
+	}
+	s.replace(c, orders, reverse)
+}
+
+// replace thay chunk c bằng orders và reverse, tách đôi chunk quá dài, xóa
+// chunk rỗng và gộp chunk quá ngắn với chunk tiếp theo, xem chunkSize.
+func (s *side) replace(c int, orders, reverse []route.Order) {
+	if len(orders) < chunkSize/2 && c+1 < len(s.orders) &&
+		len(orders)+len(s.orders[c+1]) <= 2*chunkSize {
+		orders, reverse = slices.Concat(orders, s.orders[c+1]), slices.Concat(reverse, s.reverse[c+1])
+		s.orders, s.reverse = slices.Delete(s.orders, c+1, c+2), slices.Delete(s.reverse, c+1, c+2)
+	}
+
+	switch {
+	case len(orders) == 0:
+		s.orders, s.reverse = slices.Delete(s.orders, c, c+1), slices.Delete(s.reverse, c, c+1)
+	case len(orders) > 2*chunkSize:
+		half := len(orders) / 2
+		s.orders = slices.Replace(s.orders, c, c+1, orders[:half:half], orders[half:])
+		s.reverse = slices.Replace(s.reverse, c, c+1, reverse[:half:half], reverse[half:])
+	default:
+		s.orders[c], s.reverse[c] = orders, reverse
+	}
+}
+
+// best trả về price level tốt nhất của side.
+func (s *side) best() (route.Order, bool) {
+	if len(s.orders) == 0 {
+		return route.Order{}, false
+	}
+	return s.orders[0][0], true
+}
+
+// view trả về các chunk price level và order đảo ngược theo thứ tự từ tốt tới
+// kém. Danh sách chunk được dùng chung với side cho tới lần thay đổi tiếp
+// theo.
+func (s *side) view() (route.Levels, route.Levels) {
+	s.shared = true
+	return slices.Clip(s.orders), slices.Clip(s.reverse)
+}
+
+// validate kiểm tra các price level có giá dương và khối lượng không âm.
+func validate(bids, asks []route.Order) error {
+	for _, levels := range [][]route.Order{bids, asks} {
+		for _, level := range levels {
+			if !level.Price.IsPositive() || level.Quantity.IsNegative() {
+				return fmt.Errorf("%w: price %s, quantity %s",
+					ErrInvalidLevel, level.Price, level.Quantity)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package orderbook
+
+import (
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+// levels tạo danh sách price level từ các cặp giá, khối lượng.
+func levels(pairs ...string) []route.Order {
+	orders := make([]route.Order, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		orders = append(orders, route.Order{Price: d(pairs[i]), Quantity: d(pairs[i+1])})
+	}
+	return orders
+}
+
+func newTestBook(t *testing.T) *OrderBook {
+	t.Helper()
+	b, err := New(Snapshot{
+		LastUpdateID: 100,
+		Bids:         levels("0.9", "100", "1.0", "50", "0.8", "0"),
+		Asks:         levels("1.2", "10", "1.1", "20"),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return b
+}
+
+func TestNew_SortsLevels(t *testing.T) {
+	view := newTestBook(t).View()
+	assertLevels(t, "bids", view.Bids, levels("1.0", "50", "0.9", "100"))
+	assertLevels(t, "asks", view.Asks, levels("1.1", "20", "1.2", "10"))
+	if view.LastUpdateID != 100 {
+		t.Errorf("LastUpdateID = %d, want 100", view.LastUpdateID)
+	}
+}
+
+func TestOrderBook_Apply(t *testing.T) {
+	b := newTestBook(t)
+
+	err := b.Apply(DiffEvent{
+		FirstUpdateID: 99,
+		FinalUpdateID: 105,
+		Bids:          levels("0.95", "30", "1.0", "0", "0.7", "0"),
+		Asks:          levels("1.1", "5", "1.3", "1"),
+	})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	view := b.View()
+	assertLevels(t, "bids", view.Bids, levels("0.95", "30", "0.9", "100"))
+	assertLevels(t, "asks", view.Asks, levels("1.1", "5", "1.2", "10", "1.3", "1"))
+	if b.LastUpdateID() != 105 {
+		t.Errorf("LastUpdateID() = %d, want 105", b.LastUpdateID())
+	}
+	if best, ok := b.BestBid(); !ok || !best.Price.Equal(d("0.95")) {
+		t.Errorf("BestBid() = (%v, %v), want 0.95", best, ok)
+	}
+	if best, ok := b.BestAsk(); !ok || !best.Price.Equal(d("1.1")) {
+		t.Errorf("BestAsk() = (%v, %v), want 1.1", best, ok)
+	}
+}
+
+func TestOrderBook_Apply_Sequencing(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   DiffEvent
+		wantErr error
+	}{
+		{name: "Stale", event: DiffEvent{FirstUpdateID: 90, FinalUpdateID: 100}, wantErr: ErrStaleEvent},
+		{name: "Gap", event: DiffEvent{FirstUpdateID: 102, FinalUpdateID: 103}, wantErr: ErrOutOfSequence},
+		{name: "Single update id gap", event: DiffEvent{FinalUpdateID: 102}, wantErr: ErrOutOfSequence},
+		{name: "Next", event: DiffEvent{FinalUpdateID: 101}, wantErr: nil},
+		{
+			name:    "Invalid level",
+			event:   DiffEvent{FirstUpdateID: 101, FinalUpdateID: 101, Bids: levels("0", "1")},
+			wantErr: ErrInvalidLevel,
+		},
+		{
+			name:    "Negative quantity",
+			event:   DiffEvent{FirstUpdateID: 101, FinalUpdateID: 101, Asks: levels("1", "-1")},
+			wantErr: ErrInvalidLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBook(t)
+			err := b.Apply(tt.event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && b.LastUpdateID() != 100 {
+				t.Errorf("LastUpdateID() = %d after failed Apply, want 100", b.LastUpdateID())
+			}
+		})
+	}
+}
+
+func TestOrderBook_ViewIsImmutable(t *testing.T) {
+	b := newTestBook(t)
+	before := b.View()
+
+	events := []DiffEvent{
+		{FinalUpdateID: 101, Bids: levels("1.0", "1")},
+		{FinalUpdateID: 102, Bids: levels("0.9", "0", "0.85", "7")},
+		{FinalUpdateID: 103, Asks: levels("1.05", "3")},
+	}
+	for _, event := range events {
+		if err := b.Apply(event); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	}
+
+	assertLevels(t, "old bids", before.Bids, levels("1.0", "50", "0.9", "100"))
+	assertLevels(t, "old asks", before.Asks, levels("1.1", "20", "1.2", "10"))
+	after := b.View()
+	assertLevels(t, "new bids", after.Bids, levels("1.0", "1", "0.85", "7"))
+	assertLevels(t, "new asks", after.Asks, levels("1.05", "3", "1.1", "20", "1.2", "10"))
+}
+
+func TestView_OrderEdge(t *testing.T) {
+	edge := newTestBook(t).View().OrderEdge("KNC", "USDT")
+
+	// Bán 60 KNC: 50 * 1.0 + 10 * 0.9
+	if got, ok := edge.SimulateSell(d("60")); !ok || !got.Equal(d("59")) {
+		t.Errorf("SimulateSell(60) = (%v, %v), want (59, true)", got, ok)
+	}
+	// Mua 25 KNC: 20 * 1.1 + 5 * 1.2
+	if got, ok := edge.SimulateBuy(d("25")); !ok || !got.Equal(d("28")) {
+		t.Errorf("SimulateBuy(25) = (%v, %v), want (28, true)", got, ok)
+	}
+}
+
+func TestView_ReverseEdge(t *testing.T) {
+	b := newTestBook(t)
+	if err := b.Apply(DiffEvent{FinalUpdateID: 101, Bids: levels("0.9", "40", "0.95", "3")}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	view := b.View()
+	edge := view.OrderEdge("KNC", "USDT")
+	edge.Fee = route.Fee{Bps: d("10")}
+	edge.Venue = "binance"
+	if got, want := view.ReverseEdge(edge), edge.GetReverseEdge(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReverseEdge() = %+v, want %+v", got, want)
+	}
+}
+
+func TestOrderBook_ViewReusesUnchangedSide(t *testing.T) {
+	b := newTestBook(t)
+	before := b.View()
+	if err := b.Apply(DiffEvent{FinalUpdateID: 101, Bids: levels("1.0", "1")}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	after := b.View()
+	if &after.Asks[0][0] != &before.Asks[0][0] || &after.ReverseBids[0][0] != &before.ReverseBids[0][0] {
+		t.Errorf("View() copied asks again although they did not change")
+	}
+	if &after.Bids[0][0] == &before.Bids[0][0] {
+		t.Errorf("View() reused bids after they changed")
+	}
+}
+
+func TestOrderBook_ViewCopiesChangedChunkOnly(t *testing.T) {
+	// 1000 bids 1000, 999, ..., 1 và 1000 asks 1001, 1002, ...
+	snapshot := Snapshot{LastUpdateID: 1}
+	for i := range 1000 {
+		snapshot.Bids = append(snapshot.Bids, route.Order{
+			Price: decimal.NewFromInt(int64(1000 - i)), Quantity: d("5")})
+		snapshot.Asks = append(snapshot.Asks, route.Order{
+			Price: decimal.NewFromInt(int64(1001 + i)), Quantity: d("5")})
+	}
+	b, err := New(snapshot)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	before := b.View()
+	event := DiffEvent{FinalUpdateID: 2, Bids: levels("500", "7", "499.5", "1")}
+	if err := b.Apply(event); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	after := b.View()
+
+	for i := range after.Asks {
+		if &after.Asks[i][0] != &before.Asks[i][0] || &after.ReverseBids[i][0] != &before.ReverseBids[i][0] {
+			t.Fatalf("View() copied ask chunk %d although asks did not change", i)
+		}
+	}
+	shared := map[*route.Order]bool{}
+	for _, chunk := range before.Bids {
+		shared[&chunk[0]] = true
+	}
+	copied := 0
+	for _, chunk := range after.Bids {
+		if !shared[&chunk[0]] {
+			copied++
+		}
+	}
+	if copied != 1 {
+		t.Errorf("View() copied %d bid chunks, want 1", copied)
+	}
+
+	bids := slices.Collect(after.Bids.All())
+	if len(bids) != 1001 || !slices.IsSortedFunc(bids, func(a, b route.Order) int {
+		return b.Price.Cmp(a.Price)
+	}) {
+		t.Errorf("bids after Apply are not 1001 levels sorted descending")
+	}
+	if got := slices.Collect(before.Bids.All()); len(got) != 1000 || !got[500].Quantity.Equal(d("5")) {
+		t.Errorf("old view changed after Apply")
+	}
+}
+
+func TestOrderBook_ApplyRandom(t *testing.T) {
+	b, err := New(Snapshot{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// Thêm, cập nhật và xóa ngẫu nhiên để các chunk bị tách và gộp nhiều lần
+	rng := rand.New(rand.NewPCG(1, 2))
+	want := map[int64]int64{}
+	for id := range uint64(5000) {
+		price, quantity := rng.Int64N(400)+1, rng.Int64N(3)
+		order := route.Order{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromInt(quantity)}
+		if err := b.Apply(DiffEvent{FinalUpdateID: id + 1, Bids: []route.Order{order}}); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if quantity == 0 {
+			delete(want, price)
+		} else {
+			want[price] = quantity
+		}
+		if id%500 == 0 {
+			b.View()
+		}
+	}
+
+	prices := slices.Sorted(maps.Keys(want))
+	slices.Reverse(prices)
+	expected := make([]route.Order, 0, len(prices))
+	for _, price := range prices {
+		expected = append(expected, route.Order{
+			Price: decimal.NewFromInt(price), Quantity: decimal.NewFromInt(want[price])})
+	}
+	view := b.View()
+	assertLevels(t, "bids", view.Bids, expected)
+	for _, chunk := range view.Bids {
+		if len(chunk) == 0 || len(chunk) > 2*chunkSize {
+			t.Errorf("chunk length = %d, want 1..%d", len(chunk), 2*chunkSize)
+		}
+	}
+}
+
+func assertLevels(t *testing.T, name string, levels route.Levels, want []route.Order) {
+	t.Helper()
+	got := slices.Collect(levels.All())
+	if len(got) != len(want) {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+	for i := range want {
+		if !got[i].Price.Equal(want[i].Price) || !got[i].Quantity.Equal(want[i].Quantity) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
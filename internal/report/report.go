@@ -0,0 +1,168 @@
+// Package report in kết quả tìm đường của cmd/simple và cmd/expanded dưới dạng
+// văn bản: route và giá tốt nhất, chi tiết khớp lệnh từng chặng, các route dự
+// phòng và đường giá theo amount.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+// Options là các tùy chọn in kết quả của Query.
+//   - Verbose: in chi tiết khớp lệnh từng chặng và price impact, xem Details
+//   - Top: tổng số route in ra mỗi chiều, lớn hơn 1 thì in thêm các route dự
+//     phòng, xem Alternatives
+//   - QueryOptions: các tùy chọn tìm đường của mọi query
+type Options struct {
+	Verbose      bool
+	Top          int
+	QueryOptions []route.QueryOption
+}
+
+// Query in route và giá ask, bid tốt nhất khi giao dịch amount base token từ
+// base tới quote, mỗi chiều gồm route trên một dòng và giá trên dòng tiếp
+// theo, hoặc một dòng lỗi nếu không tìm được route.
+func Query(w io.Writer, graph route.Graph, base, quote string, amount decimal.Decimal,
+	options Options) {
+	// find best ask price
+	bestAsk, err := graph.BestAskRoute(base, quote, amount, options.QueryOptions...)
+	printBest(w, "ask", base, quote, bestAsk, err, options, func() ([]route.RouteResult, error) {
+		return topRoutes(graph.BestAskRoute(base, quote, amount,
+			append(slices.Clip(options.QueryOptions), route.WithTopRoutes(options.Top))...))
+	})
+
+	// find best bid price
+	bestBid, err := graph.BestBidRoute(base, quote, amount, options.QueryOptions...)
+	printBest(w, "bid", base, quote, bestBid, err, options, func() ([]route.RouteResult, error) {
+		return topRoutes(graph.BestBidRoute(base, quote, amount,
+			append(slices.Clip(options.QueryOptions), route.WithTopRoutes(options.Top))...))
+	})
+}
+
+// topRoutes trả về route tốt nhất cùng các route dự phòng của kết quả query
+// với route.WithTopRoutes.
+func topRoutes(result route.RouteResult, err error) ([]route.RouteResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return append([]route.RouteResult{result}, result.Alternatives...), nil
+}
+
+// printBest in kết quả tìm đường tốt nhất của một chiều (side là "ask" hoặc
+// "bid"), kèm chi tiết nếu options.Verbose và các route dự phòng từ top nếu
+// options.Top lớn hơn 1.
+func printBest(w io.Writer, side, base, quote string, result route.RouteResult, err error,
+	options Options, top func() ([]route.RouteResult, error)) {
+	if err != nil {
+		var arbErr *route.ArbitrageError
+		var liqErr *route.LiquidityError
+		switch {
+		case errors.As(err, &arbErr):
+			fmt.Fprintf(w, "Cannot find best %s price %s->%s, arbitrage loop detected: %s.\n",
+				side, quote, base, strings.Join(arbErr.Cycle, "->"))
+		case errors.Is(err, route.ErrArbitrageLoop):
+			fmt.Fprintf(w, "Cannot find best %s price %s->%s, arbitrage loop detected.\n", side, quote, base)
+		case errors.As(err, &liqErr):
+			fmt.Fprintf(w, "Cannot find best %s price %s->%s, insufficient liquidity%s.\n",
+				side, quote, base, liquidityLimit(liqErr))
+		case errors.Is(err, route.ErrNoRoute):
+			fmt.Fprintf(w, "Cannot find best %s price %s->%s, no route.\n", side, quote, base)
+		default:
+			fmt.Fprintf(w, "Cannot find best %s price %s->%s: %v.\n", side, quote, base, err)
+		}
+		return
+	}
+
+	fmt.Fprintln(w, strings.Join(result.Route, "->"))
+	fmt.Fprintln(w, result.Price.StringFixed(6))
+	if options.Verbose {
+		Details(w, result)
+	}
+	if options.Top > 1 {
+		alternatives, err := top()
+		Alternatives(w, alternatives, err)
+	}
+}
+
+// liquidityLimit trả về ", at most <amount> via <route>" với lượng token tối
+// đa giao dịch được của liqErr, rỗng nếu không tính được.
+func liquidityLimit(liqErr *route.LiquidityError) string {
+	maxAmount, maxRoute, err := liqErr.Max(context.Background())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(", at most %s via %s", maxAmount, strings.Join(maxRoute, "->"))
+}
+
+// Alternatives in các route dự phòng sau route tốt nhất (results[0]), theo thứ
+// tự từ tốt tới kém, mỗi route một dòng kèm giá.
+func Alternatives(w io.Writer, results []route.RouteResult, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "  cannot find alternative routes: %v\n", err)
+		return
+	}
+	for i, result := range results[1:] {
+		fmt.Fprintf(w, "  #%d %s %s\n", i+2, strings.Join(result.Route, "->"),
+			result.Price.StringFixed(6))
+	}
+}
+
+// Details in chi tiết từng chặng của route: lệnh cần đặt (chiều giao dịch,
+// trading pair và exchange), lượng token đưa vào, nhận về, giá khớp trung
+// bình, giá khớp sâu nhất, số mức giá đã khớp, mid price và độ trượt giá so
+// với top of book (các giá theo trading pair, quote/base của Symbol), sau đó là
+// mid price và price impact của cả route.
+func Details(w io.Writer, result route.RouteResult) {
+	for _, leg := range result.Legs {
+		pair := leg.Symbol
+		if leg.Venue != "" {
+			pair += "@" + leg.Venue
+		}
+		fmt.Fprintf(w, "  %s->%s (%s %s): in %s, out %s, avg %s, worst %s, levels %d, mid %s, impact %s bps\n",
+			leg.From, leg.To, leg.Side, pair, leg.AmountIn, leg.AmountOut,
+			leg.AveragePrice.StringFixed(6), leg.WorstPrice.StringFixed(6), leg.Levels,
+			leg.MidPrice.StringFixed(6), leg.ImpactBps.StringFixed(2))
+	}
+	fmt.Fprintf(w, "  mid %s, impact %s bps\n",
+		result.MidPrice.StringFixed(6), result.ImpactBps.StringFixed(2))
+}
+
+// Curve in giá và route tốt nhất của từng mức amount của một chiều (side là
+// "ask" hoặc "bid"), đánh dấu "*" ở các mức mà route tối ưu thay đổi, sau đó
+// là lượng token tối đa nếu order book không đủ độ sâu cho các mức lớn.
+func Curve(w io.Writer, side, base, quote string, curve route.PriceCurve, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "Cannot compute %s curve %s->%s: %v.\n", side, quote, base, err)
+		return
+	}
+	fmt.Fprintf(w, "%s curve %s->%s:\n", side, quote, base)
+	for _, point := range curve.Points {
+		var liqErr *route.LiquidityError
+		switch {
+		case errors.As(point.Err, &liqErr):
+			fmt.Fprintf(w, "  %s: insufficient liquidity\n", point.Amount)
+		case errors.Is(point.Err, route.ErrNoRoute):
+			fmt.Fprintf(w, "  %s: no route\n", point.Amount)
+		case point.Err != nil:
+			fmt.Fprintf(w, "  %s: %v\n", point.Amount, point.Err)
+		default:
+			marker := ""
+			if point.RouteChanged {
+				marker = " *"
+			}
+			fmt.Fprintf(w, "  %s: %s %s%s\n", point.Amount, point.Result.Price.StringFixed(6),
+				strings.Join(point.Result.Route, "->"), marker)
+		}
+	}
+	if curve.Exhausted {
+		fmt.Fprintf(w, "  depth exhausted, at most %s via %s\n",
+			curve.MaxAmount, strings.Join(curve.MaxRoute, "->"))
+	}
+}
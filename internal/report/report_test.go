@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+	"github.com/nkngn/kyber-homework/internal/route"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+// newTestGraph tạo trading pair KNC/USDT giống test/expanded_input.txt.
+func newTestGraph() route.Graph {
+	edge := route.OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		AskOrders:  []route.Order{{Price: d("1.1"), Quantity: d("150")}, {Price: d("1.2"), Quantity: d("200")}},
+		BidOrders:  []route.Order{{Price: d("0.9"), Quantity: d("100")}, {Price: d("0.8"), Quantity: d("300")}},
+		Venue:      "binance",
+	}
+	return route.NewGraphWithEdges([]route.Edge{edge, edge.GetReverseEdge()})
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		options Options
+		want    string
+	}{
+		{
+			name:   "Best routes",
+			amount: "100",
+			want:   "USDT->KNC\n1.100000\nKNC->USDT\n0.900000\n",
+		},
+		{
+			name:    "Verbose",
+			amount:  "200",
+			options: Options{Verbose: true},
+			want: "USDT->KNC\n1.125000\n" +
+				"  USDT->KNC (buy KNC/USDT@binance): in 225, out 200, avg 1.125000, worst 1.200000, levels 2, mid 1.000000, impact 227.27 bps\n" +
+				"  mid 1.000000, impact 1250.00 bps\n" +
+				"KNC->USDT\n0.850000\n" +
+				"  KNC->USDT (sell KNC/USDT@binance): in 200, out 170, avg 0.850000, worst 0.800000, levels 2, mid 1.000000, impact 555.56 bps\n" +
+				"  mid 1.000000, impact 1500.00 bps\n",
+		},
+		{
+			name:   "Insufficient liquidity",
+			amount: "500",
+			want: "Cannot find best ask price USDT->KNC, insufficient liquidity, at most 350 via USDT->KNC.\n" +
+				"Cannot find best bid price USDT->KNC, insufficient liquidity, at most 400 via KNC->USDT.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			Query(&out, newTestGraph(), "KNC", "USDT", d(tt.amount), tt.options)
+			if out.String() != tt.want {
+				t.Errorf("Query() output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCurve(t *testing.T) {
+	amounts := []decimal.Decimal{d("100"), d("200"), d("500")}
+	curve, err := newTestGraph().BidCurve("KNC", "USDT", amounts)
+
+	var out bytes.Buffer
+	Curve(&out, "bid", "KNC", "USDT", curve, err)
+	want := "bid curve USDT->KNC:\n" +
+		"  100: 0.900000 KNC->USDT\n" +
+		"  200: 0.850000 KNC->USDT\n" +
+		"  500: insufficient liquidity\n" +
+		"  depth exhausted, at most 400 via KNC->USDT\n"
+	if out.String() != want {
+		t.Errorf("Curve() output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
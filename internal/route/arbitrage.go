@@ -0,0 +1,203 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ArbitrageError mô tả arbitrage loop được phát hiện trong quá trình tìm
+// đường. errors.Is(err, ErrArbitrageLoop) trả về true với lỗi này.
+//   - Cycle: các token trên chu trình theo thứ tự giao dịch, token đầu và
+//     token cuối trùng nhau
+//   - Edges: các cạnh tạo nên chu trình, Edges[i] đi từ Cycle[i] đến
+//     Cycle[i+1]
+//   - Amount: lượng token Cycle[0] lan truyền tới chu trình tại thời điểm
+//     phát hiện
+//   - Profit: tỷ lệ lợi nhuận khi đi hết một vòng với Amount, lớn hơn 1 nghĩa
+//     là có lời. Với bid là lượng thu về / lượng bán ra, với ask là lượng mua
+//     được / lượng phải trả. Bằng 0 nếu không mô phỏng lại được chu trình
+//   - Sell: true nếu phát hiện khi tìm giá bid (SimulateSell), false nếu khi
+//     tìm giá ask (SimulateBuy)
+type ArbitrageError struct {
+	Cycle  []string
+	Edges  []Edge
+	Amount decimal.Decimal
+	Profit decimal.Decimal
+	Sell   bool
+}
+
+func (e *ArbitrageError) Error() string {
+	return fmt.Sprintf("%s: %s (profit ratio %s at amount %s)",
+		ErrArbitrageLoop, strings.Join(e.Cycle, "->"),
+		e.Profit.StringFixed(6), e.Amount)
+}
+
+// Is cho phép so sánh errors.Is(err, ErrArbitrageLoop).
+func (e *ArbitrageError) Is(target error) bool {
+	return target == ErrArbitrageLoop
+}
+
+// arbitrageCycle truy vết chu trình trong prevs xuất phát từ token vừa được
+// cập nhật, theo cách làm chuẩn của Bellman-Ford: lùi lại n bước theo prevs
+// để chắc chắn đã nằm trong chu trình, sau đó đi tiếp cho tới khi gặp lại
+// token ban đầu. amounts là maxAcquired (sell = true) hoặc minRequired
+// (sell = false) tại thời điểm phát hiện.
+//
+// Kết quả trả về *ArbitrageError, hoặc ErrArbitrageLoop nếu không truy vết
+// được chu trình.
+func (g *graph) arbitrageCycle(prevs map[string]Edge,
+	amounts map[string]decimal.Decimal, token string, sell bool) error {
+	current := token
+	for range g.edges.len() {
+		prev, ok := prevs[current]
+		if !ok {
+			return ErrArbitrageLoop
+		}
+		current = prev.From()
+	}
+
+	start := current
+	edges := []Edge{}
+	for {
+		prev, ok := prevs[current]
+		if !ok || len(edges) > g.edges.len() {
+			return ErrArbitrageLoop
+		}
+		edges = append(edges, prev)
+		current = prev.From()
+		if current == start {
+			break
+		}
+	}
+	slices.Reverse(edges)
+
+	cycle := make([]string, 0, len(edges)+1)
+	cycle = append(cycle, start)
+	for _, edge := range edges {
+		cycle = append(cycle, edge.To())
+	}
+
+	amount := amounts[start]
+	return &ArbitrageError{
+		Cycle:  cycle,
+		Edges:  edges,
+		Amount: amount,
+		Profit: cycleProfit(edges, amount, sell),
+		Sell:   sell,
+	}
+}
+
+// cycleProfit mô phỏng đi hết một vòng chu trình với amount, trả về tỷ lệ lợi
+// nhuận theo quy ước của ArbitrageError.Profit.
+func cycleProfit(edges []Edge, amount decimal.Decimal, sell bool) decimal.Decimal {
+	if !amount.IsPositive() {
+		return decimal.Zero
+	}
+
+	current := amount
+	for _, edge := range edges {
+		var isFeasible bool
+		if sell {
+			current, isFeasible = edge.SimulateSell(current)
+		} else {
+			current, isFeasible = edge.SimulateBuy(current)
+		}
+		if !isFeasible || !current.IsPositive() {
+			return decimal.Zero
+		}
+	}
+
+	if sell {
+		return current.Quo(amount)
+	}
+	return amount.Quo(current)
+}
+
+// cycleSet gom các chu trình phát hiện được trong một lần tìm đường, bỏ qua
+// các chu trình trùng nhau (cùng dãy token nhưng bắt đầu từ token khác).
+type cycleSet struct {
+	cycles   []*ArbitrageError
+	keys     map[string]bool
+	untraced bool
+}
+
+// add thêm lỗi trả về từ arbitrageCycle vào tập, lỗi không truy vết được chu
+// trình chỉ được đánh dấu lại.
+func (s *cycleSet) add(err error) {
+	var arbErr *ArbitrageError
+	if !errors.As(err, &arbErr) {
+		s.untraced = true
+		return
+	}
+
+	key := cycleKey(arbErr.Cycle)
+	if s.keys[key] {
+		return
+	}
+	if s.keys == nil {
+		s.keys = map[string]bool{}
+	}
+	s.keys[key] = true
+	s.cycles = append(s.cycles, arbErr)
+}
+
+// err trả về nil nếu tập rỗng, *ArbitrageError nếu chỉ có một chu trình và
+// errors.Join của các chu trình nếu có nhiều hơn. Trả về ErrArbitrageLoop nếu
+// chỉ phát hiện được chu trình mà không truy vết được.
+func (s *cycleSet) err() error {
+	switch len(s.cycles) {
+	case 0:
+		if s.untraced {
+			return ErrArbitrageLoop
+		}
+		return nil
+	case 1:
+		return s.cycles[0]
+	}
+
+	errs := make([]error, 0, len(s.cycles))
+	for _, cycle := range s.cycles {
+		errs = append(errs, cycle)
+	}
+	return errors.Join(errs...)
+}
+
+// arbitrageCycles trả về các *ArbitrageError chứa trong lỗi trả về từ
+// propagateBellmanFord hoặc bellmanFord. Kết quả rỗng nếu lỗi không chứa chu
+// trình nào truy vết được.
+func arbitrageCycles(err error) []*ArbitrageError {
+	if arbErr, ok := err.(*ArbitrageError); ok {
+		return []*ArbitrageError{arbErr}
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	cycles := []*ArbitrageError{}
+	for _, err := range joined.Unwrap() {
+		cycles = append(cycles, arbitrageCycles(err)...)
+	}
+	return cycles
+}
+
+// cycleKey trả về khóa của chu trình, không phụ thuộc vào token bắt đầu.
+func cycleKey(cycle []string) string {
+	if len(cycle) < 2 {
+		return strings.Join(cycle, "->")
+	}
+
+	tokens := cycle[:len(cycle)-1]
+	start := 0
+	for i, token := range tokens {
+		if token < tokens[start] {
+			start = i
+		}
+	}
+	rotated := append(slices.Clone(tokens[start:]), tokens[:start]...)
+	return strings.Join(rotated, "->")
+}
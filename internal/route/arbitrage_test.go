@@ -0,0 +1,176 @@
+package route
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newArbitrageTestGraph tạo đồ thị có chu trình A->B->C->A với giá của các
+// cạnh lần lượt là prices, D chỉ nối với A.
+func newArbitrageTestGraph(prices ...string) Graph {
+	ab := SimpleEdge{BaseToken: "A", QuoteToken: "B", BidPrice: d(prices[0]), AskPrice: d(prices[0])}
+	bc := SimpleEdge{BaseToken: "B", QuoteToken: "C", BidPrice: d(prices[1]), AskPrice: d(prices[1])}
+	ca := SimpleEdge{BaseToken: "C", QuoteToken: "A", BidPrice: d(prices[2]), AskPrice: d(prices[2])}
+	ad := SimpleEdge{BaseToken: "D", QuoteToken: "A", BidPrice: d("1"), AskPrice: d("1")}
+	return NewGraphWithEdges([]Edge{ab, bc, ca, ad, ad.GetReverseEdge()})
+}
+
+func TestGraph_BestBidPrice_ArbitrageError(t *testing.T) {
+	g := newArbitrageTestGraph("2", "3", "0.2")
+
+	_, _, err := g.BestBidPrice("D", "A", d("1"))
+	if !errors.Is(err, ErrArbitrageLoop) {
+		t.Fatalf("BestBidPrice() error = %v, want ErrArbitrageLoop", err)
+	}
+
+	var arbErr *ArbitrageError
+	if !errors.As(err, &arbErr) {
+		t.Fatalf("BestBidPrice() error = %T, want *ArbitrageError", err)
+	}
+	if !arbErr.Sell {
+		t.Errorf("Sell = false, want true")
+	}
+	assertCycle(t, arbErr, []string{"A", "B", "C", "A"})
+
+	// 1 vòng bất kỳ nhân lượng token lên 2 * 3 * 0.2 = 1.2 lần
+	if !arbErr.Profit.Equal(d("1.2")) {
+		t.Errorf("Profit = %v, want 1.2", arbErr.Profit)
+	}
+	if !arbErr.Amount.IsPositive() {
+		t.Errorf("Amount = %v, want positive", arbErr.Amount)
+	}
+}
+
+func TestGraph_BestAskPrice_ArbitrageError(t *testing.T) {
+	// Mua 1 A qua cả vòng chỉ tốn 0.5 * 0.5 * 3 = 0.75 A
+	g := newArbitrageTestGraph("0.5", "0.5", "3")
+
+	_, _, err := g.BestAskPrice("D", "A", d("1"))
+	var arbErr *ArbitrageError
+	if !errors.As(err, &arbErr) {
+		t.Fatalf("BestAskPrice() error = %v, want *ArbitrageError", err)
+	}
+	if arbErr.Sell {
+		t.Errorf("Sell = true, want false")
+	}
+	assertCycle(t, arbErr, []string{"A", "B", "C", "A"})
+	if !arbErr.Profit.GreaterThan(d("1.333333")) {
+		t.Errorf("Profit = %v, want 1/0.75", arbErr.Profit)
+	}
+}
+
+// assertCycle kiểm tra chu trình trong lỗi bằng want, không phụ thuộc vào
+// token bắt đầu của chu trình.
+func assertCycle(t *testing.T, arbErr *ArbitrageError, want []string) {
+	t.Helper()
+	if len(arbErr.Edges) != len(arbErr.Cycle)-1 {
+		t.Fatalf("got %d edges for cycle %v", len(arbErr.Edges), arbErr.Cycle)
+	}
+	for i, edge := range arbErr.Edges {
+		if edge.From() != arbErr.Cycle[i] || edge.To() != arbErr.Cycle[i+1] {
+			t.Errorf("edge %d = %s->%s, want %s->%s", i, edge.From(), edge.To(),
+				arbErr.Cycle[i], arbErr.Cycle[i+1])
+		}
+	}
+
+	got := arbErr.Cycle[:len(arbErr.Cycle)-1]
+	loop := want[:len(want)-1]
+	for i := range loop {
+		rotated := append(slices.Clone(loop[i:]), loop[:i]...)
+		if slices.Equal(got, rotated) {
+			return
+		}
+	}
+	t.Errorf("Cycle = %v, want rotation of %v", arbErr.Cycle, want)
+}
+
+func TestGraph_BestBidRoute_CycleResilient(t *testing.T) {
+	g := newArbitrageTestGraph("2", "3", "0.2")
+	de := SimpleEdge{BaseToken: "D", QuoteToken: "E", BidPrice: d("5"), AskPrice: d("5")}
+	g.AddEdge(de)
+	g.AddEdge(de.GetReverseEdge())
+
+	// Chu trình A->B->C->A không liên quan tới cặp D/E nhưng vẫn làm hỏng
+	// kết quả của Bellman-Ford
+	if _, err := g.BestBidRoute("D", "E", d("1")); !errors.Is(err, ErrArbitrageLoop) {
+		t.Fatalf("BestBidRoute() error = %v, want ErrArbitrageLoop", err)
+	}
+
+	got, err := g.BestBidRoute("D", "E", d("1"), WithCycleResilience())
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithCycleResilience) error = %v", err)
+	}
+	if !slices.Equal(got.Route, []string{"D", "E"}) || !got.Price.Equal(d("5")) {
+		t.Errorf("got route %v price %v, want D->E 5", got.Route, got.Price)
+	}
+	if len(got.Warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(got.Warnings))
+	}
+	assertCycle(t, got.Warnings[0], []string{"A", "B", "C", "A"})
+
+	// Route đi qua token nằm trên chu trình vẫn là đường đi đơn
+	price, path, err := g.BestBidPrice("D", "A", d("1"), WithCycleResilience())
+	if err != nil || !slices.Equal(path, []string{"D", "A"}) || !price.Equal(d("1")) {
+		t.Errorf("BestBidPrice(D, A) = (%v, %v, %v), want (1, D->A, nil)", price, path, err)
+	}
+}
+
+func TestGraph_BestBidRoute_CycleResilient_Exact(t *testing.T) {
+	// Chu trình Y->M->Y lời 6 lần. Nếu mỗi token chỉ giữ một đường đi, M có
+	// thể giữ B->Y->M (3) thay vì B->M (2), khi đó M không đi tiếp qua Y được
+	// và route tối ưu B->M->Y->Q (2 * 2 * 1 = 4) bị mất, tùy thứ tự duyệt
+	// token. Lặp lại nhiều lần để gặp các thứ tự duyệt khác nhau.
+	g := NewGraphWithEdges([]Edge{
+		SimpleEdge{BaseToken: "B", QuoteToken: "Y", BidPrice: d("1")},
+		SimpleEdge{BaseToken: "Y", QuoteToken: "M", BidPrice: d("3")},
+		SimpleEdge{BaseToken: "B", QuoteToken: "M", BidPrice: d("2")},
+		SimpleEdge{BaseToken: "M", QuoteToken: "Y", BidPrice: d("2")},
+		SimpleEdge{BaseToken: "Y", QuoteToken: "Q", BidPrice: d("1")},
+		SimpleEdge{BaseToken: "Q", QuoteToken: "B", BidPrice: d("0.1")},
+	})
+
+	for range 20 {
+		got, err := g.BestBidRoute("B", "Q", d("1"), WithCycleResilience())
+		if err != nil {
+			t.Fatalf("BestBidRoute(WithCycleResilience) error = %v", err)
+		}
+		if !slices.Equal(got.Route, []string{"B", "M", "Y", "Q"}) || !got.Price.Equal(d("4")) {
+			t.Fatalf("got route %v price %v, want B->M->Y->Q 4", got.Route, got.Price)
+		}
+		if len(got.Warnings) == 0 {
+			t.Fatalf("got no warnings, want the Y->M->Y cycle")
+		}
+	}
+}
+
+func TestGraph_BestAskRoute_CycleTolerance(t *testing.T) {
+	// Chu trình lời 1/0.96 - 1 ~ 4.17%
+	g := newArbitrageTestGraph("0.8", "0.4", "3")
+
+	tests := []struct {
+		name      string
+		tolerance string
+		wantErr   bool
+	}{
+		{name: "Below profit", tolerance: "0.04", wantErr: true},
+		{name: "Above profit", tolerance: "0.05", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.BestAskRoute("D", "A", d("1"), WithCycleTolerance(d(tt.tolerance)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BestAskRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Warnings) != 0 {
+				t.Errorf("got warnings %v, want none", got.Warnings)
+			}
+			if !slices.Equal(got.Route, []string{"A", "D"}) || !got.Price.Equal(d("1")) {
+				t.Errorf("got route %v price %v, want A->D 1", got.Route, got.Price)
+			}
+		})
+	}
+}
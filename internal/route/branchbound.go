@@ -0,0 +1,282 @@
+package route
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// BranchAndBound trả về RouteFinder tìm route tối ưu chính xác trong các
+// đường đi đơn có tối đa maxHops cạnh, maxHops không dương là không giới hạn
+// (tối đa số token - 1 cạnh). Xem graph.branchAndBound.
+//
+// Với OrderEdge, giá của một cạnh phụ thuộc lượng token đi vào cạnh nên việc
+// chỉ giữ lượng token tốt nhất cho mỗi token của Bellman-Ford là heuristic:
+// prevs của một token có thể bị ghi đè sau khi các token phía sau đã được cập
+// nhật. BranchAndBound không có vấn đề này nên phù hợp làm thuật toán mặc định
+// cho đồ thị nhỏ (xem WithExactSearchLimit) và làm chuẩn để kiểm tra kết quả
+// của các thuật toán khác. Giống DFS, arbitrage loop không được phát hiện vì
+// chỉ đường đi đơn được xét.
+func BranchAndBound(maxHops int) RouteFinder {
+	return branchAndBoundFinder{maxHops: max(maxHops, 0)}
+}
+
+// branchAndBoundFinder là RouteFinder của BranchAndBound. incumbent là các
+// cạnh (từ base tới quote) của một route đã biết, ví dụ route của mức amount
+// trước trong price curve, dùng làm route tốt nhất ban đầu để loại bỏ nhánh
+// ngay từ đầu, nil nếu không có.
+type branchAndBoundFinder struct {
+	maxHops   int
+	incumbent []Edge
+}
+
+func (branchAndBoundFinder) Name() string { return "branch-and-bound" }
+
+func (f branchAndBoundFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	// Đường đi đơn có tối đa n-1 cạnh
+	limit := max(g.vertexCount()-1, 1)
+	if f.maxHops > 0 {
+		limit = min(limit, f.maxHops)
+	}
+	if maxHops > 0 {
+		limit = min(limit, maxHops)
+	}
+	return g.branchAndBound(ctx, base, quote, amount, sell, limit, f.incumbent)
+}
+
+// topOfBookEdge là cạnh cho biết giá tốt nhất của order book.
+type topOfBookEdge interface {
+	// topPrice trả về giá tốt nhất khi bán (best bid, sell = true) hoặc mua
+	// (best ask) base token của cạnh, false nếu phía đó không có thanh khoản.
+	// Bán x base token không thu được nhiều hơn x * best bid, mua x base token
+	// không tốn ít hơn x * best ask.
+	topPrice(sell bool) (decimal.Decimal, bool)
+}
+
+// topPrice trả về BidPrice (sell = true) hoặc AskPrice nếu dương.
+func (e SimpleEdge) topPrice(sell bool) (decimal.Decimal, bool) {
+	price := e.AskPrice
+	if sell {
+		price = e.BidPrice
+	}
+	return price, price.IsPositive()
+}
+
+// topPrice trả về giá bid cao nhất (sell = true) hoặc giá ask thấp nhất. Các
+// mức giá đều được duyệt nên order book không cần được sắp xếp. Đây là định
+// nghĩa top of book duy nhất của OrderEdge, dùng cho cả cận của
+// BranchAndBound lẫn mid price và độ trượt giá của fillStats.
+func (e OrderEdge) topPrice(sell bool) (decimal.Decimal, bool) {
+	best, found := decimal.Zero, false
+	for order := range e.orders(sell) {
+		switch {
+		case !found:
+			best, found = order.Price, true
+		case sell:
+			best = decimal.Max(best, order.Price)
+		default:
+			best = decimal.Min(best, order.Price)
+		}
+	}
+	return best, found && best.IsPositive()
+}
+
+// rateBounds trả về cận của tỷ lệ quy đổi từ mỗi token tới quote:
+// bounds[h][token] là tích giá top of book tốt nhất (lớn nhất khi sell, nhỏ
+// nhất khi mua) trên các đường đi có tối đa h cạnh từ token tới quote. Token
+// không có trong bounds[h] không tới được quote trong h cạnh.
+//
+// Lượng token nhận về khi bán không vượt quá amount * tích các best bid, lượng
+// token phải trả khi mua không ít hơn amount * tích các best ask, vì phí và
+// các mức giá sâu hơn chỉ làm kết quả xấu đi. Tích được làm tròn theo chiều
+// lạc quan nên luôn là cận đúng. Trả về nil nếu có cạnh không phải
+// topOfBookEdge, khi đó không có cận.
+func (g *graph) rateBounds(quote string, sell bool, maxHops int) []map[string]decimal.Decimal {
+	mode := decimal.RoundDown
+	if sell {
+		mode = decimal.RoundUp
+	}
+
+	bounds := make([]map[string]decimal.Decimal, maxHops+1)
+	bounds[0] = map[string]decimal.Decimal{quote: decimal.One}
+	for h := 1; h <= maxHops; h++ {
+		bounds[h] = map[string]decimal.Decimal{quote: decimal.One}
+		for token := range g.edges.tokens() {
+			if token == quote {
+				continue
+			}
+			for edge := range g.outgoing(token) {
+				top, ok := edge.(topOfBookEdge)
+				if !ok {
+					return nil
+				}
+				price, ok := top.topPrice(sell)
+				if !ok {
+					continue
+				}
+				next, ok := bounds[h-1][edge.To()]
+				if !ok {
+					continue
+				}
+				rate := price.MulRound(next, mode)
+				if current, ok := bounds[h][token]; !ok ||
+					(sell && rate.GreaterThan(current)) || (!sell && rate.LessThan(current)) {
+					bounds[h][token] = rate
+				}
+			}
+		}
+	}
+	return bounds
+}
+
+// branchAndBound giống dfs nhưng loại bỏ (prune) các nhánh không thể tốt hơn
+// route tốt nhất đã tìm được: tại token với lượng value và còn r cạnh, lượng
+// quote token tốt nhất có thể đạt được bị chặn bởi value * rateBounds[r][token].
+// Các cạnh đi ra được duyệt theo cận tốt nhất trước để sớm tìm được route tốt
+// và loại bỏ được nhiều nhánh hơn.
+//
+// Nếu incumbent (các cạnh từ base tới quote) mô phỏng lại được với amount và
+// có không quá maxHops cạnh, route này là route tốt nhất ban đầu: chỉ các nhánh
+// tốt hơn hẳn incumbent được duyệt, và incumbent được trả về nếu không có
+// nhánh nào như vậy.
+//
+// Kết quả là route tối ưu trong các đường đi đơn có tối đa maxHops cạnh, giống
+// dfs với cùng maxHops, khác biệt chỉ có thể ở việc chọn route nào khi nhiều
+// route cho cùng một lượng token.
+func (g *graph) branchAndBound(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int, incumbent []Edge) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	mode := decimal.RoundDown
+	if sell {
+		mode = decimal.RoundUp
+	}
+	bounds := g.rateBounds(quote, sell, maxHops)
+
+	var best *pathLabel
+	if len(incumbent) <= maxHops {
+		best = incumbentLabel(incumbent, amount, sell)
+	}
+	// bound trả về cận của lượng quote token khi đi tiếp từ token với value và
+	// còn remaining cạnh, false nếu không tới được quote
+	bound := func(value decimal.Decimal, token string, remaining int) (decimal.Decimal, bool) {
+		if bounds == nil {
+			return value, true
+		}
+		rate, ok := bounds[remaining][token]
+		if !ok {
+			return decimal.Zero, false
+		}
+		return value.MulRound(rate, mode), true
+	}
+	// promising kiểm tra cận có thể tốt hơn route tốt nhất hay không
+	promising := func(limit decimal.Decimal) bool {
+		if bounds == nil || best == nil {
+			return true
+		}
+		if sell {
+			return limit.GreaterThan(best.value)
+		}
+		return limit.LessThan(best.value)
+	}
+
+	type branch struct {
+		label *pathLabel
+		limit decimal.Decimal
+	}
+	var visit func(label *pathLabel, token string, hops int) bool
+	visit = func(label *pathLabel, token string, hops int) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if token == quote {
+			if best == nil || (sell && label.value.GreaterThan(best.value)) ||
+				(!sell && label.value.LessThan(best.value)) {
+				best = label
+			}
+			return true
+		}
+		if hops == maxHops {
+			return true
+		}
+
+		var branches []branch
+		for edge := range g.outgoing(token) {
+			if edge.To() == base || label.visits(edge.To()) {
+				continue
+			}
+			value, feasible := simulate(edge, label.value, sell)
+			if !feasible || (sell && value.IsZero()) {
+				continue
+			}
+			limit, ok := bound(value, edge.To(), maxHops-hops-1)
+			if !ok || !promising(limit) {
+				continue
+			}
+			branches = append(branches, branch{
+				label: &pathLabel{edge: edge, prev: label, value: value},
+				limit: limit,
+			})
+		}
+		if bounds != nil {
+			slices.SortStableFunc(branches, func(a, b branch) int {
+				if sell {
+					return b.limit.Cmp(a.limit)
+				}
+				return a.limit.Cmp(b.limit)
+			})
+		}
+
+		for _, next := range branches {
+			// best có thể đã tốt hơn sau các nhánh trước
+			if !promising(next.limit) {
+				continue
+			}
+			if !visit(next.label, next.label.edge.To(), hops+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	finished := visit(&pathLabel{value: amount}, base, 0)
+	if best == nil {
+		if !finished {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, ErrNoRoute
+	}
+
+	values, prevs := best.result(base)
+	if !finished {
+		return values, prevs, ctx.Err()
+	}
+	return values, prevs, nil
+}
+
+// incumbentLabel trả về pathLabel của đường đi qua các cạnh edges với amount,
+// nil nếu edges rỗng hoặc không mô phỏng lại được.
+func incumbentLabel(edges []Edge, amount decimal.Decimal, sell bool) *pathLabel {
+	if len(edges) == 0 {
+		return nil
+	}
+	label := &pathLabel{value: amount}
+	for _, edge := range edges {
+		value, feasible := simulate(edge, label.value, sell)
+		if !feasible || (sell && value.IsZero()) {
+			return nil
+		}
+		label = &pathLabel{edge: edge, prev: label, value: value, hops: label.hops + 1}
+	}
+	return label
+}
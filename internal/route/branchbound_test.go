@@ -0,0 +1,106 @@
+package route
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGraph_RateBounds(t *testing.T) {
+	g := newTopKTestGraph().(*syncGraph).snapshot()
+
+	tests := []struct {
+		name  string
+		sell  bool
+		hops  int
+		token string
+		want  string
+	}{
+		{name: "Bid direct", sell: true, hops: 1, token: "KNC", want: "0.9"},
+		{name: "Bid via ETH", sell: true, hops: 2, token: "KNC", want: "0.9"},
+		{name: "Bid ETH", sell: true, hops: 1, token: "ETH", want: "350"},
+		{name: "Ask via ETH", sell: false, hops: 2, token: "KNC", want: "0.936"},
+		{name: "Ask direct", sell: false, hops: 1, token: "KNC", want: "1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := g.rateBounds("USDT", tt.sell, tt.hops)
+			if got := bounds[tt.hops][tt.token]; !got.Equal(d(tt.want)) {
+				t.Errorf("bounds[%d][%s] = %s, want %s", tt.hops, tt.token, got, tt.want)
+			}
+		})
+	}
+
+	if bounds := g.rateBounds("USDT", true, 0); len(bounds[0]) != 1 {
+		t.Errorf("bounds[0] = %v, want only USDT", bounds[0])
+	}
+}
+
+func TestGraph_BranchAndBound_MatchesDFS(t *testing.T) {
+	ctx := context.Background()
+	graphs := map[string]Graph{
+		"Simple": newTopKTestGraph(),
+		"Order":  newSplitTestGraph(),
+	}
+
+	for name, graph := range graphs {
+		g := graph.(*syncGraph).snapshot()
+		for _, amount := range []string{"1", "50", "150"} {
+			for _, sell := range []bool{true, false} {
+				for hops := 1; hops <= 3; hops++ {
+					want, _, wantErr := g.dfs(ctx, "KNC", "USDT", d(amount), sell, hops)
+					got, prevs, err := g.branchAndBound(ctx, "KNC", "USDT", d(amount), sell, hops, nil)
+					if (err == nil) != (wantErr == nil) {
+						t.Fatalf("%s %s sell=%v hops=%d: error = %v, want %v",
+							name, amount, sell, hops, err, wantErr)
+					}
+					if err != nil {
+						continue
+					}
+					if !got["USDT"].Equal(want["USDT"]) {
+						t.Errorf("%s %s sell=%v hops=%d: value = %s, want %s",
+							name, amount, sell, hops, got["USDT"], want["USDT"])
+					}
+					edges, ok := getEdges(prevs, "KNC", "USDT")
+					if !ok || len(edges) > hops {
+						t.Errorf("%s %s sell=%v hops=%d: route = %v",
+							name, amount, sell, hops, getPath(prevs, "KNC", "USDT"))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGraph_ExactSearchLimit(t *testing.T) {
+	g := newTopKTestGraph()
+
+	tests := []struct {
+		name string
+		opts []QueryOption
+		want string
+	}{
+		{name: "Default", want: "bellman-ford"},
+		{name: "Small graph", opts: []QueryOption{WithExactSearchLimit(4)}, want: "branch-and-bound"},
+		{name: "Large graph", opts: []QueryOption{WithExactSearchLimit(3)}, want: "bellman-ford"},
+		{name: "Filtered graph", opts: []QueryOption{WithExactSearchLimit(3), WithExcludedTokens("DAI")},
+			want: "branch-and-bound"},
+		{name: "Explicit finder", opts: []QueryOption{WithExactSearchLimit(4), WithFinder(SPFA())},
+			want: "spfa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.BestBidRoute("KNC", "USDT", d("100"), tt.opts...)
+			if err != nil {
+				t.Fatalf("BestBidRoute() error = %v", err)
+			}
+			if got.Algorithm != tt.want {
+				t.Errorf("Algorithm = %q, want %q", got.Algorithm, tt.want)
+			}
+			if !got.Price.Equal(d("0.9")) {
+				t.Errorf("Price = %s, want 0.9", got.Price)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// cancelEdge hủy context của query khi được mô phỏng, dùng để dừng thuật toán
+// tìm đường giữa chừng.
+type cancelEdge struct {
+	SimpleEdge
+	cancel context.CancelFunc
+}
+
+func (e cancelEdge) SimulateSell(amount decimal.Decimal) (decimal.Decimal, bool) {
+	e.cancel()
+	return e.SimpleEdge.SimulateSell(amount)
+}
+
+// newCancelTestGraph giống newFinderTestGraph nhưng cạnh A->C hủy context khi
+// được mô phỏng, tức trong lượt duyệt đầu tiên của Bellman-Ford.
+func newCancelTestGraph(cancel context.CancelFunc) Graph {
+	ab := SimpleEdge{BaseToken: "A", QuoteToken: "B", BidPrice: d("0.5"), AskPrice: d("0.5")}
+	bc := SimpleEdge{BaseToken: "B", QuoteToken: "C", BidPrice: d("10"), AskPrice: d("10")}
+	ac := SimpleEdge{BaseToken: "A", QuoteToken: "C", BidPrice: d("2"), AskPrice: d("2")}
+	cd := SimpleEdge{BaseToken: "C", QuoteToken: "D", BidPrice: d("1"), AskPrice: d("1")}
+	return NewGraphWithEdges([]Edge{ab, bc, cancelEdge{SimpleEdge: ac, cancel: cancel}, cd})
+}
+
+func TestGraph_BestBidRoute_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, finder := range []RouteFinder{BellmanFord(), UCS(), SPFA(), DFS(0)} {
+		_, err := newFinderTestGraph().BestBidRoute("A", "C", d("1"),
+			WithContext(ctx), WithFinder(finder), WithPartialResult())
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: BestBidRoute(WithContext) error = %v, want context.Canceled",
+				finder.Name(), err)
+		}
+	}
+
+	_, _, err := newFinderTestGraph().BestAskPrice("A", "C", d("1"), WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("BestAskPrice(WithContext) error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGraph_BestBidRoute_ContextPartial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := newCancelTestGraph(cancel)
+
+	got, err := g.BestBidRoute("A", "C", d("1"), WithContext(ctx), WithPartialResult())
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithContext) error = %v", err)
+	}
+	if !got.Partial {
+		t.Errorf("Partial = false, want true")
+	}
+	// Lượt đầu tiên chắc chắn đã nới cạnh A->C, route qua B có thể chưa
+	if got.Price.LessThan(d("2")) {
+		t.Errorf("Price = %v, want at least 2", got.Price)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	g = newCancelTestGraph(cancel)
+	_, err = g.BestBidRoute("A", "C", d("1"), WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("BestBidRoute(WithContext) error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGraph_BestBidRoute_ContextComplete(t *testing.T) {
+	got, err := newFinderTestGraph().BestBidRoute("A", "C", d("1"),
+		WithContext(context.Background()), WithPartialResult())
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithContext) error = %v", err)
+	}
+	if got.Partial || !got.Price.Equal(d("5")) {
+		t.Errorf("got Price = %v, Partial = %v, want 5, false", got.Price, got.Partial)
+	}
+}
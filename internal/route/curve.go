@@ -0,0 +1,297 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ErrInvalidLadder nghĩa là các mức amount của price curve không hợp lệ: rỗng,
+// có amount không dương hoặc không tăng dần.
+var ErrInvalidLadder = errors.New("invalid amount ladder")
+
+// Spacing là cách chia các mức amount của Ladder.
+type Spacing int
+
+const (
+	// LinearSpacing chia đều khoảng cách giữa các mức amount.
+	LinearSpacing Spacing = iota
+
+	// LogSpacing chia đều tỷ lệ giữa các mức amount liền kề, phù hợp khi
+	// khoảng amount trải qua nhiều bậc độ lớn.
+	LogSpacing
+)
+
+// Ladder trả về steps mức amount tăng dần từ from tới to, kể cả hai đầu mút.
+// Với LogSpacing, tỷ lệ giữa các mức liền kề là căn bậc steps - 1 của to /
+// from, tính bằng decimal (xem nthRoot), và các mức ở giữa được làm tròn tới
+// 12 chữ số có nghĩa. Trả về ErrInvalidLadder nếu steps < 2, from không dương,
+// from >= to hoặc các mức quá sát nhau để phân biệt được.
+func Ladder(from, to decimal.Decimal, steps int, spacing Spacing) ([]decimal.Decimal, error) {
+	if steps < 2 || !from.IsPositive() || from.GreaterThanOrEqual(to) {
+		return nil, ErrInvalidLadder
+	}
+
+	amounts := make([]decimal.Decimal, steps)
+	amounts[0], amounts[steps-1] = from, to
+	intervals := decimal.NewFromInt(int64(steps - 1))
+	width := to.Sub(from)
+	ratio := nthRoot(to.QuoRound(from, decimal.RoundHalfEven), steps-1)
+	amount := from
+	for i := 1; i < steps-1; i++ {
+		switch spacing {
+		case LogSpacing:
+			amount = amount.Mul(ratio)
+			amounts[i] = roundSignificant(amount, 12)
+		default:
+			amounts[i] = from.Add(width.Mul(decimal.NewFromInt(int64(i))).
+				QuoRound(intervals, decimal.RoundHalfEven))
+		}
+	}
+	if err := checkLadder(amounts); err != nil {
+		return nil, err
+	}
+	return amounts, nil
+}
+
+// nthRoot trả về căn bậc n của a > 1 bằng phương pháp Newton trên decimal.
+// Giá trị ban đầu 1 + (a - 1) / n không nhỏ hơn căn (bất đẳng thức Bernoulli)
+// nên dãy giảm dần tới căn, việc lặp dừng khi dãy không còn giảm.
+func nthRoot(a decimal.Decimal, n int) decimal.Decimal {
+	count := decimal.NewFromInt(int64(n))
+	x := decimal.One.Add(a.Sub(decimal.One).QuoRound(count, decimal.RoundUp))
+	for {
+		power := decimal.One
+		for range n - 1 {
+			power = power.Mul(x)
+		}
+		next := x.Mul(count.Sub(decimal.One)).
+			Add(a.QuoRound(power, decimal.RoundHalfEven)).
+			QuoRound(count, decimal.RoundHalfEven)
+		if !next.LessThan(x) {
+			return x
+		}
+		x = next
+	}
+}
+
+// roundSignificant làm tròn v > 0 tới digits chữ số có nghĩa, trong giới hạn
+// số chữ số thập phân của decimal.
+func roundSignificant(v decimal.Decimal, digits int) decimal.Decimal {
+	// magnitude là số mũ của chữ số khác 0 đầu tiên, ví dụ 2 với 123.4 và -3
+	// với 0.00123
+	integer, fraction, _ := strings.Cut(v.String(), ".")
+	magnitude := len(integer) - 1
+	if integer == "0" {
+		magnitude = -1 - (len(fraction) - len(strings.TrimLeft(fraction, "0")))
+	}
+	places := max(0, min(digits-1-magnitude, decimal.Scale))
+	return v.Round(int32(places), decimal.RoundHalfEven)
+}
+
+// checkLadder kiểm tra các mức amount dương và tăng dần.
+func checkLadder(amounts []decimal.Decimal) error {
+	if len(amounts) == 0 || !amounts[0].IsPositive() {
+		return ErrInvalidLadder
+	}
+	for i := 1; i < len(amounts); i++ {
+		if !amounts[i].GreaterThan(amounts[i-1]) {
+			return ErrInvalidLadder
+		}
+	}
+	return nil
+}
+
+// CurvePoint là kết quả tìm đường tại một mức amount của PriceCurve.
+//   - Amount: lượng token của query, giống amount của BestBidRoute
+//   - Result: kết quả tìm đường, rỗng nếu Err khác nil
+//   - Err: lỗi tìm đường tại mức này, *LiquidityError nếu vượt quá độ sâu
+//     của order book
+//   - RouteChanged: route tối ưu khác route của mức khả thi liền trước, tính
+//     cả exchange của từng chặng
+type CurvePoint struct {
+	Amount       decimal.Decimal
+	Result       RouteResult
+	Err          error
+	RouteChanged bool
+}
+
+// PriceCurve là giá và route tốt nhất theo từng mức amount của một token pair.
+//   - Points: kết quả của từng mức amount, theo thứ tự tăng dần
+//   - Exhausted: có mức amount vượt quá độ sâu của order book
+//   - MaxAmount, MaxRoute: lượng token lớn nhất giao dịch được và route tương
+//     ứng khi Exhausted, MaxAmount bằng 0 nếu không amount dương nào khả thi
+type PriceCurve struct {
+	Points    []CurvePoint
+	Exhausted bool
+	MaxAmount decimal.Decimal
+	MaxRoute  []string
+}
+
+// Switches trả về chỉ số các điểm mà route tối ưu thay đổi.
+func (c PriceCurve) Switches() []int {
+	var switches []int
+	for i, point := range c.Points {
+		if point.RouteChanged {
+			switches = append(switches, i)
+		}
+	}
+	return switches
+}
+
+// BidCurve tính giá bid và route tốt nhất cho từng mức amount tăng dần, giống
+// gọi BestBidRoute với từng amount trên cùng một snapshot, ví dụ với
+// các mức từ Ladder. Công việc được dùng lại giữa các mức:
+//   - kết nối giữa base và quote chỉ được kiểm tra một lần
+//   - route của mức khả thi liền trước là route ban đầu (incumbent) của mức
+//     tiếp theo, xem curveRoute: với BranchAndBound, các nhánh không tốt hơn
+//     incumbent bị loại bỏ ngay từ đầu; với các thuật toán khác, incumbent
+//     được chọn nếu tốt hơn kết quả tìm đường. Không áp dụng với
+//     WithExactQuote
+//   - mức đầu tiên vượt quá độ sâu của order book được binary search từ mức
+//     khả thi liền trước thay vì từ đầu, xem WithMaxAmount
+//   - các mức lớn hơn được đánh dấu không khả thi mà không cần tìm đường, vì
+//     tính khả thi là đơn điệu theo amount
+//
+// Trả về ErrInvalidLadder nếu amounts không dương và tăng dần, ErrNoRoute nếu
+// base và quote không kết nối. Lỗi tìm đường của từng mức nằm trong
+// CurvePoint.Err, trừ khi context của WithContext kết thúc: khi đó các điểm đã
+// tính được trả về cùng lỗi của context. WithTopRoutes và WithMaxAmount không
+// có tác dụng.
+func (g *graph) BidCurve(base, quote string, amounts []decimal.Decimal,
+	opts ...QueryOption) (PriceCurve, error) {
+	options := newQueryOptions(opts)
+	return g.curve(options.context(), base, quote, amounts, true, options)
+}
+
+// AskCurve giống BidCurve nhưng tính giá ask, xem BestAskRoute.
+func (g *graph) AskCurve(base, quote string, amounts []decimal.Decimal,
+	opts ...QueryOption) (PriceCurve, error) {
+	options := newQueryOptions(opts)
+	return g.curve(options.context(), base, quote, amounts, false, options)
+}
+
+func (g *graph) curve(ctx context.Context, base, quote string,
+	amounts []decimal.Decimal, sell bool, options QueryOptions) (PriceCurve, error) {
+	if err := checkLadder(amounts); err != nil {
+		return PriceCurve{}, err
+	}
+	options.PartialResult = false
+	if !g.view(options).connected(base, quote, options.MaxHops) {
+		return PriceCurve{}, ErrNoRoute
+	}
+
+	curve := PriceCurve{Points: make([]CurvePoint, 0, len(amounts))}
+	var previous *RouteResult
+	var incumbent []Edge
+	low := decimal.Zero
+	for _, amount := range amounts {
+		point := CurvePoint{Amount: amount}
+		if curve.Exhausted {
+			point.Err = curve.exhaustedError(amount, sell)
+			curve.Points = append(curve.Points, point)
+			continue
+		}
+
+		result, err := g.curveRoute(ctx, base, quote, amount, sell, options, incumbent)
+		switch {
+		case err == nil:
+			point.Result = result
+			point.RouteChanged = previous != nil && !sameRoute(*previous, result)
+			previous, low = &point.Result, amount
+			incumbent = routeEdges(result, sell)
+		case ctx.Err() != nil:
+			return curve, ctx.Err()
+		case errors.Is(err, ErrNoRoute):
+			maxAmount, err := g.bisect(ctx, base, quote, low, amount, sell, options)
+			if err != nil {
+				return curve, err
+			}
+			curve.Exhausted, curve.MaxAmount = true, maxAmount
+			if maxAmount.IsPositive() {
+				result, err := g.findRoute(ctx, base, quote, maxAmount, sell, options)
+				if err != nil {
+					return curve, err
+				}
+				curve.MaxRoute = result.Route
+			}
+			point.Err = curve.exhaustedError(amount, sell)
+		default:
+			point.Err = err
+		}
+		curve.Points = append(curve.Points, point)
+	}
+	return curve, nil
+}
+
+// incumbentAlgorithm là RouteResult.Algorithm của điểm trên price curve dùng
+// lại route của mức trước vì route này tốt hơn kết quả tìm đường.
+const incumbentAlgorithm = "incumbent"
+
+// curveRoute giống findRoute nhưng dùng incumbent (các cạnh từ base tới quote
+// của route ở mức amount trước) làm route ban đầu: với BranchAndBound (chọn qua
+// WithFinder hoặc WithExactSearchLimit), incumbent là cận để loại bỏ nhánh;
+// với các thuật toán khác, kết quả tìm đường được so sánh với incumbent mô
+// phỏng lại ở amount và route tốt hơn được chọn. Nếu tìm đường trả về
+// ErrNoRoute nhưng incumbent vẫn khả thi, incumbent được dùng.
+func (g *graph) curveRoute(ctx context.Context, base, quote string, amount decimal.Decimal,
+	sell bool, options QueryOptions, incumbent []Edge) (RouteResult, error) {
+	if len(incumbent) == 0 || options.ExactQuote {
+		return g.findRoute(ctx, base, quote, amount, sell, options)
+	}
+
+	view := g.view(options)
+	finder := options.Finder
+	if finder == nil && len(options.Race) == 0 && view.vertexCount() <= options.ExactSearchLimit {
+		finder = BranchAndBound(0)
+	}
+	if bb, ok := finder.(branchAndBoundFinder); ok && len(options.Race) == 0 {
+		bb.incumbent = incumbent
+		options.Finder = bb
+	}
+
+	result, err := g.findRoute(ctx, base, quote, amount, sell, options)
+	if err != nil && !errors.Is(err, ErrNoRoute) {
+		return RouteResult{}, err
+	}
+	_, value, ok := replay(incumbent, amount, sell)
+	if !ok || (sell && value.IsZero()) {
+		return result, err
+	}
+	if err == nil && ((sell && !value.GreaterThan(result.AmountOut)) ||
+		(!sell && !value.LessThan(result.AmountIn))) {
+		return result, nil
+	}
+	return view.newRouteResult(base, incumbent, amount, sell, searchResult{algorithm: incumbentAlgorithm})
+}
+
+// routeEdges trả về các cạnh của result theo chiều từ base tới quote.
+func routeEdges(result RouteResult, sell bool) []Edge {
+	edges := make([]Edge, 0, len(result.Legs))
+	for _, leg := range result.Legs {
+		edges = append(edges, leg.Edge)
+	}
+	if !sell {
+		slices.Reverse(edges)
+	}
+	return edges
+}
+
+// exhaustedError trả về lỗi của mức amount vượt quá độ sâu của order book:
+// *LiquidityError, hoặc ErrNoRoute nếu không amount dương nào khả thi.
+func (c PriceCurve) exhaustedError(amount decimal.Decimal, sell bool) error {
+	if !c.MaxAmount.IsPositive() {
+		return ErrNoRoute
+	}
+	return NewLiquidityError(amount, c.MaxAmount, c.MaxRoute, sell)
+}
+
+// sameRoute so sánh token và exchange của từng chặng của hai route.
+func sameRoute(a, b RouteResult) bool {
+	return slices.EqualFunc(a.Legs, b.Legs, func(x, y Leg) bool {
+		return x.From == y.From && x.To == y.To && x.Venue == y.Venue
+	})
+}
@@ -0,0 +1,247 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func TestLadder(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		steps   int
+		spacing Spacing
+		want    []string
+		wantErr bool
+	}{
+		{name: "Linear", from: "100", to: "500", steps: 5, spacing: LinearSpacing,
+			want: []string{"100", "200", "300", "400", "500"}},
+		{name: "Log", from: "1", to: "1000", steps: 4, spacing: LogSpacing,
+			want: []string{"1", "10", "100", "1000"}},
+		{name: "Log rounded", from: "1", to: "3", steps: 3, spacing: LogSpacing,
+			want: []string{"1", "1.73205080757", "3"}},
+		{name: "Log wide range", from: "0.001", to: "1000000000", steps: 13, spacing: LogSpacing,
+			want: []string{"0.001", "0.01", "0.1", "1", "10", "100", "1000", "10000", "100000",
+				"1000000", "10000000", "100000000", "1000000000"}},
+		{name: "Too few steps", from: "1", to: "2", steps: 1, wantErr: true},
+		{name: "Zero start", from: "0", to: "2", steps: 3, wantErr: true},
+		{name: "Decreasing", from: "2", to: "1", steps: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Ladder(d(tt.from), d(tt.to), tt.steps, tt.spacing)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLadder) {
+					t.Fatalf("Ladder() error = %v, want ErrInvalidLadder", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Ladder() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Ladder() = %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				// Các mức log spacing được làm tròn tới 12 chữ số có nghĩa
+				if got[i].Sub(d(want)).Abs().IsPositive() {
+					t.Errorf("Ladder()[%d] = %s, want %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGraph_BidCurve(t *testing.T) {
+	kncUSDT := newDepthTestEdge()
+	// Qua ETH bán được 1000 KNC với giá 0.0024 * 350 = 0.84 USDT
+	kncETH := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "ETH",
+		AskOrders:  []Order{{Price: d("0.0025"), Quantity: d("1000")}},
+		BidOrders:  []Order{{Price: d("0.0024"), Quantity: d("1000")}},
+	}
+	ethUSDT := OrderEdge{
+		BaseToken:  "ETH",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("360"), Quantity: d("10")}},
+		BidOrders:  []Order{{Price: d("350"), Quantity: d("10")}},
+	}
+	g := NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		kncETH, kncETH.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+
+	amounts, err := Ladder(d("100"), d("1500"), 8, LinearSpacing)
+	if err != nil {
+		t.Fatalf("Ladder() error = %v", err)
+	}
+	curve, err := g.BidCurve("KNC", "USDT", amounts)
+	if err != nil {
+		t.Fatalf("BidCurve() error = %v", err)
+	}
+
+	// Bán trực tiếp tốt hơn tới 250 KNC, sau đó route qua ETH tốt hơn
+	// tới 1000 KNC
+	direct, viaETH := []string{"KNC", "USDT"}, []string{"KNC", "ETH", "USDT"}
+	wantRoutes := [][]string{direct, viaETH, viaETH, viaETH, viaETH}
+	if len(curve.Points) != len(amounts) {
+		t.Fatalf("len(Points) = %d, want %d", len(curve.Points), len(amounts))
+	}
+	for i, want := range wantRoutes {
+		point := curve.Points[i]
+		if point.Err != nil || !slices.Equal(point.Result.Route, want) {
+			t.Errorf("Points[%d] = %v, %v, want %v", i, point.Result.Route, point.Err, want)
+		}
+	}
+	if !curve.Points[0].Result.Price.Equal(d("0.9")) ||
+		!curve.Points[1].Result.Price.Equal(d("0.84")) {
+		t.Errorf("prices = %s, %s, want 0.9, 0.84",
+			curve.Points[0].Result.Price, curve.Points[1].Result.Price)
+	}
+	if got := curve.Switches(); !slices.Equal(got, []int{1}) {
+		t.Errorf("Switches() = %v, want [1]", got)
+	}
+
+	if !curve.Exhausted || !curve.MaxAmount.Equal(d("1000")) ||
+		!slices.Equal(curve.MaxRoute, viaETH) {
+		t.Errorf("curve exhausted = %v, max %s via %v, want 1000 via %v",
+			curve.Exhausted, curve.MaxAmount, curve.MaxRoute, viaETH)
+	}
+	for _, point := range curve.Points[len(wantRoutes):] {
+		var liqErr *LiquidityError
+		if !errors.As(point.Err, &liqErr) || !liqErr.Amount.Equal(point.Amount) {
+			t.Errorf("point %s error = %v, want *LiquidityError", point.Amount, point.Err)
+			continue
+		}
+		if maxAmount, _, err := liqErr.Max(context.Background()); err != nil || !maxAmount.Equal(d("1000")) {
+			t.Errorf("point %s error = %v, want *LiquidityError with max 1000",
+				point.Amount, point.Err)
+		}
+	}
+}
+
+// detourFinder chỉ tìm route tốt nhất ở lần gọi đầu tiên, các lần sau tìm
+// route không dùng pair.
+type detourFinder struct {
+	RouteFinder
+	pair  PairKey
+	count *int
+}
+
+func (f detourFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	*f.count++
+	if *f.count > 1 {
+		g = g.view(QueryOptions{ExcludedPairs: []PairKey{f.pair}})
+	}
+	return f.RouteFinder.find(ctx, g, base, quote, amount, sell, maxHops)
+}
+
+func TestGraph_Curve_Incumbent(t *testing.T) {
+	kncUSDT := newDepthTestEdge()
+	kncETH := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "ETH",
+		AskOrders:  []Order{{Price: d("0.0025"), Quantity: d("1000")}},
+		BidOrders:  []Order{{Price: d("0.0024"), Quantity: d("1000")}},
+	}
+	ethUSDT := OrderEdge{
+		BaseToken:  "ETH",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("360"), Quantity: d("10")}},
+		BidOrders:  []Order{{Price: d("350"), Quantity: d("10")}},
+	}
+	g := NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		kncETH, kncETH.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+	amounts := []decimal.Decimal{d("100"), d("200"), d("300"), d("600")}
+
+	// Với BranchAndBound, route của mức trước chỉ là cận: kết quả giống gọi
+	// riêng từng mức
+	exact := WithExactSearchLimit(10)
+	for _, sell := range []bool{true, false} {
+		curve := g.BidCurve
+		single := g.BestBidRoute
+		if !sell {
+			curve, single = g.AskCurve, g.BestAskRoute
+		}
+		got, err := curve("KNC", "USDT", amounts, exact)
+		if err != nil {
+			t.Fatalf("curve(sell = %v) error = %v", sell, err)
+		}
+		for i, amount := range amounts {
+			want, wantErr := single("KNC", "USDT", amount, exact)
+			point := got.Points[i]
+			if (point.Err == nil) != (wantErr == nil) ||
+				!slices.Equal(point.Result.Route, want.Route) ||
+				!point.Result.Price.Equal(want.Price) {
+				t.Errorf("sell = %v, Points[%d] = %v %s, %v, want %v %s, %v", sell, i,
+					point.Result.Route, point.Result.Price, point.Err, want.Route, want.Price, wantErr)
+			}
+		}
+	}
+
+	// Route của mức trước được chọn khi tốt hơn kết quả tìm đường: bán 200 KNC
+	// trực tiếp được 170 USDT, qua ETH chỉ được 168 USDT
+	count := 0
+	got, err := g.BidCurve("KNC", "USDT", amounts[:3],
+		WithFinder(detourFinder{RouteFinder: BellmanFord(), pair: KeyOf(kncUSDT), count: &count}))
+	if err != nil {
+		t.Fatalf("BidCurve() error = %v", err)
+	}
+	direct, viaETH := []string{"KNC", "USDT"}, []string{"KNC", "ETH", "USDT"}
+	wants := []struct {
+		route     []string
+		algorithm string
+		out       string
+	}{
+		{direct, BellmanFord().Name(), "90"},
+		{direct, incumbentAlgorithm, "170"},
+		{viaETH, BellmanFord().Name(), "252"},
+	}
+	for i, want := range wants {
+		point := got.Points[i]
+		if point.Err != nil || point.Result.Algorithm != want.algorithm ||
+			!slices.Equal(point.Result.Route, want.route) ||
+			!point.Result.AmountOut.Equal(d(want.out)) {
+			t.Errorf("Points[%d] = %v via %s = %s, %v, want %v via %s = %s", i,
+				point.Result.Route, point.Result.Algorithm, point.Result.AmountOut, point.Err,
+				want.route, want.algorithm, want.out)
+		}
+	}
+}
+
+func TestGraph_AskCurve_Errors(t *testing.T) {
+	edge := newDepthTestEdge()
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	amounts := []decimal.Decimal{d("10"), d("10")}
+	if _, err := g.AskCurve("KNC", "USDT", amounts); !errors.Is(err, ErrInvalidLadder) {
+		t.Errorf("AskCurve(duplicate amounts) error = %v, want ErrInvalidLadder", err)
+	}
+	amounts = []decimal.Decimal{d("10"), d("20")}
+	if _, err := g.AskCurve("KNC", "ETH", amounts); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("AskCurve(no route) error = %v, want ErrNoRoute", err)
+	}
+
+	// Ask orders chỉ có tổng cộng 350 KNC
+	curve, err := g.AskCurve("KNC", "USDT", []decimal.Decimal{d("100"), d("400")})
+	if err != nil {
+		t.Fatalf("AskCurve() error = %v", err)
+	}
+	if curve.Points[0].Err != nil || !curve.Exhausted || !curve.MaxAmount.Equal(d("350")) ||
+		!errors.Is(curve.Points[1].Err, ErrInsufficientLiquidity) {
+		t.Errorf("AskCurve() = %+v, want exhausted at 350", curve)
+	}
+}
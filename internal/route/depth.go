@@ -0,0 +1,108 @@
+package route
+
+import "github.com/nkngn/kyber-homework/internal/decimal"
+
+// fillStats là chi tiết khớp lệnh khi bán hoặc mua qua một cạnh. Các giá tính
+// bằng quote token của cạnh cho một base token, trước phí.
+//   - average: giá khớp trung bình
+//   - worst: giá của mức giá sâu nhất đã khớp
+//   - top: giá tốt nhất của order book (top of book) ở phía được khớp, xem
+//     topOfBookEdge
+//   - mid: trung bình của best bid và best ask, 0 nếu thiếu một phía
+//   - levels: số mức giá đã khớp
+type fillStats struct {
+	average decimal.Decimal
+	worst   decimal.Decimal
+	top     decimal.Decimal
+	mid     decimal.Decimal
+	levels  int
+}
+
+// depthEdge là cạnh cho biết chi tiết khớp lệnh trên order book.
+type depthEdge interface {
+	// fillStats trả về chi tiết khớp lệnh khi bán (sell = true) hoặc mua
+	// amount base token, false nếu không khớp được hết amount.
+	fillStats(amount decimal.Decimal, sell bool) (fillStats, bool)
+}
+
+// fillStats walk qua bid orders (sell = true) hoặc ask orders giống
+// fillBids/fillAsks, phần phí trừ vào base token không được khớp trên order
+// book.
+func (e OrderEdge) fillStats(amount decimal.Decimal, sell bool) (fillStats, bool) {
+	remaining, mode := e.Fee.sellBookAmount(amount), decimal.RoundDown
+	if !sell {
+		remaining, mode = e.Fee.buyBookAmount(amount), decimal.RoundUp
+	}
+	if !remaining.IsPositive() {
+		return fillStats{}, false
+	}
+
+	top, _ := e.topPrice(sell)
+	stats := fillStats{top: top, mid: e.midPrice()}
+	filled, total := remaining, decimal.Zero
+	for order := range e.orders(sell) {
+		if !remaining.IsPositive() {
+			break
+		}
+		quantity := decimal.Min(order.Quantity, remaining)
+		total = total.Add(order.Price.MulRound(quantity, mode))
+		remaining = remaining.Sub(quantity)
+		stats.worst = order.Price
+		stats.levels++
+	}
+	if remaining.IsPositive() {
+		return fillStats{}, false
+	}
+
+	stats.average = total.QuoRound(filled, mode)
+	return stats, true
+}
+
+// midPrice trả về trung bình của best bid và best ask theo topPrice, 0 nếu
+// thiếu một phía.
+func (e OrderEdge) midPrice() decimal.Decimal {
+	bid, _ := e.topPrice(true)
+	ask, _ := e.topPrice(false)
+	return midPrice(bid, ask)
+}
+
+// fillStats của SimpleEdge luôn khớp ở một mức giá duy nhất là BidPrice
+// (sell = true) hoặc AskPrice.
+func (e SimpleEdge) fillStats(amount decimal.Decimal, sell bool) (fillStats, bool) {
+	price, ok := e.topPrice(sell)
+	if !ok {
+		return fillStats{}, false
+	}
+	return fillStats{
+		average: price,
+		worst:   price,
+		top:     price,
+		mid:     midPrice(e.BidPrice, e.AskPrice),
+		levels:  1,
+	}, true
+}
+
+// midPrice trả về (bid + ask) / 2, 0 nếu bid hoặc ask không dương.
+func midPrice(bid, ask decimal.Decimal) decimal.Decimal {
+	if !bid.IsPositive() || !ask.IsPositive() {
+		return decimal.Zero
+	}
+	return bid.Add(ask).QuoRound(decimal.NewFromInt(2), decimal.RoundHalfEven)
+}
+
+// impactBps trả về độ chênh của price so với reference tính bằng basis
+// points, dương nếu price kém hơn reference: thấp hơn khi bán (sell = true),
+// cao hơn khi mua. Kết quả làm tròn 2 chữ số thập phân, 0 nếu reference không
+// dương.
+func impactBps(reference, price decimal.Decimal, sell bool) decimal.Decimal {
+	if !reference.IsPositive() {
+		return decimal.Zero
+	}
+	diff := price.Sub(reference)
+	if sell {
+		diff = diff.Neg()
+	}
+	return diff.Mul(bpsDenominator).
+		QuoRound(reference, decimal.RoundHalfEven).
+		Round(2, decimal.RoundHalfEven)
+}
@@ -0,0 +1,169 @@
+package route
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// newDepthTestEdge tạo trading pair KNC/USDT giống test/expanded_input.txt.
+func newDepthTestEdge() OrderEdge {
+	return OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("1.1"), Quantity: d("150")}, {Price: d("1.2"), Quantity: d("200")}},
+		BidOrders:  []Order{{Price: d("0.9"), Quantity: d("100")}, {Price: d("0.8"), Quantity: d("300")}},
+	}
+}
+
+func TestOrderEdge_FillStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		sell    bool
+		want    fillStats
+		wantErr bool
+	}{
+		{
+			name:   "Sell within top level",
+			amount: "50",
+			sell:   true,
+			want:   fillStats{average: d("0.9"), worst: d("0.9"), top: d("0.9"), mid: d("1"), levels: 1},
+		},
+		{
+			// (100 * 0.9 + 50 * 0.8) / 150
+			name:   "Sell two levels",
+			amount: "150",
+			sell:   true,
+			want:   fillStats{average: d("0.866666666666666666"), worst: d("0.8"), top: d("0.9"), mid: d("1"), levels: 2},
+		},
+		{
+			// (150 * 1.1 + 100 * 1.2) / 250
+			name:   "Buy two levels",
+			amount: "250",
+			want:   fillStats{average: d("1.14"), worst: d("1.2"), top: d("1.1"), mid: d("1"), levels: 2},
+		},
+		{
+			name:    "Insufficient depth",
+			amount:  "500",
+			sell:    true,
+			wantErr: true,
+		},
+	}
+
+	edge := newDepthTestEdge()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := edge.fillStats(d(tt.amount), tt.sell)
+			if ok == tt.wantErr {
+				t.Fatalf("fillStats() ok = %v, wantErr %v", ok, tt.wantErr)
+			}
+			if !ok {
+				return
+			}
+			if !got.average.Equal(tt.want.average) || !got.worst.Equal(tt.want.worst) ||
+				!got.top.Equal(tt.want.top) || !got.mid.Equal(tt.want.mid) ||
+				got.levels != tt.want.levels {
+				t.Errorf("fillStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderEdge_FillStats_UnsortedBook(t *testing.T) {
+	edge := newDepthTestEdge()
+	slices.Reverse(edge.BidOrders)
+	slices.Reverse(edge.AskOrders)
+
+	// Top of book của fillStats giống topPrice, cận của BranchAndBound
+	for _, sell := range []bool{true, false} {
+		stats, ok := edge.fillStats(d("10"), sell)
+		top, topOK := edge.topPrice(sell)
+		if !ok || !topOK || !stats.top.Equal(top) {
+			t.Errorf("fillStats(sell = %v) top = %v, topPrice() = %v", sell, stats.top, top)
+		}
+		if !stats.mid.Equal(d("1")) {
+			t.Errorf("fillStats(sell = %v) mid = %v, want 1", sell, stats.mid)
+		}
+	}
+}
+
+func TestGraph_BestAskRoute_FillStats(t *testing.T) {
+	edge := newDepthTestEdge()
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	got, err := g.BestAskRoute("KNC", "USDT", d("250"))
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+	if !slices.Equal(got.Route, []string{"USDT", "KNC"}) || len(got.Legs) != 1 {
+		t.Fatalf("got route %v legs %+v, want USDT->KNC", got.Route, got.Legs)
+	}
+
+	// Trượt giá (1.14 - 1.1) / 1.1 so với top of book, (1.14 - 1) / 1 so với mid
+	leg := got.Legs[0]
+	if !leg.AveragePrice.Equal(d("1.14")) || !leg.WorstPrice.Equal(d("1.2")) ||
+		leg.Levels != 2 || !leg.MidPrice.Equal(d("1")) || !leg.ImpactBps.Equal(d("363.64")) {
+		t.Errorf("leg = %+v, want average 1.14, worst 1.2, 2 levels, mid 1, impact 363.64", leg)
+	}
+	if !got.Price.Equal(d("1.14")) || !got.MidPrice.Equal(d("1")) || !got.ImpactBps.Equal(d("1400")) {
+		t.Errorf("got price %v mid %v impact %v, want 1.14, 1, 1400",
+			got.Price, got.MidPrice, got.ImpactBps)
+	}
+}
+
+func TestGraph_BestBidRoute_ReversedLegPrices(t *testing.T) {
+	edge := newDepthTestEdge()
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	// Bán 195 USDT qua cạnh đảo ngược: mua 150 KNC giá 1.1 và 25 KNC giá 1.2,
+	// các giá tính theo KNC/USDT giống Symbol
+	got, err := g.BestBidRoute("USDT", "KNC", d("195"))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	leg := got.Legs[0]
+	if leg.Symbol != "KNC/USDT" || leg.Side != SideBuy || leg.Levels != 2 {
+		t.Fatalf("leg = %+v, want buy KNC/USDT over 2 levels", leg)
+	}
+	if !leg.AveragePrice.Round(6, decimal.RoundHalfEven).Equal(d("1.114286")) ||
+		!leg.WorstPrice.Round(6, decimal.RoundHalfEven).Equal(d("1.2")) || !leg.MidPrice.Equal(d("1")) {
+		t.Errorf("leg prices avg %v worst %v mid %v, want about 1.114286, 1.2, 1",
+			leg.AveragePrice, leg.WorstPrice, leg.MidPrice)
+	}
+	if !got.MidPrice.Equal(d("1")) {
+		t.Errorf("MidPrice = %v, want 1", got.MidPrice)
+	}
+}
+
+func TestGraph_BestBidRoute_FillStats(t *testing.T) {
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("0.9"), AskPrice: d("1.1")}
+	ethUSDT := SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("400"), AskPrice: d("400")}
+	g := NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+
+	got, err := g.BestBidRoute("KNC", "ETH", d("40"))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+
+	// SimpleEdge luôn khớp ở một mức giá nên không trượt giá so với top of book.
+	// Chặng USDT->ETH có giá theo ETH/USDT, route mid vẫn theo KNC->ETH
+	wantMids := []string{"1", "400"}
+	for i, leg := range got.Legs {
+		if leg.Levels != 1 || !leg.ImpactBps.IsZero() || !leg.AveragePrice.Equal(leg.WorstPrice) ||
+			!leg.MidPrice.Equal(d(wantMids[i])) {
+			t.Errorf("leg %d = %+v, want one level, mid %s, no impact", i, leg, wantMids[i])
+		}
+	}
+
+	// 40 KNC -> 36 USDT -> 0.09 ETH, giá 0.00225 so với mid 0.0025
+	if !got.Price.Equal(d("0.00225")) || !got.MidPrice.Equal(d("0.0025")) ||
+		!got.ImpactBps.Equal(d("1000")) {
+		t.Errorf("got price %v mid %v impact %v, want 0.00225, 0.0025, 1000",
+			got.Price, got.MidPrice, got.ImpactBps)
+	}
+}
@@ -1,16 +1,80 @@
 package route
 
+import "github.com/nkngn/kyber-homework/internal/decimal"
+
 type Edge interface {
 	From() string
 	To() string
 
 	// SimulateSell mô phỏng việc bán amount base token qua cạnh này.
-	SimulateSell(amount float64) (float64, bool)
+	SimulateSell(amount decimal.Decimal) (decimal.Decimal, bool)
 
 	// SimulateBuy mô phỏng việc mua amount base token qua cạnh này.
-	SimulateBuy(amount float64) (float64, bool)
+	SimulateBuy(amount decimal.Decimal) (decimal.Decimal, bool)
 
 	// GetReverseEdge trả về một cạnh đảo ngược chiều giao dịch so với
 	// cạnh hiện tại.
 	GetReverseEdge() Edge
 }
+
+// venueEdge là cạnh được gắn với một exchange.
+type venueEdge interface {
+	venue() string
+}
+
+// VenueOf trả về exchange của cạnh e, rỗng nếu cạnh không gắn với exchange
+// nào.
+func VenueOf(e Edge) string {
+	if v, ok := e.(venueEdge); ok {
+		return v.venue()
+	}
+	return ""
+}
+
+// reversibleEdge là cạnh cho biết chiều của nó so với trading pair của
+// exchange.
+type reversibleEdge interface {
+	reversed() bool
+}
+
+// SymbolOf trả về trading pair của cạnh e trên exchange dạng BASE/QUOTE, ví dụ
+// KNC/USDT cho cả cạnh KNC->USDT lẫn cạnh đảo ngược USDT->KNC của nó. Cạnh
+// không cho biết chiều được coi là cùng chiều với trading pair.
+func SymbolOf(e Edge) string {
+	if isReversed(e) {
+		return e.To() + "/" + e.From()
+	}
+	return e.From() + "/" + e.To()
+}
+
+func isReversed(e Edge) bool {
+	r, ok := e.(reversibleEdge)
+	return ok && r.reversed()
+}
+
+// Side là chiều giao dịch trên trading pair của exchange.
+type Side int
+
+const (
+	// SideSell là bán base token của trading pair lấy quote token.
+	SideSell Side = iota
+
+	// SideBuy là mua base token của trading pair bằng quote token.
+	SideBuy
+)
+
+func (s Side) String() string {
+	if s == SideBuy {
+		return "buy"
+	}
+	return "sell"
+}
+
+// sideOf trả về chiều giao dịch trên trading pair của cạnh e khi đưa vào token
+// from và nhận về token còn lại.
+func sideOf(e Edge, from string) Side {
+	if (from == e.From()) != isReversed(e) {
+		return SideSell
+	}
+	return SideBuy
+}
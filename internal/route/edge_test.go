@@ -0,0 +1,129 @@
+package route
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSymbolOf(t *testing.T) {
+	edge := OrderEdge{BaseToken: "KNC", QuoteToken: "USDT", Venue: "binance"}
+	reverse := edge.GetReverseEdge()
+	simple := SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT"}
+
+	tests := []struct {
+		name     string
+		edge     Edge
+		from     string
+		wantSym  string
+		wantSide Side
+	}{
+		{name: "Order edge", edge: edge, from: "KNC", wantSym: "KNC/USDT", wantSide: SideSell},
+		{name: "Reverse order edge", edge: reverse, from: "USDT", wantSym: "KNC/USDT", wantSide: SideBuy},
+		{name: "Reverse of reverse", edge: reverse.GetReverseEdge(), from: "KNC",
+			wantSym: "KNC/USDT", wantSide: SideSell},
+		{name: "Reverse simple edge", edge: simple.GetReverseEdge(), from: "USDT",
+			wantSym: "ETH/USDT", wantSide: SideBuy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SymbolOf(tt.edge); got != tt.wantSym {
+				t.Errorf("SymbolOf() = %s, want %s", got, tt.wantSym)
+			}
+			if got := sideOf(tt.edge, tt.from); got != tt.wantSide {
+				t.Errorf("sideOf() = %s, want %s", got, tt.wantSide)
+			}
+		})
+	}
+}
+
+// newParallelTestGraph tạo đồ thị có trading pair KNC/USDT trên hai exchange,
+// kraken có giá tốt hơn cả khi bán lẫn khi mua.
+func newParallelTestGraph() Graph {
+	binance := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("1.1"), Quantity: d("1000")}},
+		BidOrders:  []Order{{Price: d("0.9"), Quantity: d("1000")}},
+		Venue:      "binance",
+	}
+	kraken := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("1.05"), Quantity: d("1000")}},
+		BidOrders:  []Order{{Price: d("0.92"), Quantity: d("1000")}},
+		Venue:      "kraken",
+	}
+	return NewGraphWithEdges([]Edge{
+		binance, binance.GetReverseEdge(),
+		kraken, kraken.GetReverseEdge(),
+	})
+}
+
+func TestGraph_BestRoute_ParallelEdges(t *testing.T) {
+	g := newParallelTestGraph()
+
+	tests := []struct {
+		name    string
+		sell    bool
+		opts    []QueryOption
+		wantLeg Leg
+	}{
+		{name: "Bid", sell: true,
+			wantLeg: Leg{From: "KNC", To: "USDT", Venue: "kraken", Symbol: "KNC/USDT", Side: SideSell}},
+		{name: "Ask", sell: false,
+			wantLeg: Leg{From: "USDT", To: "KNC", Venue: "kraken", Symbol: "KNC/USDT", Side: SideBuy}},
+		{name: "Bid exact quote", sell: true, opts: []QueryOption{WithExactQuote()},
+			wantLeg: Leg{From: "KNC", To: "USDT", Venue: "kraken", Symbol: "KNC/USDT", Side: SideSell}},
+		{name: "Bid excluded venue", sell: true, opts: []QueryOption{WithAllowedVenues("binance")},
+			wantLeg: Leg{From: "KNC", To: "USDT", Venue: "binance", Symbol: "KNC/USDT", Side: SideSell}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := g.BestAskRoute
+			if tt.sell {
+				best = g.BestBidRoute
+			}
+			result, err := best("KNC", "USDT", d("100"), tt.opts...)
+			if err != nil {
+				t.Fatalf("best route error = %v", err)
+			}
+			if len(result.Legs) != 1 {
+				t.Fatalf("got %d legs, want 1", len(result.Legs))
+			}
+			leg := result.Legs[0]
+			if leg.From != tt.wantLeg.From || leg.To != tt.wantLeg.To ||
+				leg.Venue != tt.wantLeg.Venue || leg.Symbol != tt.wantLeg.Symbol ||
+				leg.Side != tt.wantLeg.Side {
+				t.Errorf("leg = %s->%s %s %s@%s, want %s->%s %s %s@%s",
+					leg.From, leg.To, leg.Side, leg.Symbol, leg.Venue,
+					tt.wantLeg.From, tt.wantLeg.To, tt.wantLeg.Side, tt.wantLeg.Symbol, tt.wantLeg.Venue)
+			}
+			if leg.Edge == nil || VenueOf(leg.Edge) != leg.Venue {
+				t.Errorf("leg edge = %v, want edge on %s", leg.Edge, leg.Venue)
+			}
+		})
+	}
+}
+
+func TestGraph_TopRoutes_ParallelEdges(t *testing.T) {
+	g := newParallelTestGraph()
+
+	// Cùng token nhưng khác exchange là hai route khác nhau
+	best, err := g.BestBidRoute("KNC", "USDT", d("100"), WithTopRoutes(3))
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithTopRoutes(3)) error = %v", err)
+	}
+	results := append([]RouteResult{best}, best.Alternatives...)
+	var venues []string
+	for _, result := range results {
+		venues = append(venues, result.Legs[0].Venue)
+	}
+	if !slices.Equal(venues, []string{"kraken", "binance"}) {
+		t.Errorf("venues = %v, want [kraken binance]", venues)
+	}
+	if !results[1].Price.Equal(d("0.9")) {
+		t.Errorf("second price = %s, want 0.9", results[1].Price)
+	}
+}
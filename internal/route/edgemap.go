@@ -0,0 +1,101 @@
+package route
+
+import (
+	"iter"
+	"maps"
+)
+
+// edgeMap là map từ token tới các cạnh đi ra của token đó, chỉ gồm các token
+// có ít nhất một cạnh đi ra. Giá trị zero là map rỗng.
+//
+// Để mỗi lần cập nhật đồ thị không phải sao chép toàn bộ map, edgeMap gồm hai
+// lớp: base dùng chung giữa các snapshot và không bao giờ bị thay đổi, changes
+// chứa các token đã thay đổi kể từ khi base được tạo (slice rỗng là token đã
+// bị xóa). clone chỉ sao chép changes, và khi changes nhiều hơn căn bậc hai số
+// token thì gộp cả hai lớp thành base mới, nên mỗi lần cập nhật tốn khoảng
+// O(căn bậc hai số token) thay vì O(số token).
+type edgeMap struct {
+	base    map[string][]Edge
+	changes map[string][]Edge
+	size    int
+}
+
+// from trả về các cạnh đi ra của token, nil nếu token không có cạnh nào. Slice
+// trả về dùng chung với edgeMap, không được thay đổi.
+func (m edgeMap) from(token string) []Edge {
+	if edges, ok := m.changes[token]; ok {
+		return edges
+	}
+	return m.base[token]
+}
+
+// has kiểm tra token có cạnh đi ra hay không.
+func (m edgeMap) has(token string) bool {
+	return len(m.from(token)) > 0
+}
+
+// len trả về số token có cạnh đi ra.
+func (m edgeMap) len() int {
+	return m.size
+}
+
+// all trả về các token có cạnh đi ra cùng các cạnh đó, không theo thứ tự nào.
+func (m edgeMap) all() iter.Seq2[string, []Edge] {
+	return func(yield func(string, []Edge) bool) {
+		for token, edges := range m.changes {
+			if len(edges) > 0 && !yield(token, edges) {
+				return
+			}
+		}
+		for token, edges := range m.base {
+			if _, changed := m.changes[token]; !changed && !yield(token, edges) {
+				return
+			}
+		}
+	}
+}
+
+// tokens trả về các token có cạnh đi ra, không theo thứ tự nào.
+func (m edgeMap) tokens() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for token := range m.all() {
+			if !yield(token) {
+				return
+			}
+		}
+	}
+}
+
+// clone trả về bản sao của m có thể thay đổi bằng set mà không ảnh hưởng tới
+// m. base được dùng chung, trừ khi changes đã đủ lớn để gộp lại.
+func (m edgeMap) clone() edgeMap {
+	if len(m.changes)*len(m.changes) <= m.size {
+		return edgeMap{base: m.base, changes: maps.Clone(m.changes), size: m.size}
+	}
+	base := make(map[string][]Edge, m.size)
+	for token, edges := range m.all() {
+		base[token] = edges
+	}
+	return edgeMap{base: base, size: m.size}
+}
+
+// set thay các cạnh đi ra của token bằng edges, xóa token nếu edges rỗng. Chỉ
+// dùng cho edgeMap chưa được chia sẻ, ví dụ bản sao từ clone.
+func (m *edgeMap) set(token string, edges []Edge) {
+	existed := m.has(token)
+	switch {
+	case existed && len(edges) == 0:
+		m.size--
+	case !existed && len(edges) > 0:
+		m.size++
+	}
+
+	if _, inBase := m.base[token]; !inBase && len(edges) == 0 {
+		delete(m.changes, token)
+		return
+	}
+	if m.changes == nil {
+		m.changes = make(map[string][]Edge)
+	}
+	m.changes[token] = edges
+}
@@ -0,0 +1,109 @@
+package route
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// exactEdge là cạnh hỗ trợ mô phỏng ngược theo lượng quote token, dùng cho
+// WithExactQuote.
+type exactEdge interface {
+	Edge
+
+	// SimulateSellExactOut trả về lượng base token cần bán để thu được ít
+	// nhất amount quote token.
+	SimulateSellExactOut(amount decimal.Decimal) (decimal.Decimal, bool)
+
+	// SimulateBuyExactIn trả về lượng base token mua được khi trả tối đa
+	// amount quote token.
+	SimulateBuyExactIn(amount decimal.Decimal) (decimal.Decimal, bool)
+}
+
+// invertedEdge là cạnh gốc exactEdge được đảo chiều để tìm đường từ quote về
+// base theo lượng quote token: From và To đổi chỗ cho nhau, SimulateBuy là
+// SimulateSellExactOut và SimulateSell là SimulateBuyExactIn của cạnh gốc.
+// Nhờ đó các thuật toán tìm đường dùng lại được nguyên vẹn:
+//   - bid exact output: tối thiểu lượng base token cần bán, như tìm giá ask
+//   - ask exact input: tối đa lượng base token mua được, như tìm giá bid
+type invertedEdge struct {
+	exactEdge
+}
+
+func (e invertedEdge) From() string { return e.exactEdge.To() }
+func (e invertedEdge) To() string   { return e.exactEdge.From() }
+
+// SimulateSell trả về lượng From token của cạnh gốc mua được khi trả amount
+// To token của cạnh gốc.
+func (e invertedEdge) SimulateSell(amount decimal.Decimal) (decimal.Decimal, bool) {
+	return e.exactEdge.SimulateBuyExactIn(amount)
+}
+
+// SimulateBuy trả về lượng From token của cạnh gốc cần bán để thu được amount
+// To token của cạnh gốc.
+func (e invertedEdge) SimulateBuy(amount decimal.Decimal) (decimal.Decimal, bool) {
+	return e.exactEdge.SimulateSellExactOut(amount)
+}
+
+// GetReverseEdge đảo chiều cạnh đảo ngược của cạnh gốc, nil nếu cạnh này
+// không hỗ trợ mô phỏng ngược.
+func (e invertedEdge) GetReverseEdge() Edge {
+	if reverse, ok := e.exactEdge.GetReverseEdge().(exactEdge); ok {
+		return invertedEdge{reverse}
+	}
+	return nil
+}
+
+// inverse trả về đồ thị gồm các invertedEdge của các cạnh trong g được phép
+// dùng (xem allows) và hỗ trợ mô phỏng ngược, các cạnh khác bị bỏ qua. Đồ thị
+// được tạo mới ở mỗi query với chi phí O(E), thứ tự các cạnh được giữ ổn định
+// để kết quả tái lập được.
+func (g *graph) inverse() *graph {
+	inverse := &graph{version: g.version}
+	for _, token := range slices.Sorted(g.edges.tokens()) {
+		for edge := range g.outgoing(token) {
+			if exact, ok := edge.(exactEdge); ok {
+				inverse.AddEdge(invertedEdge{exact})
+			}
+		}
+	}
+	return inverse
+}
+
+// venue trả về exchange của cạnh gốc để KeyOf phân biệt được các cạnh song
+// song trên đồ thị inverse.
+func (e invertedEdge) venue() string { return VenueOf(e.exactEdge) }
+
+// uninvertEdges chuyển các cạnh của một đường đi trên đồ thị inverse (từ quote
+// về base) về các cạnh gốc theo chiều giao dịch thực tế (từ base tới quote).
+func uninvertEdges(edges []Edge) []Edge {
+	result := make([]Edge, 0, len(edges))
+	for _, edge := range slices.Backward(edges) {
+		if inverted, ok := edge.(invertedEdge); ok {
+			edge = inverted.exactEdge
+		}
+		result = append(result, edge)
+	}
+	return result
+}
+
+// uninvert chuyển *ArbitrageError phát hiện trên đồ thị inverse về các cạnh
+// gốc, chu trình được đảo lại theo chiều giao dịch thực tế. Các lỗi khác được
+// giữ nguyên.
+func uninvert(err error) error {
+	var arbErr *ArbitrageError
+	if !errors.As(err, &arbErr) {
+		return err
+	}
+	return uninvertCycle(arbErr)
+}
+
+// uninvertCycle giống uninvert cho một chu trình.
+func uninvertCycle(cycle *ArbitrageError) *ArbitrageError {
+	result := *cycle
+	result.Cycle = slices.Clone(cycle.Cycle)
+	slices.Reverse(result.Cycle)
+	result.Edges = uninvertEdges(cycle.Edges)
+	return &result
+}
@@ -0,0 +1,136 @@
+package route
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func Test_SimulateExactQuote(t *testing.T) {
+	unit := func(fee Fee, precisions Precisions) SimpleEdge {
+		return SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1"),
+			Fee: fee, Precisions: precisions}
+	}
+	tests := []struct {
+		name        string
+		edge        exactEdge
+		amount      string
+		sell        bool
+		want        string
+		notFeasible bool
+	}{
+		{name: "Simple sell", edge: unit(Fee{}, nil), amount: "90", sell: true, want: "90"},
+		{name: "Simple buy", edge: unit(Fee{}, nil), amount: "110", want: "110"},
+		{name: "Fee in quote sell", edge: unit(Fee{Bps: d("10")}, nil), amount: "99.9", sell: true, want: "100"},
+		{name: "Fee in quote buy", edge: unit(Fee{Bps: d("10")}, nil), amount: "100.1", want: "100"},
+		{name: "Fee in base sell", edge: unit(Fee{Bps: d("10"), Side: FeeInBase}, nil), amount: "99.9", sell: true, want: "100"},
+		{name: "Fee in base buy", edge: unit(Fee{Bps: d("10"), Side: FeeInBase}, nil), amount: "100", want: "99.9"},
+		{name: "Fixed fee buy", edge: unit(Fee{Fixed: d("1")}, nil), amount: "1", notFeasible: true},
+		{name: "Quote precision sell", edge: unit(Fee{}, Precisions{"USDT": 2}), amount: "0.001", sell: true, want: "0.01"},
+		{name: "Quote precision buy", edge: unit(Fee{}, Precisions{"USDT": 2}), amount: "1.009", want: "1"},
+		// 100 * 0.9 + 50 * 0.8 = 130
+		{name: "Order book sell", edge: newDepthTestEdge(), amount: "130", sell: true, want: "150"},
+		// 150 * 1.1 + 100 * 1.2 = 285
+		{name: "Order book buy", edge: newDepthTestEdge(), amount: "285", want: "250"},
+		{name: "Order book sell insufficient depth", edge: newDepthTestEdge(), amount: "400", sell: true, notFeasible: true},
+		{name: "Order book buy insufficient depth", edge: newDepthTestEdge(), amount: "1000", notFeasible: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulate := tt.edge.SimulateBuyExactIn
+			if tt.sell {
+				simulate = tt.edge.SimulateSellExactOut
+			}
+			got, ok := simulate(d(tt.amount))
+			if ok == tt.notFeasible {
+				t.Fatalf("got %v, feasible %v, want feasible %v", got, ok, !tt.notFeasible)
+			}
+			if !ok {
+				return
+			}
+			if !got.Equal(d(tt.want)) {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+
+			// Mô phỏng xuôi phải thu được ít nhất (bán) hoặc trả tối đa (mua) amount
+			if tt.sell {
+				if acquired, _ := tt.edge.SimulateSell(got); acquired.LessThan(d(tt.amount)) {
+					t.Errorf("SimulateSell(%v) = %v, want at least %s", got, acquired, tt.amount)
+				}
+			} else if required, _ := tt.edge.SimulateBuy(got); required.GreaterThan(d(tt.amount)) {
+				t.Errorf("SimulateBuy(%v) = %v, want at most %s", got, required, tt.amount)
+			}
+		})
+	}
+}
+
+// newExactTestGraph tạo đồ thị KNC/USDT, ETH/USDT giống test/expanded_input.txt.
+func newExactTestGraph() Graph {
+	kncUSDT := newDepthTestEdge()
+	ethUSDT := OrderEdge{
+		BaseToken:  "ETH",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("360"), Quantity: d("1000")}, {Price: d("365"), Quantity: d("500")}},
+		BidOrders:  []Order{{Price: d("355"), Quantity: d("800")}, {Price: d("350"), Quantity: d("600")}},
+	}
+	return NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+}
+
+func TestGraph_BestBidRoute_ExactQuote(t *testing.T) {
+	g := newExactTestGraph()
+
+	got, err := g.BestBidRoute("KNC", "ETH", d("0.3"), WithExactQuote())
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if !slices.Equal(got.Route, []string{"KNC", "USDT", "ETH"}) {
+		t.Errorf("Route = %v, want KNC->USDT->ETH", got.Route)
+	}
+	if got.AmountOut.LessThan(d("0.3")) {
+		t.Errorf("AmountOut = %v, want at least 0.3", got.AmountOut)
+	}
+
+	// 0.3 ETH cần 108 USDT, tức 100 KNC giá 0.9 và 22.5 KNC giá 0.8
+	if got.AmountIn.LessThan(d("122.5")) || got.AmountIn.GreaterThan(d("122.500001")) {
+		t.Errorf("AmountIn = %v, want about 122.5", got.AmountIn)
+	}
+
+	// Bán đúng lượng KNC tìm được phải cho cùng kết quả
+	forward, err := g.BestBidRoute("KNC", "ETH", got.AmountIn)
+	if err != nil {
+		t.Fatalf("BestBidRoute(%v) error = %v", got.AmountIn, err)
+	}
+	if !forward.AmountOut.Equal(got.AmountOut) || !forward.Price.Equal(got.Price) {
+		t.Errorf("forward = %v at %v, want %v at %v",
+			forward.AmountOut, forward.Price, got.AmountOut, got.Price)
+	}
+}
+
+func TestGraph_BestAskRoute_ExactQuote(t *testing.T) {
+	edge := newDepthTestEdge()
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	got, err := g.BestAskRoute("KNC", "USDT", d("285"), WithExactQuote())
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+	if !slices.Equal(got.Route, []string{"USDT", "KNC"}) || !got.AmountIn.Equal(d("285")) ||
+		!got.AmountOut.Equal(d("250")) || !got.Price.Equal(d("1.14")) {
+		t.Errorf("got %v %v -> %v at %v, want USDT->KNC 285 -> 250 at 1.14",
+			got.Route, got.AmountIn, got.AmountOut, got.Price)
+	}
+
+	price, route, err := g.BestAskPrice("KNC", "USDT", d("285"), WithExactQuote())
+	if err != nil || !price.Equal(got.Price) || !slices.Equal(route, got.Route) {
+		t.Errorf("BestAskPrice() = %v %v %v, want %v %v", price, route, err, got.Price, got.Route)
+	}
+
+	// Order book chỉ dùng được tối đa 405 USDT
+	if _, err := g.BestAskRoute("KNC", "USDT", d("1000"), WithExactQuote()); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("BestAskRoute(1000) error = %v, want ErrNoRoute", err)
+	}
+}
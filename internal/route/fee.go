@@ -0,0 +1,311 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ErrInvalidFee cho biết Fee có giá trị nằm ngoài miền hợp lệ, xem Fee.Validate.
+var ErrInvalidFee = errors.New("invalid fee")
+
+// FeeSide xác định token dùng để trả phí của một cạnh.
+type FeeSide int
+
+const (
+	// FeeInQuote trừ phí vào quote token của cạnh (mặc định).
+	FeeInQuote FeeSide = iota
+	// FeeInBase trừ phí vào base token của cạnh.
+	FeeInBase
+)
+
+// bpsDenominator là số basis points tương ứng 100%.
+var bpsDenominator = decimal.NewFromInt(10000)
+
+// Fee mô tả phí taker khi giao dịch qua một cạnh. Giá trị zero của Fee nghĩa
+// là không có phí.
+//   - Bps: phí tính theo basis points trên lượng token của Side (10 bps = 0.1%)
+//   - Side: token dùng để trả phí, base hoặc quote token của cạnh
+//   - Fixed: phí cố định mỗi lần đi qua cạnh (hop), tính bằng token của Side
+type Fee struct {
+	Bps   decimal.Decimal
+	Side  FeeSide
+	Fixed decimal.Decimal
+}
+
+// Validate kiểm tra Fee hợp lệ: Bps trong [0, 10000), Fixed không âm và Side
+// là FeeInQuote hoặc FeeInBase. Lỗi trả về thỏa mãn errors.Is(err,
+// ErrInvalidFee). Cạnh có Fee không hợp lệ không giao dịch được: các hàm mô
+// phỏng của cạnh trả về false thay vì tính giá sai.
+func (f Fee) Validate() error {
+	switch {
+	case f.Bps.IsNegative() || f.Bps.GreaterThanOrEqual(bpsDenominator):
+		return fmt.Errorf("%w: bps must be in [0, 10000), got %s", ErrInvalidFee, f.Bps)
+	case f.Fixed.IsNegative():
+		return fmt.Errorf("%w: fixed must not be negative, got %s", ErrInvalidFee, f.Fixed)
+	case f.Side != FeeInQuote && f.Side != FeeInBase:
+		return fmt.Errorf("%w: unknown side %d", ErrInvalidFee, f.Side)
+	}
+	return nil
+}
+
+// IsZero kiểm tra cạnh có miễn phí hay không.
+func (f Fee) IsZero() bool {
+	return f.Bps.IsZero() && f.Fixed.IsZero()
+}
+
+// reverse trả về Fee cho cạnh đảo ngược. Base và quote đổi chỗ cho nhau nên
+// Side cũng đảo lại, phí vẫn được trả bằng cùng một token.
+func (f Fee) reverse() Fee {
+	if f.Side == FeeInQuote {
+		f.Side = FeeInBase
+	} else {
+		f.Side = FeeInQuote
+	}
+	return f
+}
+
+// token trả về tên token dùng để trả phí trên cạnh base/quote.
+func (f Fee) token(base, quote string) string {
+	if f.Side == FeeInBase {
+		return base
+	}
+	return quote
+}
+
+// deduct trừ phí ra khỏi amount (lượng token của Side), trả về lượng còn lại
+// và phí đã trả. Phí được làm tròn lên. ok = false nếu phí lớn hơn hoặc bằng
+// amount.
+func (f Fee) deduct(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool) {
+	if f.IsZero() || amount.IsZero() {
+		return amount, decimal.Zero, true
+	}
+	fee := f.charge(amount)
+	net := amount.Sub(fee)
+	if !net.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return net, fee, true
+}
+
+// gross tính lượng token của Side cần có trước phí để sau khi trừ phí còn
+// lại đúng amount, trả về lượng trước phí và phí đã trả. Lượng trước phí được
+// làm tròn lên. ok = false nếu phí theo bps từ 100% trở lên.
+func (f Fee) gross(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool) {
+	if f.IsZero() || amount.IsZero() {
+		return amount, decimal.Zero, true
+	}
+	remaining := bpsDenominator.Sub(f.Bps)
+	if !remaining.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	total := amount.Add(f.Fixed).
+		MulRound(bpsDenominator, decimal.RoundUp).
+		QuoRound(remaining, decimal.RoundUp)
+	return total, total.Sub(amount), true
+}
+
+// charge tính phí phải trả trên amount token của Side, làm tròn lên.
+func (f Fee) charge(amount decimal.Decimal) decimal.Decimal {
+	return amount.MulRound(f.Bps, decimal.RoundUp).
+		QuoRound(bpsDenominator, decimal.RoundUp).
+		Add(f.Fixed)
+}
+
+// sell mô phỏng việc bán amount base token có tính phí, fill là hàm khớp
+// lệnh trên order book trả về lượng quote token thu được trước phí.
+//   - FeeInBase: phí được trừ vào amount trước khi khớp lệnh
+//   - FeeInQuote: phí được trừ vào lượng quote token thu được
+func (f Fee) sell(amount decimal.Decimal,
+	fill func(decimal.Decimal) (decimal.Decimal, bool)) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	if f.Validate() != nil {
+		return decimal.Zero, decimal.Zero, false
+	}
+	if f.Side == FeeInBase {
+		net, fee, ok := f.deduct(amount)
+		if !ok {
+			return decimal.Zero, decimal.Zero, false
+		}
+		acquired, ok := fill(net)
+		return acquired, fee, ok
+	}
+
+	acquired, ok := fill(amount)
+	if !ok {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return f.deduct(acquired)
+}
+
+// buy mô phỏng việc mua amount base token có tính phí, fill là hàm khớp lệnh
+// trên order book trả về lượng quote token phải trả trước phí.
+//   - FeeInBase: phí được trừ vào base token mua được, nên phải mua nhiều hơn
+//     amount để sau phí còn lại đúng amount
+//   - FeeInQuote: phí được cộng thêm vào lượng quote token phải trả
+func (f Fee) buy(amount decimal.Decimal,
+	fill func(decimal.Decimal) (decimal.Decimal, bool)) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	if f.Validate() != nil {
+		return decimal.Zero, decimal.Zero, false
+	}
+	if f.Side == FeeInBase {
+		total, fee, ok := f.gross(amount)
+		if !ok {
+			return decimal.Zero, decimal.Zero, false
+		}
+		required, ok := fill(total)
+		return required, fee, ok
+	}
+
+	required, ok := fill(amount)
+	if !ok || f.IsZero() || required.IsZero() {
+		return required, decimal.Zero, ok
+	}
+	fee := f.charge(required)
+	return required.Add(fee), fee, true
+}
+
+// undeduct là phép ngược của deduct: trả về lượng token nhỏ nhất của Side mà
+// sau khi trừ phí còn lại ít nhất amount. ok = false nếu phí theo bps từ 100%
+// trở lên.
+//
+// Tính theo đơn vị nhỏ nhất u của decimal, với total, amount, Fixed là số
+// nguyên lần u: charge(total) = ceil(total * Bps / 10000) + Fixed nên deduct
+// còn lại floor(total * (10000 - Bps) / 10000) - Fixed, không nhỏ hơn amount
+// khi và chỉ khi total >= (amount + Fixed) * 10000 / (10000 - Bps). Giá trị
+// làm tròn lên của vế phải chính là gross, chỉ cần kiểm tra lại một lần.
+func (f Fee) undeduct(amount decimal.Decimal) (decimal.Decimal, bool) {
+	total, _, ok := f.gross(amount)
+	if !ok {
+		return decimal.Zero, false
+	}
+	if net, _, ok := f.deduct(total); !ok || net.LessThan(amount) {
+		total = total.Add(decimal.Ulp)
+	}
+	return total, true
+}
+
+// budget là phép ngược của phần phí cộng thêm khi mua với FeeInQuote: trả về
+// lượng quote token lớn nhất được khớp trên order book để tổng cộng cả phí
+// không vượt quá amount. ok = false nếu amount không đủ trả phí.
+//
+// Giống undeduct, fill + charge(fill) = ceil(fill * (10000 + Bps) / 10000) +
+// Fixed theo đơn vị nhỏ nhất nên lượng lớn nhất là giá trị làm tròn xuống của
+// (amount - Fixed) * 10000 / (10000 + Bps), chỉ cần kiểm tra lại một lần.
+func (f Fee) budget(amount decimal.Decimal) (decimal.Decimal, bool) {
+	if f.IsZero() {
+		return amount, true
+	}
+	fill := amount.Sub(f.Fixed).
+		MulRound(bpsDenominator, decimal.RoundDown).
+		QuoRound(bpsDenominator.Add(f.Bps), decimal.RoundDown)
+	if fill.IsPositive() && fill.Add(f.charge(fill)).GreaterThan(amount) {
+		fill = fill.Sub(decimal.Ulp)
+	}
+	if !fill.IsPositive() {
+		return decimal.Zero, false
+	}
+	return fill, true
+}
+
+// sellExactOut là phép ngược của sell: trả về lượng base token nhỏ nhất cần
+// bán để thu được ít nhất amount quote token sau phí. unfill là phép ngược của
+// hàm khớp lệnh, trả về lượng base token nhỏ nhất cần khớp để thu được một
+// lượng quote token trước phí.
+func (f Fee) sellExactOut(amount decimal.Decimal,
+	unfill func(decimal.Decimal) (decimal.Decimal, bool)) (decimal.Decimal, bool) {
+	if f.Validate() != nil {
+		return decimal.Zero, false
+	}
+	if f.Side == FeeInBase {
+		net, ok := unfill(amount)
+		if !ok {
+			return decimal.Zero, false
+		}
+		return f.undeduct(net)
+	}
+
+	acquired, ok := f.undeduct(amount)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return unfill(acquired)
+}
+
+// buyExactIn là phép ngược của buy: trả về lượng base token lớn nhất mua được
+// sau phí khi trả tối đa amount quote token. unfill là phép ngược của hàm khớp
+// lệnh, trả về lượng base token lớn nhất khớp được với một lượng quote token
+// trước phí.
+func (f Fee) buyExactIn(amount decimal.Decimal,
+	unfill func(decimal.Decimal) (decimal.Decimal, bool)) (decimal.Decimal, bool) {
+	if f.Validate() != nil {
+		return decimal.Zero, false
+	}
+	if f.Side == FeeInBase {
+		total, ok := unfill(amount)
+		if !ok {
+			return decimal.Zero, false
+		}
+		net, _, ok := f.deduct(total)
+		return net, ok
+	}
+
+	budget, ok := f.budget(amount)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return unfill(budget)
+}
+
+// sellBookAmount trả về lượng base token thực sự được khớp trên order book
+// khi bán amount base token.
+func (f Fee) sellBookAmount(amount decimal.Decimal) decimal.Decimal {
+	if f.Side != FeeInBase {
+		return amount
+	}
+	net, _, _ := f.deduct(amount)
+	return net
+}
+
+// buyBookAmount trả về lượng base token thực sự được khớp trên order book
+// khi mua amount base token.
+func (f Fee) buyBookAmount(amount decimal.Decimal) decimal.Decimal {
+	if f.Side != FeeInBase {
+		return amount
+	}
+	total, _, _ := f.gross(amount)
+	return total
+}
+
+// feeEdge là cạnh có tính phí, cho biết thêm lượng phí đã trả khi mô phỏng.
+type feeEdge interface {
+	// simulateSellFee giống SimulateSell, trả về thêm phí đã trả.
+	simulateSellFee(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool)
+	// simulateBuyFee giống SimulateBuy, trả về thêm phí đã trả.
+	simulateBuyFee(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool)
+	// feeToken trả về token dùng để trả phí.
+	feeToken() string
+}
+
+// simulateWithFee mô phỏng bán (sell = true) hoặc mua amount base token qua
+// một cạnh bất kỳ, trả về thêm phí và token trả phí nếu cạnh có tính phí.
+func simulateWithFee(edge Edge, amount decimal.Decimal, sell bool) (
+	value, fee decimal.Decimal, feeToken string, ok bool) {
+	if fe, isFeeEdge := edge.(feeEdge); isFeeEdge {
+		if sell {
+			value, fee, ok = fe.simulateSellFee(amount)
+		} else {
+			value, fee, ok = fe.simulateBuyFee(amount)
+		}
+		return value, fee, fe.feeToken(), ok
+	}
+
+	if sell {
+		value, ok = edge.SimulateSell(amount)
+	} else {
+		value, ok = edge.SimulateBuy(amount)
+	}
+	return value, decimal.Zero, "", ok
+}
@@ -0,0 +1,257 @@
+package route
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func Test_SimulateWithFee(t *testing.T) {
+	orders := []Order{{Price: d("2"), Quantity: d("100")}}
+	tests := []struct {
+		name    string
+		fee     Fee
+		sell    bool
+		amount  string
+		want    string
+		wantFee string
+		wantOk  bool
+	}{
+		{
+			name:    "Sell, fee in quote",
+			fee:     Fee{Bps: d("10"), Side: FeeInQuote},
+			sell:    true,
+			amount:  "10",
+			want:    "19.98", // 20 - 0.1% * 20
+			wantFee: "0.02",
+			wantOk:  true,
+		},
+		{
+			name:    "Sell, fee in base",
+			fee:     Fee{Bps: d("10"), Side: FeeInBase},
+			sell:    true,
+			amount:  "10",
+			want:    "19.98", // (10 - 0.01) * 2
+			wantFee: "0.01",
+			wantOk:  true,
+		},
+		{
+			name:    "Sell, fixed fee in quote",
+			fee:     Fee{Fixed: d("1")},
+			sell:    true,
+			amount:  "10",
+			want:    "19",
+			wantFee: "1",
+			wantOk:  true,
+		},
+		{
+			name:    "Sell, fixed fee larger than amount",
+			fee:     Fee{Fixed: d("1"), Side: FeeInBase},
+			sell:    true,
+			amount:  "0.5",
+			want:    "0",
+			wantFee: "0",
+			wantOk:  false,
+		},
+		{
+			name:    "Buy, fee in quote",
+			fee:     Fee{Bps: d("10"), Side: FeeInQuote},
+			sell:    false,
+			amount:  "10",
+			want:    "20.02",
+			wantFee: "0.02",
+			wantOk:  true,
+		},
+		{
+			name:    "Buy, fee in base",
+			fee:     Fee{Bps: d("10"), Side: FeeInBase},
+			sell:    false,
+			amount:  "9.99",
+			want:    "20", // phải mua 10 base để sau phí còn 9.99
+			wantFee: "0.01",
+			wantOk:  true,
+		},
+		{
+			name:    "Buy, fee in base exceeds depth",
+			fee:     Fee{Bps: d("10"), Side: FeeInBase},
+			sell:    false,
+			amount:  "100",
+			want:    "0",
+			wantFee: "0",
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edge := OrderEdge{
+				BaseToken:  "KNC",
+				QuoteToken: "USDT",
+				BidOrders:  orders,
+				AskOrders:  orders,
+				Fee:        tt.fee,
+			}
+			got, fee, _, ok := simulateWithFee(edge, d(tt.amount), tt.sell)
+			if !got.Equal(d(tt.want)) || !fee.Equal(d(tt.wantFee)) || ok != tt.wantOk {
+				t.Errorf("simulateWithFee(%v) = (%v, %v, %v), want (%v, %v, %v)",
+					tt.amount, got, fee, ok, tt.want, tt.wantFee, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_GetReverseEdge_KeepsFeeToken(t *testing.T) {
+	edge := SimpleEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		BidPrice:   d("2"),
+		AskPrice:   d("2"),
+		Fee:        Fee{Bps: d("10"), Side: FeeInQuote},
+	}
+	reverse := edge.GetReverseEdge().(feeEdge)
+	if got := reverse.feeToken(); got != "USDT" {
+		t.Errorf("reverse feeToken() = %s, want USDT", got)
+	}
+
+	// Bán 20 USDT qua cạnh đảo ngược, phí 0.02 USDT trừ trước khi khớp
+	got, fee, ok := reverse.simulateSellFee(d("20"))
+	if !ok || !got.Equal(d("9.99")) || !fee.Equal(d("0.02")) {
+		t.Errorf("reverse simulateSellFee(20) = (%v, %v, %v), want (9.99, 0.02, true)", got, fee, ok)
+	}
+}
+
+func TestGraph_BestBidRoute_Fees(t *testing.T) {
+	fee := Fee{Bps: d("10")}
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1"), Fee: fee}
+	ethUSDT := SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("400"), AskPrice: d("400"), Fee: fee}
+	g := NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+
+	got, err := g.BestBidRoute("KNC", "ETH", d("1000"))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if !slices.Equal(got.Route, []string{"KNC", "USDT", "ETH"}) {
+		t.Errorf("Route = %v, want KNC->USDT->ETH", got.Route)
+	}
+
+	// KNC->USDT: 1000 USDT, phí 1 USDT
+	// USDT->ETH: phí 0.999 USDT trừ trước, (999 - 0.999) / 400 = 2.4950025 ETH
+	want := []Leg{
+		{From: "KNC", To: "USDT", AmountIn: d("1000"), AmountOut: d("999"), Fee: d("1"), FeeToken: "USDT"},
+		{From: "USDT", To: "ETH", AmountIn: d("999"), AmountOut: d("2.4950025"), Fee: d("0.999"), FeeToken: "USDT"},
+	}
+	assertLegs(t, got.Legs, want)
+	if !got.AmountOut.Equal(d("2.4950025")) {
+		t.Errorf("AmountOut = %v, want 2.4950025", got.AmountOut)
+	}
+}
+
+func TestGraph_BestAskRoute_Legs(t *testing.T) {
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1"), Fee: Fee{Bps: d("10")}}
+	g := NewGraphWithEdges([]Edge{kncUSDT, kncUSDT.GetReverseEdge()})
+
+	got, err := g.BestAskRoute("KNC", "USDT", d("100"))
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+
+	want := []Leg{
+		{From: "USDT", To: "KNC", AmountIn: d("100.1"), AmountOut: d("100"), Fee: d("0.1"), FeeToken: "USDT"},
+	}
+	assertLegs(t, got.Legs, want)
+	if !got.Price.Equal(d("1.001")) || !slices.Equal(got.Route, []string{"USDT", "KNC"}) {
+		t.Errorf("got price %v route %v, want 1.001 USDT->KNC", got.Price, got.Route)
+	}
+}
+
+func assertLegs(t *testing.T, got, want []Leg) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d legs %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.From != w.From || g.To != w.To || !g.AmountIn.Equal(w.AmountIn) ||
+			!g.AmountOut.Equal(w.AmountOut) || !g.Fee.Equal(w.Fee) ||
+			g.FeeToken != w.FeeToken {
+			t.Errorf("leg %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestFee_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fee     Fee
+		wantErr bool
+	}{
+		{name: "Zero", fee: Fee{}},
+		{name: "Max bps", fee: Fee{Bps: d("9999.999999999999999999"), Side: FeeInBase, Fixed: d("1")}},
+		{name: "Negative bps", fee: Fee{Bps: d("-1")}, wantErr: true},
+		{name: "Full bps", fee: Fee{Bps: d("10000")}, wantErr: true},
+		{name: "Negative fixed", fee: Fee{Fixed: d("-0.1")}, wantErr: true},
+		{name: "Unknown side", fee: Fee{Side: FeeSide(2)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fee.Validate()
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidFee)) {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Cạnh có phí không hợp lệ không giao dịch được
+	edge := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("2"), AskPrice: d("2"),
+		Fee: Fee{Bps: d("-20000")}}
+	if _, ok := edge.SimulateSell(d("10")); ok {
+		t.Errorf("SimulateSell() with invalid fee ok = true, want false")
+	}
+	if _, ok := edge.SimulateBuy(d("10")); ok {
+		t.Errorf("SimulateBuy() with invalid fee ok = true, want false")
+	}
+}
+
+// TestFee_Inverse kiểm tra undeduct trả về lượng nhỏ nhất và budget trả về
+// lượng lớn nhất thỏa mãn điều kiện, kể cả khi Bps gần 10000.
+func TestFee_Inverse(t *testing.T) {
+	fees := []Fee{
+		{Bps: d("10")},
+		{Bps: d("7.123456789012345678"), Fixed: d("0.000000000000000003")},
+		{Bps: d("9999")},
+		{Bps: d("9999.999999999999999999"), Fixed: d("0.5")},
+	}
+	amounts := []string{"0.000000000000000001", "0.000000000000000007", "1", "3.141592653589793238", "123456.789"}
+	for _, fee := range fees {
+		for _, value := range amounts {
+			amount := d(value)
+
+			total, ok := fee.undeduct(amount)
+			if !ok {
+				t.Fatalf("%+v undeduct(%v) failed", fee, amount)
+			}
+			if net, _, ok := fee.deduct(total); !ok || net.LessThan(amount) {
+				t.Errorf("%+v deduct(undeduct(%v) = %v) = %v, want >= amount", fee, amount, total, net)
+			}
+			if net, _, ok := fee.deduct(total.Sub(decimal.Ulp)); ok && net.GreaterThanOrEqual(amount) {
+				t.Errorf("%+v undeduct(%v) = %v, not the smallest", fee, amount, total)
+			}
+
+			fill, ok := fee.budget(amount)
+			if !ok {
+				continue
+			}
+			if fill.Add(fee.charge(fill)).GreaterThan(amount) {
+				t.Errorf("%+v budget(%v) = %v exceeds amount", fee, amount, fill)
+			}
+			if next := fill.Add(decimal.Ulp); next.Add(fee.charge(next)).LessThanOrEqual(amount) {
+				t.Errorf("%+v budget(%v) = %v, not the largest", fee, amount, fill)
+			}
+		}
+	}
+}
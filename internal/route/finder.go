@@ -0,0 +1,357 @@
+package route
+
+import (
+	"container/heap"
+	"context"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// defaultDFSMaxHops là số cạnh tối đa của DFS khi maxHops không dương.
+const defaultDFSMaxHops = 4
+
+// RouteFinder là một thuật toán tìm đường trên snapshot của đồ thị, được chọn
+// qua WithFinder hoặc chạy song song qua WithRace. Các thuật toán được cài đặt
+// trong package route:
+//   - BellmanFord: thuật toán mặc định, phát hiện arbitrage loop
+//   - UCS: Uniform Cost Search (Dijkstra), nhanh nhưng mỗi đỉnh chỉ được duyệt
+//     một lần nên không đảm bảo tối ưu khi lượng token tăng qua một cạnh
+//   - SPFA: Bellman-Ford dùng hàng đợi, chỉ nới các cạnh của đỉnh vừa thay đổi,
+//     phát hiện arbitrage loop
+//   - DFS: duyệt mọi đường đi đơn có tối đa maxHops cạnh
+//   - BranchAndBound: giống DFS nhưng loại bỏ các nhánh không thể tốt hơn
+//     route tốt nhất theo giá top of book, cho route tối ưu chính xác
+//
+// Với WithMaxHops, BellmanFord, DFS và BranchAndBound cho kết quả tối ưu trong các route có
+// tối đa MaxHops cạnh, UCS và SPFA chỉ bỏ qua các route dài hơn.
+type RouteFinder interface {
+	// Name trả về tên thuật toán, dùng trong RouteResult.Algorithm.
+	Name() string
+
+	// find tìm đường từ base đến quote có tối đa maxHops cạnh (0 là không
+	// giới hạn), kết quả trả về giống propagateBellmanFord (sell = true) hoặc
+	// bellmanFord (sell = false). Khi ctx kết thúc, thuật toán dừng lại và trả
+	// về ctx.Err() cùng với kết quả tốt nhất tìm được tới lúc đó.
+	find(ctx context.Context, g *graph, base, quote string,
+		amount decimal.Decimal, sell bool, maxHops int) (
+		map[string]decimal.Decimal, map[string]Edge, error)
+}
+
+// BellmanFord trả về RouteFinder dùng propagateBellmanFord và bellmanFord.
+func BellmanFord() RouteFinder { return bellmanFordFinder{} }
+
+// UCS trả về RouteFinder dùng Uniform Cost Search, xem graph.ucs.
+func UCS() RouteFinder { return ucsFinder{} }
+
+// SPFA trả về RouteFinder dùng Shortest Path Faster Algorithm, xem graph.spfa.
+func SPFA() RouteFinder { return spfaFinder{} }
+
+// DFS trả về RouteFinder duyệt mọi đường đi đơn có tối đa maxHops cạnh, mặc
+// định defaultDFSMaxHops nếu maxHops không dương. Số đường đi tăng theo hàm
+// mũ của maxHops nên DFS phù hợp với maxHops nhỏ hoặc khi chạy trong WithRace
+// có deadline.
+func DFS(maxHops int) RouteFinder {
+	if maxHops <= 0 {
+		maxHops = defaultDFSMaxHops
+	}
+	return dfsFinder{maxHops: maxHops}
+}
+
+type bellmanFordFinder struct{}
+
+func (bellmanFordFinder) Name() string { return "bellman-ford" }
+
+func (bellmanFordFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	// Route có tối đa n-1 cạnh nên giới hạn từ n-1 trở lên không có tác dụng
+	if maxHops > 0 && maxHops < g.vertexCount()-1 {
+		return g.boundedBellmanFord(ctx, base, quote, amount, sell, maxHops)
+	}
+	if sell {
+		return g.propagateBellmanFord(ctx, base, quote, amount)
+	}
+	return g.bellmanFord(ctx, base, quote, amount)
+}
+
+type ucsFinder struct{}
+
+func (ucsFinder) Name() string { return "ucs" }
+
+func (ucsFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	return g.ucs(ctx, base, quote, amount, sell, maxHops)
+}
+
+type spfaFinder struct{}
+
+func (spfaFinder) Name() string { return "spfa" }
+
+func (spfaFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	return g.spfa(ctx, base, quote, amount, sell, maxHops)
+}
+
+type dfsFinder struct {
+	maxHops int
+}
+
+func (dfsFinder) Name() string { return "dfs" }
+
+func (f dfsFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if maxHops <= 0 || maxHops > f.maxHops {
+		maxHops = f.maxHops
+	}
+	return g.dfs(ctx, base, quote, amount, sell, maxHops)
+}
+
+// simulate bán (sell = true) hoặc mua amount qua cạnh e.
+func simulate(e Edge, amount decimal.Decimal, sell bool) (decimal.Decimal, bool) {
+	if sell {
+		return e.SimulateSell(amount)
+	}
+	return e.SimulateBuy(amount)
+}
+
+// improves kiểm tra value có tốt hơn values[token] hay không: lớn hơn khi
+// sell, nhỏ hơn khi mua. Token không có trong map được coi là 0 khi sell và
+// +Inf khi mua.
+func improves(value decimal.Decimal, values map[string]decimal.Decimal,
+	token string, sell bool) bool {
+	if !sell {
+		return isLess(value, values, token)
+	}
+	current, ok := values[token]
+	if !ok {
+		return value.IsPositive()
+	}
+	return value.GreaterThan(current)
+}
+
+// ucs (Uniform Cost Search) là một biến thể của thuật toán Dijkstra dùng để
+// tìm số lượng token tốt nhất tại quote: tối đa khi bán (sell = true), tối
+// thiểu khi mua amount base token. ucs dùng heap để chọn ra đỉnh có lượng
+// token tốt nhất ở mỗi lần lặp. Độ phức tạp O(E log(V)).
+//
+// Ý tưởng:
+//   - Gán values[base] = amount, các đỉnh còn lại chưa có trong map (0 khi
+//     bán, +Inf khi mua).
+//   - Mỗi bước, lấy ra đỉnh có lượng token tốt nhất chưa visited, giả lập việc
+//     bán hoặc mua qua các cạnh. Nếu khả thi và tốt hơn giá trị hiện tại ở
+//     đỉnh kề, thì cập nhật.
+//   - Mỗi đỉnh chỉ được visited một lần, đảm bảo không đi vào chu trình lợi
+//     nhuận vô hạn.
+//
+// maxHops lớn hơn 0 thì không nới các cạnh từ đỉnh đã cách base maxHops cạnh.
+//
+// Kết quả trả về giống propagateBellmanFord/bellmanFord, err là ErrNoRoute nếu
+// không tìm được route khả thi.
+//
+// Lưu ý: Dijkstra chỉ tối ưu khi lượng token không tốt lên qua mỗi cạnh, điều
+// không đúng với tỷ giá nên kết quả có thể kém hơn Bellman-Ford.
+func (g *graph) ucs(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	values := map[string]decimal.Decimal{base: amount}
+
+	// prevs là một map có key là đỉnh, value là cạnh đi vào đỉnh đó, From()
+	// của cạnh là đỉnh liền trước. Dùng để xây dựng route sau này
+	prevs := map[string]Edge{}
+
+	// Khi bán lấy ra đỉnh có nhiều token nhất, khi mua lấy ra đỉnh cần ít
+	// token nhất
+	var queue heap.Interface = &TokenMinHeap{}
+	if sell {
+		queue = &TokenMaxHeap{}
+	}
+	heap.Push(queue, TokenInfo{Token: base, MinRequired: amount})
+
+	visited := make(map[string]bool, g.edges.len())
+	hops := map[string]int{base: 0}
+
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return values, prevs, err
+		}
+
+		tokenInfo := heap.Pop(queue).(TokenInfo)
+		token := tokenInfo.Token
+		if visited[token] {
+			continue
+		}
+		visited[token] = true
+		if maxHops > 0 && hops[token] >= maxHops {
+			continue
+		}
+
+		for edge := range g.outgoing(token) {
+			value, feasible := simulate(edge, tokenInfo.MinRequired, sell)
+			if feasible && !visited[edge.To()] &&
+				improves(value, values, edge.To(), sell) {
+				values[edge.To()] = value
+				prevs[edge.To()] = edge
+				hops[edge.To()] = hops[token] + 1
+				heap.Push(queue, TokenInfo{Token: edge.To(), MinRequired: value})
+			}
+		}
+	}
+
+	if !visited[quote] {
+		return nil, nil, ErrNoRoute // không có route khả thi
+	}
+
+	return values, prevs, nil
+}
+
+// spfa (Shortest Path Faster Algorithm) là biến thể của Bellman-Ford dùng hàng
+// đợi: chỉ các đỉnh vừa được cập nhật mới được nới (relax) các cạnh đi ra ở
+// lượt sau, nên thường nhanh hơn nhiều so với n-1 lượt duyệt toàn bộ cạnh.
+//
+// Một đỉnh được đưa vào hàng đợi từ n lần trở lên (với n là số đỉnh) nghĩa là
+// lượng token tại đỉnh đó tốt lên mãi, tức tồn tại arbitrage loop đi qua hoặc
+// dẫn tới đỉnh đó.
+//
+// maxHops lớn hơn 0 thì không nới các cạnh từ đỉnh mà đường đi hiện tại tới
+// nó đã có maxHops cạnh.
+//
+// Kết quả trả về giống propagateBellmanFord (sell = true) hoặc bellmanFord
+// (sell = false).
+func (g *graph) spfa(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	values := map[string]decimal.Decimal{base: amount}
+	prevs := make(map[string]Edge, g.edges.len())
+
+	queue := []string{base}
+	inQueue := map[string]bool{base: true}
+	n := g.vertexCount()
+	enqueued := map[string]int{base: 1}
+	hops := map[string]int{base: 0}
+
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return values, prevs, err
+		}
+
+		token := queue[0]
+		queue = queue[1:]
+		inQueue[token] = false
+		if maxHops > 0 && hops[token] >= maxHops {
+			continue
+		}
+
+		for edge := range g.outgoing(token) {
+			value, feasible := simulate(edge, values[token], sell)
+			if !feasible || !improves(value, values, edge.To(), sell) {
+				continue
+			}
+
+			values[edge.To()] = value
+			prevs[edge.To()] = edge
+			hops[edge.To()] = hops[token] + 1
+			if inQueue[edge.To()] {
+				continue
+			}
+
+			enqueued[edge.To()]++
+			if enqueued[edge.To()] >= n {
+				cycles := cycleSet{}
+				cycles.add(g.arbitrageCycle(prevs, values, edge.To(), sell))
+				return nil, nil, cycles.err()
+			}
+			queue = append(queue, edge.To())
+			inQueue[edge.To()] = true
+		}
+	}
+
+	if _, ok := values[quote]; !ok {
+		return nil, nil, ErrNoRoute
+	}
+
+	return values, prevs, nil
+}
+
+// dfs duyệt theo chiều sâu mọi đường đi đơn từ base có tối đa maxHops cạnh và
+// giữ lại đường đi tốt nhất tới quote. Đường đi đơn không lặp token nên dfs
+// không bị ảnh hưởng bởi arbitrage loop.
+//
+// Kết quả trả về giống propagateBellmanFord/bellmanFord, values và prevs chỉ
+// chứa các token trên đường đi tốt nhất tới quote.
+func (g *graph) dfs(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	var best *pathLabel
+	var visit func(label *pathLabel, token string, hops int) bool
+	visit = func(label *pathLabel, token string, hops int) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if token == quote {
+			if best == nil || (sell && label.value.GreaterThan(best.value)) ||
+				(!sell && label.value.LessThan(best.value)) {
+				best = label
+			}
+			return true
+		}
+		if hops == maxHops {
+			return true
+		}
+
+		for edge := range g.outgoing(token) {
+			if edge.To() == base || label.visits(edge.To()) {
+				continue
+			}
+			value, feasible := simulate(edge, label.value, sell)
+			if !feasible || (sell && value.IsZero()) {
+				continue
+			}
+			next := &pathLabel{edge: edge, prev: label, value: value}
+			if !visit(next, edge.To(), hops+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	finished := visit(&pathLabel{value: amount}, base, 0)
+	if best == nil {
+		if !finished {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, ErrNoRoute
+	}
+
+	values, prevs := best.result(base)
+	if !finished {
+		return values, prevs, ctx.Err()
+	}
+	return values, prevs, nil
+}
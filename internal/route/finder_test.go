@@ -0,0 +1,175 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// newFinderTestGraph tạo đồ thị A->B->C cho 5 C mỗi A và cạnh trực tiếp A->C
+// chỉ cho 2 C. UCS lấy C ra khỏi heap trước B nên bỏ lỡ route qua B. Cạnh
+// C->D để C có cạnh đi ra.
+func newFinderTestGraph() Graph {
+	ab := SimpleEdge{BaseToken: "A", QuoteToken: "B", BidPrice: d("0.5"), AskPrice: d("0.5")}
+	bc := SimpleEdge{BaseToken: "B", QuoteToken: "C", BidPrice: d("10"), AskPrice: d("10")}
+	ac := SimpleEdge{BaseToken: "A", QuoteToken: "C", BidPrice: d("2"), AskPrice: d("2")}
+	cd := SimpleEdge{BaseToken: "C", QuoteToken: "D", BidPrice: d("1"), AskPrice: d("1")}
+	return NewGraphWithEdges([]Edge{ab, bc, ac, cd})
+}
+
+// blockingFinder không bao giờ tìm được route, chỉ dừng lại khi ctx kết thúc.
+type blockingFinder struct{}
+
+func (blockingFinder) Name() string { return "blocking" }
+
+func (blockingFinder) find(ctx context.Context, _ *graph, _, _ string,
+	_ decimal.Decimal, _ bool, _ int) (map[string]decimal.Decimal, map[string]Edge, error) {
+	<-ctx.Done()
+	return nil, nil, ctx.Err()
+}
+
+func TestGraph_BestBidRoute_Finders(t *testing.T) {
+	tests := []struct {
+		finder    RouteFinder
+		wantPrice string
+		wantRoute []string
+	}{
+		{finder: BellmanFord(), wantPrice: "5", wantRoute: []string{"A", "B", "C"}},
+		{finder: SPFA(), wantPrice: "5", wantRoute: []string{"A", "B", "C"}},
+		{finder: DFS(0), wantPrice: "5", wantRoute: []string{"A", "B", "C"}},
+		{finder: DFS(1), wantPrice: "2", wantRoute: []string{"A", "C"}},
+		{finder: BranchAndBound(0), wantPrice: "5", wantRoute: []string{"A", "B", "C"}},
+		{finder: BranchAndBound(1), wantPrice: "2", wantRoute: []string{"A", "C"}},
+		{finder: UCS(), wantPrice: "2", wantRoute: []string{"A", "C"}},
+	}
+
+	g := newFinderTestGraph()
+	for _, tt := range tests {
+		t.Run(tt.finder.Name(), func(t *testing.T) {
+			got, err := g.BestBidRoute("A", "C", d("1"), WithFinder(tt.finder))
+			if err != nil {
+				t.Fatalf("BestBidRoute() error = %v", err)
+			}
+			if !got.Price.Equal(d(tt.wantPrice)) {
+				t.Errorf("Price = %v, want %s", got.Price, tt.wantPrice)
+			}
+			if !slices.Equal(got.Route, tt.wantRoute) {
+				t.Errorf("Route = %v, want %v", got.Route, tt.wantRoute)
+			}
+			if got.Algorithm != tt.finder.Name() {
+				t.Errorf("Algorithm = %q, want %q", got.Algorithm, tt.finder.Name())
+			}
+		})
+	}
+}
+
+func TestGraph_BestAskPrice_Finders(t *testing.T) {
+	g := newSplitTestGraph()
+	want, wantPath, err := g.BestAskPrice("KNC", "USDT", d("150"))
+	if err != nil {
+		t.Fatalf("BestAskPrice() error = %v", err)
+	}
+
+	for _, finder := range []RouteFinder{UCS(), SPFA(), DFS(0), BranchAndBound(0)} {
+		got, path, err := g.BestAskPrice("KNC", "USDT", d("150"), WithFinder(finder))
+		if err != nil {
+			t.Fatalf("%s: BestAskPrice() error = %v", finder.Name(), err)
+		}
+		if !got.Equal(want) || !slices.Equal(path, wantPath) {
+			t.Errorf("%s: BestAskPrice() = %v %v, want %v %v",
+				finder.Name(), got, path, want, wantPath)
+		}
+	}
+}
+
+func TestGraph_SPFA_ArbitrageError(t *testing.T) {
+	g := newArbitrageTestGraph("2", "3", "0.2")
+
+	_, _, err := g.BestBidPrice("D", "A", d("1"), WithFinder(SPFA()))
+	var arbErr *ArbitrageError
+	if !errors.As(err, &arbErr) {
+		t.Fatalf("BestBidPrice() error = %v, want *ArbitrageError", err)
+	}
+	assertCycle(t, arbErr, []string{"A", "B", "C", "A"})
+}
+
+func TestGraph_BestBidRoute_Race(t *testing.T) {
+	g := newFinderTestGraph()
+
+	got, err := g.BestBidRoute("A", "C", d("1"), WithRace(0))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if !got.Price.Equal(d("5")) {
+		t.Errorf("Price = %v, want 5", got.Price)
+	}
+	// UCS cho kết quả kém hơn nên không thắng
+	if got.Algorithm == "ucs" || got.Algorithm == "" {
+		t.Errorf("Algorithm = %q, want a finder other than ucs", got.Algorithm)
+	}
+}
+
+// inflatedFinder báo lượng token ở quote lớn hơn thực tế 1000.
+type inflatedFinder struct {
+	RouteFinder
+}
+
+func (inflatedFinder) Name() string { return "inflated" }
+
+func (f inflatedFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal, map[string]Edge, error) {
+	values, prevs, err := f.RouteFinder.find(ctx, g, base, quote, amount, sell, maxHops)
+	if value, ok := values[quote]; ok {
+		values[quote] = value.Add(d("1000"))
+	}
+	return values, prevs, err
+}
+
+func TestGraph_BestBidRoute_RaceValidatesEntries(t *testing.T) {
+	g := newFinderTestGraph()
+
+	// UCS chỉ tìm được A->C (2) nhưng báo 1002, kết quả này phải được kiểm tra
+	// trước khi so sánh nên Bellman-Ford thắng mà không cần sửa route
+	got, err := g.BestBidRoute("A", "C", d("1"),
+		WithRace(0, inflatedFinder{UCS()}, BellmanFord()))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if !got.Price.Equal(d("5")) || got.Algorithm != "bellman-ford" || got.Repaired {
+		t.Errorf("got price %v via %q (repaired %v), want 5 via bellman-ford",
+			got.Price, got.Algorithm, got.Repaired)
+	}
+}
+
+func TestGraph_BestBidRoute_RaceTimeout(t *testing.T) {
+	g := newFinderTestGraph()
+
+	got, err := g.BestBidRoute("A", "C", d("1"),
+		WithRace(10*time.Millisecond, blockingFinder{}, UCS()))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if got.Algorithm != "ucs" {
+		t.Errorf("Algorithm = %q, want ucs", got.Algorithm)
+	}
+
+	_, err = g.BestBidRoute("A", "C", d("1"),
+		WithRace(10*time.Millisecond, blockingFinder{}))
+	if !errors.Is(err, ErrRaceTimeout) {
+		t.Errorf("BestBidRoute() error = %v, want ErrRaceTimeout", err)
+	}
+}
+
+func TestGraph_BestBidRoute_RaceArbitrage(t *testing.T) {
+	g := newArbitrageTestGraph("2", "3", "0.2")
+
+	// UCS tìm được route nhưng Bellman-Ford phát hiện arbitrage loop
+	_, err := g.BestBidRoute("D", "A", d("1"), WithRace(0, UCS(), BellmanFord()))
+	if !errors.Is(err, ErrArbitrageLoop) {
+		t.Errorf("BestBidRoute() error = %v, want ErrArbitrageLoop", err)
+	}
+}
@@ -1,97 +1,205 @@
 package route
 
 import (
-	"container/heap"
+	"context"
 	"errors"
-	"math"
+	"iter"
 	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
 )
 
 var (
 	ErrNoRoute       = errors.New("no feasible route found")
 	ErrArbitrageLoop = errors.New("arbitrage loop detected")
+	ErrEdgeNotInPair = errors.New("edge does not belong to trading pair")
 )
 
+// Graph an toàn khi dùng đồng thời: các query đọc một snapshot bất biến của
+// đồ thị, các thao tác cập nhật tạo snapshot mới với version tăng dần.
+//
+// Mỗi loại query có một method cho mỗi chiều giao dịch (bid, ask), các biến
+// thể của query như context, số route hay lượng token tối đa được chọn qua
+// QueryOption thay vì thêm method, xem QueryOptions.
 type Graph interface {
 	AddEdge(e Edge)
+	UpdateEdge(e Edge) uint64
+	RemoveEdge(key PairKey) uint64
+	ReplacePair(key PairKey, edges ...Edge) (uint64, error)
+	Version() uint64
 	Neighbors(token string) []Edge
-	BestBidPrice(base, quote string, amount float64) (float64, []string, error)
-	BestAskPrice(base, quote string, amount float64) (float64, []string, error)
+	BestBidPrice(base, quote string, amount decimal.Decimal, opts ...QueryOption) (decimal.Decimal, []string, error)
+	BestAskPrice(base, quote string, amount decimal.Decimal, opts ...QueryOption) (decimal.Decimal, []string, error)
+	BestBidRoute(base, quote string, amount decimal.Decimal, opts ...QueryOption) (RouteResult, error)
+	BestAskRoute(base, quote string, amount decimal.Decimal, opts ...QueryOption) (RouteResult, error)
+	BidCurve(base, quote string, amounts []decimal.Decimal, opts ...QueryOption) (PriceCurve, error)
+	AskCurve(base, quote string, amounts []decimal.Decimal, opts ...QueryOption) (PriceCurve, error)
+	SplitBidPrice(base, quote string, amount decimal.Decimal, parts int, opts ...QueryOption) (SplitResult, error)
+	SplitAskPrice(base, quote string, amount decimal.Decimal, parts int, opts ...QueryOption) (SplitResult, error)
 }
 
+// graph là một snapshot của đồ thị. Sau khi được publish bởi syncGraph,
+// graph không còn bị thay đổi nên các query có thể đọc mà không cần khóa.
 type graph struct {
 	// map có key là tên token, value là các trading pairs (symbols) xuất phát
-	// từ token này
-	edges map[string][]Edge
+	// từ token này, xem edgeMap
+	edges edgeMap
+
+	// version của snapshot, tăng thêm 1 sau mỗi lần cập nhật đồ thị
+	version uint64
+
+	// filter chọn các cạnh được dùng khi tìm đường, nil là dùng tất cả các
+	// cạnh. Xem view
+	filter func(Edge) bool
 }
 
 func NewGraph() Graph {
-	return &graph{
-		edges: make(map[string][]Edge),
-	}
+	return newSyncGraph(&graph{})
 }
 
 func NewGraphWithEdges(edgeList []Edge) Graph {
-	g := &graph{edges: make(map[string][]Edge)}
+	g := &graph{}
 	for _, e := range edgeList {
 		g.AddEdge(e)
 	}
-	return g
+	return newSyncGraph(g)
 }
 
+// AddEdge thêm cạnh vào graph chưa được publish, dùng khi khởi tạo đồ thị.
 func (g *graph) AddEdge(e Edge) {
-	// hàm append tự tạo slice nếu g.edges[e.From()] truyền vào là nil, khá hay
-	g.edges[e.From()] = append(g.edges[e.From()], e)
+	// hàm append tự tạo slice nếu g.edges.from(e.From()) trả về nil, khá hay
+	g.edges.set(e.From(), append(g.edges.from(e.From()), e))
 }
 
 // Neighbors trả về danh sách các cạnh xuất phát từ token truyền vào
 func (g graph) Neighbors(token string) []Edge {
-	return g.edges[token]
+	return g.edges.from(token)
+}
+
+// view trả về graph dùng chung các cạnh với g nhưng chỉ cho phép tìm đường qua
+// các cạnh thỏa mãn bộ lọc của options, xem QueryOptions.edgeFilter. Các cạnh
+// không bị sao chép, view chỉ lọc khi duyệt qua outgoing.
+func (g *graph) view(options QueryOptions) *graph {
+	filter := options.edgeFilter()
+	if filter == nil {
+		return g
+	}
+	return g.withFilter(filter)
+}
+
+// withFilter trả về view của g chỉ gồm các cạnh thỏa mãn cả bộ lọc hiện tại
+// của g và filter.
+func (g *graph) withFilter(filter func(Edge) bool) *graph {
+	view := *g
+	if g.filter != nil {
+		view.filter = func(e Edge) bool { return g.filter(e) && filter(e) }
+	} else {
+		view.filter = filter
+	}
+	return &view
+}
+
+// allows kiểm tra cạnh e có được dùng khi tìm đường hay không.
+func (g *graph) allows(e Edge) bool {
+	return g.filter == nil || g.filter(e)
+}
+
+// vertexCount trả về số đỉnh của đồ thị, gồm cả các token chỉ có cạnh đi vào
+// nên không phải token của g.edges. Với view, chỉ các token có cạnh được phép
+// dùng được đếm.
+func (g *graph) vertexCount() int {
+	tokens := make(map[string]struct{}, g.edges.len())
+	for token := range g.edges.tokens() {
+		for e := range g.outgoing(token) {
+			tokens[token] = struct{}{}
+			tokens[e.To()] = struct{}{}
+		}
+	}
+	return len(tokens)
+}
+
+// outgoing duyệt các cạnh xuất phát từ token được phép dùng khi tìm đường.
+func (g *graph) outgoing(token string) iter.Seq[Edge] {
+	return func(yield func(Edge) bool) {
+		for _, e := range g.edges.from(token) {
+			if g.allows(e) && !yield(e) {
+				return
+			}
+		}
+	}
 }
 
 // BestBidPrice tìm giá bán tốt nhất (tối đa hóa lượng quote token thu được)
 // khi bán amount base token, xuất phát từ token base và kết thúc ở token quote.
 //
 // Kết quả trả về:
-//   - float64: tỷ lệ quote/base tốt nhất (maxAcquired[quote] / amount)
+//   - decimal.Decimal: tỷ lệ quote/base tốt nhất (maxAcquired[quote] / amount),
+//     làm tròn xuống
 //   - []string: đường đi (route) từ base đến quote
 //   - err: trường hợp không tìm được đường đi hoặc xuất hiện arbitrage loop
-func (g *graph) BestBidPrice(base, quote string, amount float64) (
-	float64, []string, error) {
-	maxAcquired, prevs, err := g.propagateBellmanFord(base, quote, amount)
+//
+// opts cho phép chọn thuật toán tìm đường và tiếp tục tìm đường khi có
+// arbitrage loop, xem QueryOptions. Dùng BestBidRoute để nhận về các chu trình được bỏ qua.
+// Với WithContext, tìm đường dừng lại và trả về ctx.Err() khi ctx kết thúc,
+// WithPartialResult không có tác dụng vì kết quả không đánh dấu được là chưa
+// hoàn chỉnh, dùng BestBidRoute để nhận kết quả này.
+func (g *graph) BestBidPrice(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (decimal.Decimal, []string, error) {
+	options := newQueryOptions(opts)
+	options.PartialResult = false
+	ctx := options.context()
+	if options.ExactQuote {
+		result, err := g.bestRoute(ctx, base, quote, amount, true, options)
+		return result.Price, result.Route, err
+	}
+	view := g.view(options)
+	result, err := view.search(ctx, base, quote, amount, true, options)
+	if err != nil {
+		return decimal.Zero, nil, g.liquidityError(base, quote, amount, true, options, err)
+	}
+	edges, err := view.validatedEdges(ctx, base, quote, amount, true, options, &result)
 	if err != nil {
-		return 0, nil, err
+		return decimal.Zero, nil, err
 	}
 
-	return maxAcquired[quote] / amount, getPath(prevs, base, quote), nil
+	price := result.values[quote].QuoRound(amount, decimal.RoundDown)
+	return price, edgesPath(base, edges), nil
 }
 
 // BestAskPrice tìm giá mua tốt nhất (tối thiểu hóa lượng quote token cần thiết)
 // để mua amount base token, xuất phát từ token base và kết thúc ở token quote.
 //
 // Kết quả trả về:
-//   - float64: tỷ lệ quote/base tốt nhất (minRequired[quote] / amount)
+//   - decimal.Decimal: tỷ lệ quote/base tốt nhất (minRequired[quote] / amount),
+//     làm tròn lên
 //   - []string: đường đi (route) từ base đến quote
 //   - err: trường hợp không tìm được đường đi hoặc xuất hiện arbitrage loop
-func (g graph) BestAskPrice(base, quote string, amount float64) (
-	float64, []string, error) {
-	minRequired, prevs, err := g.bellmanFord(base, quote, amount)
+//
+// opts cho phép chọn thuật toán tìm đường và tiếp tục tìm đường khi có
+// arbitrage loop, xem QueryOptions. Dùng BestAskRoute để nhận về các chu trình được bỏ qua.
+// WithContext có tác dụng giống BestBidPrice.
+func (g *graph) BestAskPrice(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (decimal.Decimal, []string, error) {
+	options := newQueryOptions(opts)
+	options.PartialResult = false
+	ctx := options.context()
+	if options.ExactQuote {
+		result, err := g.bestRoute(ctx, base, quote, amount, false, options)
+		return result.Price, result.Route, err
+	}
+	view := g.view(options)
+	result, err := view.search(ctx, base, quote, amount, false, options)
 	if err != nil {
-		return 0, nil, err
+		return decimal.Zero, nil, g.liquidityError(base, quote, amount, false, options, err)
+	}
+	edges, err := view.validatedEdges(ctx, base, quote, amount, false, options, &result)
+	if err != nil {
+		return decimal.Zero, nil, err
 	}
 
-	path := getPath(prevs, base, quote)
+	path := edgesPath(base, edges)
 	slices.Reverse(path)
-	return minRequired[quote] / amount, path, nil
-
-	// minRequired, prev, isFeasible := g.ucs(base, quote, amount)
-	// if isFeasible {
-	// 	path := getPath(prev, base, quote)
-	// 	slices.Reverse(path)
-	// 	return minRequired[quote] / amount, path, nil
-	// }
-
-	// return 0, nil, ErrNoRoute
+	return result.values[quote].QuoRound(amount, decimal.RoundUp), path, nil
 }
 
 // getPath sử dụng để trả về danh sách token trên đường đi từ base đến quote,
@@ -99,32 +207,67 @@ func (g graph) BestAskPrice(base, quote string, amount float64) (
 // base theo prevs, sau đó đảo ngược kết quả để trả về đúng thứ tự từ base
 // đến quote.
 // Tham số:
-//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là đỉnh liền
-//     trước)
+//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là cạnh đi
+//     vào đỉnh đó, From() của cạnh là đỉnh liền trước)
 //
 // Kết quả trả về:
 //   - path: slice lưu danh sách token trên đường đi từ base đến quote, bao
 //     gồm cả base lẫn quote
-func getPath(prevs map[string]string, base, quote string) []string {
+func getPath(prevs map[string]Edge, base, quote string) []string {
 	path := []string{}
 	path = append(path, quote)
-	current, ok := prevs[quote]
-	for {
+	prev, ok := prevs[quote]
+	// Mỗi đỉnh có tối đa một cạnh đi vào nên đường đi không dài quá len(prevs)
+	// cạnh, giới hạn này tránh lặp vô hạn nếu prevs có chu trình, ví dụ khi
+	// thuật toán bị dừng giữa chừng
+	for len(path) <= len(prevs) {
 		if !ok {
 			break
 		}
+		current := prev.From()
 		path = append(path, current)
 
 		if current == base {
 			break
 		}
-		current, ok = prevs[current]
+		prev, ok = prevs[current]
 	}
 	slices.Reverse(path)
 	return path
 	// return strings.Join(path, "->")
 }
 
+// getEdges giống getPath nhưng trả về các cạnh trên đường đi từ base đến quote
+// theo prevs, tức đúng cạnh thuật toán đã chọn ở mỗi chặng kể cả khi có nhiều
+// cạnh nối cùng hai token (nhiều exchange hoặc trading pair bị lặp lại). Trả
+// về false nếu truy vết từ quote không về tới base.
+func getEdges(prevs map[string]Edge, base, quote string) ([]Edge, bool) {
+	edges := []Edge{}
+	for token := quote; token != base; {
+		edge, ok := prevs[token]
+		// Giới hạn len(prevs) tránh lặp vô hạn nếu prevs có chu trình, giống
+		// getPath
+		if !ok || len(edges) >= len(prevs) {
+			return nil, false
+		}
+		edges = append(edges, edge)
+		token = edge.From()
+	}
+	slices.Reverse(edges)
+	return edges, true
+}
+
+// edgesPath trả về danh sách token trên đường đi gồm các cạnh edges, bắt đầu
+// từ base.
+func edgesPath(base string, edges []Edge) []string {
+	path := make([]string, 0, len(edges)+1)
+	path = append(path, base)
+	for _, edge := range edges {
+		path = append(path, edge.To())
+	}
+	return path
+}
+
 // propagateBellmanFord là một biến thể của thuật toán Bellman-Ford dùng để
 // lan truyền số lượng token tối đa có thể thu được tại mỗi đỉnh.
 // Xuất phát từ một lượng token ban đầu ở đỉnh base, thuật toán lặp n-1 lần
@@ -134,43 +277,47 @@ func getPath(prevs map[string]string, base, quote string) []string {
 // Kết quả trả về:
 //   - maxAcquired: map từ tên token đến số lượng token tối đa có thể thu được
 //     tại đỉnh đó
-//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là đỉnh liền
-//     trước)
-//   - err: trường hợp không tìm được đường đi hoặc xuất hiện arbitrage loop
-func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
-	map[string]float64, map[string]string, error) {
-	_, ok := g.edges[base]
-	if !ok {
+//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là cạnh đi
+//     vào đỉnh đó)
+//   - err: trường hợp không tìm được đường đi hoặc xuất hiện arbitrage loop,
+//     lỗi arbitrage loop gồm một hoặc nhiều *ArbitrageError mô tả các chu
+//     trình tìm được (xem arbitrageCycles)
+func (g *graph) propagateBellmanFord(ctx context.Context, base, quote string,
+	amount decimal.Decimal) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	if !g.edges.has(base) {
 		return nil, nil, ErrNoRoute
 	}
 
-	_, ok = g.edges[quote]
-	if !ok {
+	if !g.edges.has(quote) {
 		return nil, nil, ErrNoRoute
 	}
 
 	// Khởi tạo số token tối đa có thể thu được cho các đỉnh, đỉnh khởi đầu
 	// bằng lượng token cần bán, các đỉnh khác bằng 0
-	maxAcquired := make(map[string]float64, len(g.edges))
-	for token := range g.edges {
-		maxAcquired[token] = 0
+	maxAcquired := make(map[string]decimal.Decimal, g.edges.len())
+	for token := range g.edges.tokens() {
+		maxAcquired[token] = decimal.Zero
 	}
 	maxAcquired[base] = amount
 
-	// prevs là một map có key là đỉnh, value là đỉnh liền trước của nó
-	// Dùng để xây dựng route sau này
-	prevs := make(map[string]string, len(g.edges))
+	// prevs là một map có key là đỉnh, value là cạnh đi vào đỉnh đó, From()
+	// của cạnh là đỉnh liền trước. Dùng để xây dựng route sau này
+	prevs := make(map[string]Edge, g.edges.len())
 
 	// Lặp n-1 lần theo tư tưởng Bellman-Ford, với n là số đỉnh
-	for range len(g.edges) - 1 {
-		for baseToken, edges := range g.edges {
-			if maxAcquired[baseToken] == 0 {
+	for range g.vertexCount() - 1 {
+		if err := ctx.Err(); err != nil {
+			return maxAcquired, prevs, err
+		}
+		for baseToken := range g.edges.tokens() {
+			if maxAcquired[baseToken].IsZero() {
 				continue
 			}
 
 			// Đối với mỗi cạnh, thực hiện bán thử xem có được không?
 			// Nếu được thì thu về bao nhiêu quote token?
-			for _, edge := range edges {
+			for edge := range g.outgoing(baseToken) {
 				acquiredQuote, isFeasible := edge.SimulateSell(
 					maxAcquired[baseToken],
 				)
@@ -181,9 +328,9 @@ func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
 				}
 
 				// Cập nhật của đỉnh quote nếu bán được nhiều token hơn
-				if acquiredQuote > maxAcquired[edge.To()] {
+				if acquiredQuote.GreaterThan(maxAcquired[edge.To()]) {
 					maxAcquired[edge.To()] = acquiredQuote
-					prevs[edge.To()] = edge.From()
+					prevs[edge.To()] = edge
 				}
 			}
 		}
@@ -191,19 +338,20 @@ func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
 
 	// Kiểm tra đỉnh nguồn có bị cập nhật không, do thuật toán khởi đầu
 	// từ một lượng amount thay vì 0
-	if maxAcquired[base] > amount {
-		return nil, nil, ErrArbitrageLoop
+	cycles := cycleSet{}
+	if maxAcquired[base].GreaterThan(amount) {
+		cycles.add(g.arbitrageCycle(prevs, maxAcquired, base, true))
 	}
 
 	// Lặp qua tất cả các cạnh một lần nữa để kiểm tra arbitrage loop
-	for baseToken, edges := range g.edges {
-		if maxAcquired[baseToken] == 0 {
+	for baseToken := range g.edges.tokens() {
+		if maxAcquired[baseToken].IsZero() {
 			continue
 		}
 
 		// Đối với mỗi cạnh, thực hiện bán thử xem có được không?
 		// Nếu được thì thu về bao nhiêu quote token?
-		for _, edge := range edges {
+		for edge := range g.outgoing(baseToken) {
 			acquiredQuote, isFeasible := edge.SimulateSell(
 				maxAcquired[baseToken],
 			)
@@ -213,14 +361,21 @@ func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
 				continue
 			}
 
-			// Lượng token vẫn tăng, arbitrage loop tồn tại
-			if acquiredQuote > maxAcquired[edge.To()] {
-				return nil, nil, ErrArbitrageLoop
+			// Lượng token vẫn tăng, arbitrage loop tồn tại. Ghi nhận lần
+			// cập nhật cuối cùng để truy vết chu trình
+			if acquiredQuote.GreaterThan(maxAcquired[edge.To()]) {
+				maxAcquired[edge.To()] = acquiredQuote
+				prevs[edge.To()] = edge
+				cycles.add(g.arbitrageCycle(prevs, maxAcquired, edge.To(), true))
 			}
 		}
 	}
 
-	if maxAcquired[quote] == 0.0 {
+	if err := cycles.err(); err != nil {
+		return nil, nil, err
+	}
+
+	if maxAcquired[quote].IsZero() {
 		return nil, nil, ErrNoRoute
 	}
 
@@ -233,7 +388,7 @@ func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
 //
 // Ý tưởng:
 //   - Gán minRequired[base] = amount (lượng base token cần mua ở đỉnh xuất phát),
-//     các đỉnh còn lại là +Inf.
+//     các đỉnh còn lại là +Inf (không có trong map).
 //   - Lặp n-1 lần (với n là số đỉnh), mỗi lần thử mua base token qua các cạnh
 //     (SimulateBuy), cập nhật minRequired nếu tìm được giá trị nhỏ hơn.
 //   - Sau n-1 lần, lặp thêm 1 lần để kiểm tra arbitrage loop: nếu còn cập nhật
@@ -242,45 +397,47 @@ func (g *graph) propagateBellmanFord(base, quote string, amount float64) (
 // Kết quả trả về:
 //   - minRequired: map từ tên token đến số lượng quote token tối thiểu cần thiết
 //     để mua được amount base token tại đỉnh đó.
-//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là đỉnh liền trước).
-//   - err: trả về ErrNoRoute nếu không tìm được đường đi, ErrArbitrageLoop nếu phát hiện chu trình lợi nhuận.
+//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là cạnh đi vào đỉnh đó).
+//   - err: trả về ErrNoRoute nếu không tìm được đường đi, một hoặc nhiều
+//     *ArbitrageError nếu phát hiện chu trình lợi nhuận (xem arbitrageCycles).
 //
 // Lưu ý: Hàm này chỉ cho kết quả hợp lý khi đồ thị không có arbitrage loop.
-func (g *graph) bellmanFord(base, quote string, amount float64) (
-	map[string]float64, map[string]string, error) {
-	_, ok := g.edges[base]
-	if !ok {
+func (g *graph) bellmanFord(ctx context.Context, base, quote string,
+	amount decimal.Decimal) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	if !g.edges.has(base) {
 		return nil, nil, ErrNoRoute
 	}
 
-	_, ok = g.edges[quote]
-	if !ok {
+	if !g.edges.has(quote) {
 		return nil, nil, ErrNoRoute
 	}
 
 	// Khởi tạo số token tối đa tối thiểu để mua một lượng amount base token
 	// cho các đỉnh, đỉnh khởi đầu bằng lượng token cần mua, các đỉnh khác
 	// bằng 0
-	minRequired := map[string]float64{}
-	for base := range g.edges {
-		minRequired[base] = math.Inf(1)
-	}
+	// Decimal không biểu diễn được +Inf nên các đỉnh chưa mua được sẽ không
+	// có mặt trong minRequired
+	minRequired := map[string]decimal.Decimal{}
 	minRequired[base] = amount
 
-	// prevs là một map có key là đỉnh, value là đỉnh liền trước của nó
-	// Dùng để xây dựng route sau này
-	prevs := make(map[string]string, len(g.edges))
+	// prevs là một map có key là đỉnh, value là cạnh đi vào đỉnh đó, From()
+	// của cạnh là đỉnh liền trước. Dùng để xây dựng route sau này
+	prevs := make(map[string]Edge, g.edges.len())
 
 	// Lặp n-1 lần theo tư tưởng Bellman-Ford, với n là số đỉnh
-	for range len(g.edges) - 1 {
-		for baseToken, edges := range g.edges {
-			if math.IsInf(minRequired[baseToken], 1) {
+	for range g.vertexCount() - 1 {
+		if err := ctx.Err(); err != nil {
+			return minRequired, prevs, err
+		}
+		for baseToken := range g.edges.tokens() {
+			if _, ok := minRequired[baseToken]; !ok {
 				continue
 			}
 
 			// Đối với mỗi cạnh, thực hiện mua thử xem có được không?
 			// Nếu được thì cần bao nhiêu quote token?
-			for _, edge := range edges {
+			for edge := range g.outgoing(baseToken) {
 				quoteRequired, isFeasible := edge.SimulateBuy(
 					minRequired[baseToken],
 				)
@@ -291,23 +448,24 @@ func (g *graph) bellmanFord(base, quote string, amount float64) (
 				}
 
 				// Cập nhật của đỉnh quote cần ít token hơn
-				if quoteRequired < minRequired[edge.To()] {
+				if isLess(quoteRequired, minRequired, edge.To()) {
 					minRequired[edge.To()] = quoteRequired
-					prevs[edge.To()] = edge.From()
+					prevs[edge.To()] = edge
 				}
 			}
 		}
 	}
 
 	// Lặp qua tất cả các cạnh một lần nữa để kiểm tra arbitrage loop
-	for baseToken, edges := range g.edges {
-		if math.IsInf(minRequired[baseToken], 1) {
+	cycles := cycleSet{}
+	for baseToken := range g.edges.tokens() {
+		if _, ok := minRequired[baseToken]; !ok {
 			continue
 		}
 
 		// Đối với mỗi cạnh, thực hiện mua thử xem có được không?
 		// Nếu được thì cần bao nhiêu quote token?
-		for _, edge := range edges {
+		for edge := range g.outgoing(baseToken) {
 			quoteRequired, isFeasible := edge.SimulateBuy(
 				minRequired[baseToken],
 			)
@@ -317,108 +475,31 @@ func (g *graph) bellmanFord(base, quote string, amount float64) (
 				continue
 			}
 
-			// Lượng token vẫn tăng, arbitrage loop tồn tại
-			if quoteRequired < minRequired[edge.To()] {
-				return nil, nil, ErrArbitrageLoop
+			// Lượng token vẫn tăng, arbitrage loop tồn tại. Ghi nhận lần
+			// cập nhật cuối cùng để truy vết chu trình
+			if isLess(quoteRequired, minRequired, edge.To()) {
+				minRequired[edge.To()] = quoteRequired
+				prevs[edge.To()] = edge
+				cycles.add(g.arbitrageCycle(prevs, minRequired, edge.To(), false))
 			}
 		}
 	}
 
-	if math.IsInf(minRequired[quote], 1) {
-		return nil, nil, ErrNoRoute
-	}
-
-	return minRequired, prevs, nil
-}
-
-// ucs (Uniform Cost Search) là một biến thể của thuật toán Dijkstra dùng để
-// tìm số lượng quote token tối thiểu cần thiết để mua được một lượng amount
-// base token, xuất phát từ đỉnh base và kết thúc ở đỉnh quote. ucs nhanh hơn
-// Dijkstra do nó sử dụng min heap để chọn ra đỉnh có min required ở mỗi lần
-// lặp. Độ phức tạp O(E log(V)).
-//
-// Ý tưởng:
-//   - Gán minRequired[base] = amount (lượng base token cần mua ở đỉnh xuất phát),
-//     các đỉnh còn lại là +Inf.
-//   - Mỗi bước, lấy ra đỉnh có minRequired nhỏ nhất chưa visited, giả lập việc
-//     mua base token qua các cạnh (SimulateBuy). Nếu khả thi và lượng quote cần
-//     nhỏ hơn giá trị hiện tại ở đỉnh kề, thì cập nhật.
-//   - Mỗi đỉnh chỉ được visited một lần, đảm bảo không đi vào chu trình lợi
-//     nhuận vô hạn.
-//
-// Kết quả trả về:
-//   - minRequired: map từ tên token đến số lượng quote token tối thiểu cần thiết
-//     để mua được amount base token tại đỉnh đó.
-//   - prevs: map để truy vết đường đi tối ưu (key là đỉnh, value là đỉnh liền trước).
-//   - err: nếu không tìm được route khả thi.
-//
-// Lưu ý: Hàm này chỉ cho kết quả hợp lý khi đồ thị không có arbitrage loop.
-func (g graph) ucs(base, quote string, amount float64) (
-	map[string]float64, map[string]string, error) {
-	_, ok := g.edges[base]
-	if !ok {
-		return nil, nil, ErrNoRoute
+	if err := cycles.err(); err != nil {
+		return nil, nil, err
 	}
 
-	_, ok = g.edges[quote]
-	if !ok {
+	if _, ok := minRequired[quote]; !ok {
 		return nil, nil, ErrNoRoute
 	}
 
-	// Khởi tạo số token tối đa tối thiểu để mua một lượng amount base token
-	// cho các đỉnh, đỉnh khởi đầu bằng lượng token cần mua, các đỉnh khác
-	// bằng 0
-	minRequired := map[string]float64{}
-	for base := range g.edges {
-		minRequired[base] = math.Inf(1)
-	}
-	minRequired[base] = amount
-
-	// prevs là một map có key là đỉnh, value là đỉnh liền trước của nó
-	// Dùng để xây dựng route sau này
-	prevs := map[string]string{}
-
-	// Khởi tạo min heap cho thuật toán Dijkstra, để lấy ra đỉnh có số token
-	// nhỏ nhất tại mỗi bước
-	minHeap := &TokenMinHeap{}
-	heap.Init(minHeap)
-	minHeap.Push(TokenInfo{Token: base, MinRequired: amount})
-
-	visited := make(map[string]bool, len(g.edges))
-
-	for {
-		if minHeap.Len() == 0 {
-			break
-		}
-
-		// Lấy ra đỉnh có số lượng tối thiểu
-		tokenInfo := minHeap.Pop()
-		token := tokenInfo.(TokenInfo).Token
-		required := tokenInfo.(TokenInfo).MinRequired
-
-		if visited[token] {
-			continue
-		}
-		visited[token] = true
-
-		// Đối với mỗi cạnh xuất phát từ đỉnh vừa lấy, giả lập việc mua base
-		// token. Nếu khả thi (order book fill đủ) và lượng quote cần nhỏ
-		// hơn lượng hiện tại thì cập nhật
-		for _, edge := range g.edges[token] {
-			quoteRequired, feasible := edge.SimulateBuy(required)
-			if feasible && quoteRequired < minRequired[edge.To()] {
-				minRequired[edge.To()] = quoteRequired
-				minHeap.Push(TokenInfo{
-					Token: edge.To(), MinRequired: minRequired[edge.To()],
-				})
-				prevs[edge.To()] = edge.From()
-			}
-		}
-	}
-
-	if !visited[quote] {
-		return nil, nil, ErrNoRoute // không có route khả thi
-	}
-
 	return minRequired, prevs, nil
 }
+
+// isLess kiểm tra value có nhỏ hơn minRequired[token] hay không, token không
+// có trong map được coi là +Inf.
+func isLess(value decimal.Decimal, minRequired map[string]decimal.Decimal,
+	token string) bool {
+	current, ok := minRequired[token]
+	return !ok || value.LessThan(current)
+}
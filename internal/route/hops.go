@@ -0,0 +1,120 @@
+package route
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// boundedBellmanFord là biến thể của Bellman-Ford giới hạn số cạnh của route:
+// lượt thứ i chỉ nới các cạnh từ kết quả của lượt i-1, nên sau lượt i,
+// layers[i][token] là lượng token tốt nhất (tối đa khi sell, tối thiểu khi
+// mua) trong các route từ base tới token có tối đa i cạnh. Khác với
+// propagateBellmanFord và bellmanFord, các cập nhật trong cùng một lượt không
+// được dùng ngay, nếu không một lượt có thể đi qua nhiều cạnh.
+//
+// Sau maxHops lượt, route tối ưu tới quote được truy vết ngược qua từng lượt.
+// Route chỉ lặp token khi đi qua một chu trình có lời trong giới hạn maxHops
+// cạnh, khi đó trả về *ArbitrageError của chu trình này. Các arbitrage loop
+// không nằm trên route tới quote không ảnh hưởng tới kết quả.
+//
+// Kết quả trả về giống propagateBellmanFord (sell = true) hoặc bellmanFord
+// (sell = false). values là kết quả của lượt cuối, prevs chỉ chứa các cạnh
+// trên route tới quote.
+func (g *graph) boundedBellmanFord(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	// vias[i][token] là cạnh đi vào token nếu lượng token được cải thiện ở
+	// lượt i, ngược lại kết quả của lượt i giữ nguyên từ lượt i-1
+	layers := []map[string]decimal.Decimal{{base: amount}}
+	vias := []map[string]Edge{{}}
+
+	var err error
+	for range maxHops {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		last := layers[len(layers)-1]
+		next := maps.Clone(last)
+		via := map[string]Edge{}
+		for token, value := range last {
+			for edge := range g.outgoing(token) {
+				acquired, isFeasible := simulate(edge, value, sell)
+				if isFeasible && improves(acquired, next, edge.To(), sell) {
+					next[edge.To()] = acquired
+					via[edge.To()] = edge
+				}
+			}
+		}
+
+		// Không còn cập nhật thì các lượt sau cũng giống lượt này
+		if len(via) == 0 {
+			break
+		}
+		layers = append(layers, next)
+		vias = append(vias, via)
+	}
+
+	values := layers[len(layers)-1]
+	if _, ok := values[quote]; !ok {
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrNoRoute
+	}
+
+	// Truy vết ngược từ quote ở lượt cuối về base ở lượt 0
+	edges := []Edge{}
+	token := quote
+	for i := len(vias) - 1; i > 0; i-- {
+		if edge, ok := vias[i][token]; ok {
+			edges = append(edges, edge)
+			token = edge.From()
+		}
+	}
+	slices.Reverse(edges)
+
+	// seen[token] là số cạnh từ base tới token trên route
+	prevs := make(map[string]Edge, len(edges))
+	seen := map[string]int{base: 0}
+	for i, edge := range edges {
+		if j, ok := seen[edge.To()]; ok {
+			return nil, nil, walkCycle(edges[j:i+1], values, sell)
+		}
+		seen[edge.To()] = i + 1
+		prevs[edge.To()] = edge
+	}
+	return values, prevs, err
+}
+
+// walkCycle tạo *ArbitrageError từ chu trình edges trên route của
+// boundedBellmanFord. Lượng token tại đầu chu trình không được lưu theo từng
+// lượt nên dùng kết quả tốt nhất tại token này trong values, đủ để mô phỏng
+// lại lợi nhuận của chu trình.
+func walkCycle(edges []Edge, values map[string]decimal.Decimal, sell bool) error {
+	start := edges[0].From()
+	cycle := []string{start}
+	for _, edge := range edges {
+		cycle = append(cycle, edge.To())
+	}
+
+	amount := values[start]
+	return &ArbitrageError{
+		Cycle:  cycle,
+		Edges:  edges,
+		Amount: amount,
+		Profit: cycleProfit(edges, amount, sell),
+		Sell:   sell,
+	}
+}
@@ -0,0 +1,93 @@
+package route
+
+import (
+	"slices"
+	"testing"
+)
+
+// newHopsTestGraph tạo đồ thị mà route càng dài càng tốt: A->E trực tiếp cho
+// 2 E, A->B->E cho 5 E, A->B->C->D->E cho 10 E. Cạnh E->F để E có cạnh đi ra.
+func newHopsTestGraph() Graph {
+	edge := func(base, quote, price string) Edge {
+		return SimpleEdge{BaseToken: base, QuoteToken: quote, BidPrice: d(price), AskPrice: d(price)}
+	}
+	return NewGraphWithEdges([]Edge{
+		edge("A", "B", "1"), edge("B", "C", "1"), edge("C", "D", "1"), edge("D", "E", "10"),
+		edge("A", "E", "2"), edge("B", "E", "5"), edge("E", "F", "1"),
+	})
+}
+
+func TestGraph_BestBidRoute_MaxHops(t *testing.T) {
+	tests := []struct {
+		maxHops   int
+		wantPrice string
+		wantRoute []string
+	}{
+		{maxHops: 1, wantPrice: "2", wantRoute: []string{"A", "E"}},
+		{maxHops: 2, wantPrice: "5", wantRoute: []string{"A", "B", "E"}},
+		{maxHops: 3, wantPrice: "5", wantRoute: []string{"A", "B", "E"}},
+		{maxHops: 4, wantPrice: "10", wantRoute: []string{"A", "B", "C", "D", "E"}},
+		{maxHops: 0, wantPrice: "10", wantRoute: []string{"A", "B", "C", "D", "E"}},
+	}
+
+	g := newHopsTestGraph()
+	for _, tt := range tests {
+		got, err := g.BestBidRoute("A", "E", d("1"), WithMaxHops(tt.maxHops))
+		if err != nil {
+			t.Fatalf("maxHops %d: BestBidRoute() error = %v", tt.maxHops, err)
+		}
+		if !got.Price.Equal(d(tt.wantPrice)) || !slices.Equal(got.Route, tt.wantRoute) {
+			t.Errorf("maxHops %d: got %v %v, want %s %v",
+				tt.maxHops, got.Price, got.Route, tt.wantPrice, tt.wantRoute)
+		}
+	}
+}
+
+// Bellman-Ford giới hạn số cạnh phải cho kết quả giống DFS, vốn duyệt hết mọi
+// route thỏa mãn giới hạn.
+func TestGraph_MaxHops_MatchesDFS(t *testing.T) {
+	graphs := map[string]Graph{"hops": newHopsTestGraph(), "split": newSplitTestGraph()}
+	queries := map[string][2]string{"hops": {"A", "E"}, "split": {"KNC", "USDT"}}
+
+	for name, g := range graphs {
+		base, quote := queries[name][0], queries[name][1]
+		for maxHops := 1; maxHops <= 3; maxHops++ {
+			for _, sell := range []bool{true, false} {
+				query := g.BestAskPrice
+				if sell {
+					query = g.BestBidPrice
+				}
+				want, wantPath, err := query(base, quote, d("150"),
+					WithFinder(DFS(maxHops)))
+				if err != nil {
+					t.Fatalf("%s: DFS error = %v", name, err)
+				}
+				got, path, err := query(base, quote, d("150"), WithMaxHops(maxHops))
+				if err != nil {
+					t.Fatalf("%s: maxHops %d error = %v", name, maxHops, err)
+				}
+				if !got.Equal(want) || !slices.Equal(path, wantPath) {
+					t.Errorf("%s maxHops %d sell %v: got %v %v, want %v %v",
+						name, maxHops, sell, got, path, want, wantPath)
+				}
+				if len(path)-1 > maxHops {
+					t.Errorf("%s maxHops %d: route %v too long", name, maxHops, path)
+				}
+			}
+		}
+	}
+}
+
+// Arbitrage loop không nằm trong giới hạn số cạnh của route tới quote không
+// gây lỗi.
+func TestGraph_BestBidPrice_MaxHopsIgnoresFarCycle(t *testing.T) {
+	g := newArbitrageTestGraph("2", "3", "0.2")
+
+	price, path, err := g.BestBidPrice("D", "A", d("1"), WithMaxHops(2))
+	if err != nil {
+		t.Fatalf("BestBidPrice() error = %v", err)
+	}
+	if !price.Equal(d("1")) || !slices.Equal(path, []string{"D", "A"}) {
+		t.Errorf("BestBidPrice() = %v %v, want 1 [D A]", price, path)
+	}
+}
@@ -0,0 +1,32 @@
+package route
+
+import "iter"
+
+// Levels là các order của một phía order book theo thứ tự từ tốt tới kém, chia
+// thành các chunk liên tiếp. Các chunk không được thay đổi sau khi tạo nên
+// nhiều Levels có thể dùng chung chunk: hai phiên bản liên tiếp của một order
+// book chỉ khác nhau ở chunk có price level thay đổi, xem
+// orderbook.OrderBook.View.
+type Levels [][]Order
+
+// All duyệt các order theo thứ tự của Levels.
+func (l Levels) All() iter.Seq[Order] {
+	return func(yield func(Order) bool) {
+		for _, chunk := range l {
+			for _, order := range chunk {
+				if !yield(order) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Len trả về tổng số order của Levels.
+func (l Levels) Len() int {
+	n := 0
+	for _, chunk := range l {
+		n += len(chunk)
+	}
+	return n
+}
@@ -0,0 +1,316 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+var (
+	// ErrInsufficientLiquidity nghĩa là base và quote có kết nối nhưng order
+	// book không đủ depth cho lượng token yêu cầu, xem LiquidityError.
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+
+	// ErrUnlimitedLiquidity nghĩa là có route không giới hạn thanh khoản, ví
+	// dụ chỉ gồm các SimpleEdge, nên không có lượng token tối đa.
+	ErrUnlimitedLiquidity = errors.New("unlimited liquidity")
+)
+
+// maxDoublings giới hạn số lần nhân đôi amount khi tìm lượng token tối đa,
+// vượt quá giới hạn này route được coi là không giới hạn thanh khoản.
+const maxDoublings = 128
+
+// maxAmountTolerance là sai số tương đối của lượng token tối đa: binary search
+// dừng khi khoảng còn lại không quá maxAmountTolerance lần cận trên, khoảng 30
+// lần tìm đường thay vì khoảng 60 lần nếu search tới từng đơn vị nhỏ nhất của
+// decimal.
+var maxAmountTolerance = decimal.RequireFromString("0.000000001")
+
+// LiquidityError cho biết không route nào đủ thanh khoản cho lượng token yêu
+// cầu dù base và quote có kết nối.
+//   - Amount: lượng token yêu cầu của query
+//   - Sell: true nếu là query bid, false nếu là query ask
+//
+// Lượng token tối đa giao dịch được không được tính sẵn vì cần vài chục lần
+// tìm đường, gọi Max khi cần.
+//
+// LiquidityError thỏa mãn cả errors.Is(err, ErrInsufficientLiquidity) lẫn
+// errors.Is(err, ErrNoRoute) vì không có route khả thi cho Amount.
+type LiquidityError struct {
+	Amount decimal.Decimal
+	Sell   bool
+
+	mu        sync.Mutex
+	max       func(ctx context.Context) (decimal.Decimal, []string, error)
+	done      bool
+	maxAmount decimal.Decimal
+	route     []string
+	err       error
+}
+
+// NewLiquidityError tạo LiquidityError với lượng token tối đa maxAmount và
+// route đã biết trước, Max trả về ngay các giá trị này.
+func NewLiquidityError(amount, maxAmount decimal.Decimal, route []string, sell bool) *LiquidityError {
+	return &LiquidityError{
+		Amount:    amount,
+		Sell:      sell,
+		done:      true,
+		maxAmount: maxAmount,
+		route:     route,
+	}
+}
+
+// Max trả về lượng token lớn nhất giao dịch được, cùng đơn vị với Amount (base
+// token, hoặc quote token với WithExactQuote), và route tương ứng theo thứ tự
+// giao dịch. Lần gọi đầu tiên tìm lượng tối đa trên snapshot của query bị lỗi
+// (xem WithMaxAmount), kết quả được dùng lại cho các lần gọi sau, trừ khi ctx
+// kết thúc giữa chừng: khi đó lỗi của ctx được trả về cùng lượng token giao
+// dịch được đã biết tới lúc đó (Zero nếu chưa biết), lượng này nhỏ hơn hoặc
+// bằng lượng tối đa và không được lưu lại.
+//
+// Trả về ErrNoRoute nếu không lượng nào nhỏ hơn Amount giao dịch được, ví dụ
+// các order book trên đường đi đều rỗng, Amount không đủ trả phí cố định
+// (Fee.Fixed) hoặc route không thỏa mãn các option khác như
+// WithRequiredTokens.
+func (e *LiquidityError) Max(ctx context.Context) (decimal.Decimal, []string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.done && e.max == nil {
+		e.done, e.err = true, ErrNoRoute
+	}
+	if !e.done {
+		maxAmount, route, err := e.max(ctx)
+		if isContextError(err) {
+			return maxAmount, route, err
+		}
+		e.done, e.maxAmount, e.route, e.err = true, maxAmount, route, err
+	}
+	return e.maxAmount, e.route, e.err
+}
+
+func (e *LiquidityError) Error() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.done || e.err != nil {
+		return fmt.Sprintf("%s: requested %s", ErrInsufficientLiquidity, e.Amount)
+	}
+	return fmt.Sprintf("%s: requested %s, at most %s via %s",
+		ErrInsufficientLiquidity, e.Amount, e.maxAmount, strings.Join(e.route, "->"))
+}
+
+// Is cho phép so sánh errors.Is(err, ErrInsufficientLiquidity) và
+// errors.Is(err, ErrNoRoute).
+func (e *LiquidityError) Is(target error) bool {
+	return target == ErrInsufficientLiquidity || target == ErrNoRoute
+}
+
+// maxRoute tìm route của lượng token lớn nhất giao dịch được nhưng không quá
+// amount, trả về cùng lượng token đó, xem WithMaxAmount. amount đủ thanh khoản
+// thì kết quả giống bestRoute. amount bằng 0 là không giới hạn: lượng tối đa
+// được tìm từ một đơn vị token, trả về ErrUnlimitedLiquidity nếu có route
+// không giới hạn thanh khoản và ErrNoRoute nếu không có route nào.
+func (g *graph) maxRoute(ctx context.Context, base, quote string, amount decimal.Decimal,
+	sell bool, options QueryOptions) (RouteResult, decimal.Decimal, error) {
+	options.PartialResult = false
+	if amount.IsZero() {
+		maxAmount, err := g.maxAmount(ctx, base, quote, decimal.One, sell, options)
+		if err != nil {
+			return RouteResult{}, decimal.Zero, err
+		}
+		amount = maxAmount
+	} else {
+		result, err := g.bestRoute(ctx, base, quote, amount, sell, options)
+		var liqErr *LiquidityError
+		if !errors.As(err, &liqErr) {
+			return result, amount, err
+		}
+		if amount, _, err = liqErr.Max(ctx); err != nil {
+			return RouteResult{}, decimal.Zero, err
+		}
+	}
+
+	result, err := g.findRoute(ctx, base, quote, amount, sell, options)
+	return result, amount, err
+}
+
+// liquidityError chuyển ErrNoRoute của query amount thành *LiquidityError nếu
+// base và quote có kết nối qua các cạnh được phép dùng, trong giới hạn số
+// chặng của options. Chỉ kiểm tra kết nối, không tìm đường: lượng tối đa được
+// tính khi gọi LiquidityError.Max. Các trường hợp khác err được giữ nguyên.
+func (g *graph) liquidityError(base, quote string, amount decimal.Decimal, sell bool,
+	options QueryOptions, err error) error {
+	if !errors.Is(err, ErrNoRoute) || errors.Is(err, ErrInsufficientLiquidity) ||
+		!g.view(options).connected(base, quote, options.MaxHops) {
+		return err
+	}
+
+	options.PartialResult = false
+	return &LiquidityError{
+		Amount: amount,
+		Sell:   sell,
+		max: func(ctx context.Context) (decimal.Decimal, []string, error) {
+			maxAmount, err := g.maxAmount(ctx, base, quote, amount, sell, options)
+			if err != nil {
+				return decimal.Zero, nil, err
+			}
+			result, err := g.findRoute(ctx, base, quote, maxAmount, sell, options)
+			if err != nil {
+				return decimal.Zero, nil, err
+			}
+			return maxAmount, result.Route, nil
+		},
+	}
+}
+
+// maxAmount tìm amount lớn nhất mà findRoute tìm được route, bắt đầu từ hint:
+// nhân đôi hint tới khi không còn khả thi hoặc chia đôi hint tới khi khả thi,
+// sau đó binary search tới sai số tương đối maxAmountTolerance, xem bisect.
+//
+// Tính khả thi chỉ đơn điệu theo amount khi các cạnh không có phí cố định:
+// lượng token cần cho mỗi chặng tăng theo amount. Với Fee.Fixed > 0, amount
+// quá nhỏ không đủ trả phí nên cũng không khả thi, các amount khả thi của mỗi
+// route là một khoảng và của cả đồ thị là hợp các khoảng đó. Khi đó kết quả là
+// cận trên của khoảng khả thi đầu tiên gặp khi chia đôi, có thể nhỏ hơn lượng
+// tối đa thật sự, và ErrNoRoute được trả về nếu việc chia đôi bỏ qua mọi
+// khoảng khả thi (khoảng hẹp hơn một lần chia đôi, hoặc hint nhỏ hơn mọi
+// khoảng).
+//
+// Trả về ErrNoRoute nếu base và quote không kết nối hoặc không amount nào khả
+// thi, ErrUnlimitedLiquidity nếu vẫn khả thi sau maxDoublings lần nhân đôi.
+// Lỗi khác của findRoute (arbitrage loop, ctx kết thúc) được trả về ngay.
+func (g *graph) maxAmount(ctx context.Context, base, quote string,
+	hint decimal.Decimal, sell bool, options QueryOptions) (decimal.Decimal, error) {
+	if !g.view(options).connected(base, quote, options.MaxHops) {
+		return decimal.Zero, ErrNoRoute
+	}
+
+	feasible := func(amount decimal.Decimal) (bool, error) {
+		return g.feasible(ctx, base, quote, amount, sell, options)
+	}
+
+	two := decimal.NewFromInt(2)
+	low, high := decimal.Zero, hint
+	ok, err := feasible(hint)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if ok {
+		// Nhân đôi tới khi không khả thi
+		for i := 0; ok; i++ {
+			if i == maxDoublings {
+				return decimal.Zero, ErrUnlimitedLiquidity
+			}
+			low, high = high, high.Mul(two)
+			if ok, err = feasible(high); err != nil {
+				return decimal.Zero, err
+			}
+		}
+	} else {
+		// Chia đôi tới khi khả thi, high luôn không khả thi
+		for !ok {
+			low = high.QuoRound(two, decimal.RoundDown)
+			if !low.IsPositive() {
+				return decimal.Zero, ErrNoRoute
+			}
+			if ok, err = feasible(low); err != nil {
+				return decimal.Zero, err
+			}
+			if !ok {
+				high = low
+			}
+		}
+	}
+	return g.bisect(ctx, base, quote, low, high, sell, options)
+}
+
+// bisect binary search amount lớn nhất khả thi trong khoảng [low, high), với
+// low khả thi (hoặc bằng 0) và high không khả thi. Search dừng khi high - low
+// không quá maxAmountTolerance lần high, sau đó amount có ít chữ số thập phân
+// nhất trong khoảng (xem roundest) được thử thêm một lần để kết quả không có
+// các chữ số vô nghĩa, ví dụ 400 thay vì 399.99999982. Trả về 0 nếu không
+// amount dương nào khả thi.
+func (g *graph) bisect(ctx context.Context, base, quote string,
+	low, high decimal.Decimal, sell bool, options QueryOptions) (decimal.Decimal, error) {
+	two := decimal.NewFromInt(2)
+	tolerance := func() decimal.Decimal {
+		return decimal.Max(high.MulRound(maxAmountTolerance, decimal.RoundDown), decimal.Ulp)
+	}
+	for high.Sub(low).GreaterThan(tolerance()) {
+		middle := low.Add(high).QuoRound(two, decimal.RoundDown)
+		ok, err := g.feasible(ctx, base, quote, middle, sell, options)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if ok {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	if round := roundest(low, high); round.GreaterThan(low) {
+		ok, err := g.feasible(ctx, base, quote, round, sell, options)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if ok {
+			low = round
+		}
+	}
+	return low, nil
+}
+
+// roundest trả về amount có ít chữ số thập phân nhất trong khoảng [low, high),
+// với low < high: amount lớn nhất nhỏ hơn high với lần lượt 0, 1, 2, ... chữ
+// số thập phân, tới khi không nhỏ hơn low.
+func roundest(low, high decimal.Decimal) decimal.Decimal {
+	below := high.Sub(decimal.Ulp)
+	for places := int32(0); ; places++ {
+		if round := below.Round(places, decimal.RoundDown); round.GreaterThanOrEqual(low) {
+			return round
+		}
+	}
+}
+
+// feasible kiểm tra findRoute có tìm được route cho amount hay không, lỗi
+// khác ErrNoRoute được trả về.
+func (g *graph) feasible(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (bool, error) {
+	_, err := g.findRoute(ctx, base, quote, amount, sell, options)
+	if errors.Is(err, ErrNoRoute) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// connected kiểm tra có đường đi từ base tới quote qua các cạnh được phép dùng
+// với không quá maxHops cạnh (0 là không giới hạn), bỏ qua thanh khoản của các
+// cạnh.
+func (g *graph) connected(base, quote string, maxHops int) bool {
+	visited := map[string]bool{base: true}
+	layer := []string{base}
+	for hops := 0; len(layer) > 0; hops++ {
+		if slices.Contains(layer, quote) {
+			return true
+		}
+		if maxHops > 0 && hops == maxHops {
+			return false
+		}
+		var next []string
+		for _, token := range layer {
+			for edge := range g.outgoing(token) {
+				if !visited[edge.To()] {
+					visited[edge.To()] = true
+					next = append(next, edge.To())
+				}
+			}
+		}
+		layer = next
+	}
+	return false
+}
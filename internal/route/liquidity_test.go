@@ -0,0 +1,205 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func TestGraph_BestBidRoute_InsufficientLiquidity(t *testing.T) {
+	edge := newDepthTestEdge()
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	// Bid orders chỉ có tổng cộng 400 KNC
+	_, err := g.BestBidRoute("KNC", "USDT", d("500"))
+	var liqErr *LiquidityError
+	if !errors.As(err, &liqErr) {
+		t.Fatalf("BestBidRoute() error = %v, want *LiquidityError", err)
+	}
+	if !errors.Is(err, ErrInsufficientLiquidity) || !errors.Is(err, ErrNoRoute) {
+		t.Errorf("error %v should match ErrInsufficientLiquidity and ErrNoRoute", err)
+	}
+	maxAmount, maxRoute, maxErr := liqErr.Max(context.Background())
+	if maxErr != nil || !liqErr.Amount.Equal(d("500")) || !maxAmount.Equal(d("400")) ||
+		!slices.Equal(maxRoute, []string{"KNC", "USDT"}) || !liqErr.Sell {
+		t.Errorf("LiquidityError = %v, Max() = (%v, %v, %v), want 500, max 400 via KNC->USDT",
+			liqErr, maxAmount, maxRoute, maxErr)
+	}
+
+	// Với WithExactQuote, lượng tối đa là lượng USDT: 100 * 0.9 + 300 * 0.8
+	_, _, err = g.BestBidPrice("KNC", "USDT", d("500"), WithExactQuote())
+	if !errors.As(err, &liqErr) {
+		t.Fatalf("BestBidPrice(WithExactQuote) error = %v, want *LiquidityError", err)
+	}
+	if maxAmount, _, _ := liqErr.Max(context.Background()); !maxAmount.Equal(d("330")) {
+		t.Errorf("BestBidPrice(WithExactQuote) max = %v, want 330", maxAmount)
+	}
+
+	// Với WithMaxAmount, lượng vượt thanh khoản được giảm về lượng tối đa
+	got, err := g.BestBidRoute("KNC", "USDT", d("500"), WithMaxAmount())
+	if err != nil || !got.AmountIn.Equal(d("400")) || !slices.Equal(got.Route, []string{"KNC", "USDT"}) {
+		t.Errorf("BestBidRoute(500, WithMaxAmount) = %v %v, %v, want 400 via KNC->USDT",
+			got.AmountIn, got.Route, err)
+	}
+	got, err = g.BestBidRoute("KNC", "USDT", d("100"), WithMaxAmount())
+	if err != nil || !got.AmountIn.Equal(d("100")) {
+		t.Errorf("BestBidRoute(100, WithMaxAmount) = %v, %v, want 100", got.AmountIn, err)
+	}
+}
+
+func TestLiquidityError_Max_FixedFee(t *testing.T) {
+	edge := newDepthTestEdge()
+	edge.Fee = Fee{Fixed: d("5")}
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	// 1 KNC chỉ đổi được 0.9 USDT, không đủ trả phí cố định 5 USDT: không
+	// lượng nào nhỏ hơn giao dịch được
+	_, err := g.BestBidRoute("KNC", "USDT", d("1"))
+	var liqErr *LiquidityError
+	if !errors.As(err, &liqErr) {
+		t.Fatalf("BestBidRoute(1) error = %v, want *LiquidityError", err)
+	}
+	if _, _, err := liqErr.Max(context.Background()); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Max() error = %v, want ErrNoRoute", err)
+	}
+
+	if _, err := g.BestBidRoute("KNC", "USDT", d("500")); !errors.As(err, &liqErr) {
+		t.Fatalf("BestBidRoute(500) error = %v, want *LiquidityError", err)
+	}
+	if maxAmount, _, err := liqErr.Max(context.Background()); err != nil || !maxAmount.Equal(d("400")) {
+		t.Errorf("Max() = (%v, %v), want 400", maxAmount, err)
+	}
+}
+
+func TestGraph_MaxAmount(t *testing.T) {
+	kncUSDT := newDepthTestEdge()
+	// Chỉ mua được 0.25 ETH, tức bán được tối đa 90 USDT lấy ETH
+	ethUSDT := OrderEdge{
+		BaseToken:  "ETH",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("360"), Quantity: d("0.25")}},
+		BidOrders:  []Order{{Price: d("355"), Quantity: d("800")}},
+	}
+	g := NewGraphWithEdges([]Edge{
+		kncUSDT, kncUSDT.GetReverseEdge(),
+		ethUSDT, ethUSDT.GetReverseEdge(),
+	})
+
+	// amountOut chọn lượng token tối đa là AmountOut thay vì AmountIn
+	tests := []struct {
+		name      string
+		sell      bool
+		quote     string
+		opts      []QueryOption
+		amountOut bool
+		wantRoute []string
+		wantMin   string
+		wantMax   string
+	}{
+		{name: "Bid limited by first hop", sell: true, quote: "USDT",
+			wantRoute: []string{"KNC", "USDT"}, wantMin: "400", wantMax: "400"},
+		{name: "Ask limited by first hop", quote: "USDT", amountOut: true,
+			wantRoute: []string{"USDT", "KNC"}, wantMin: "350", wantMax: "350"},
+		// 100 KNC giá 0.9 cho đúng 90 USDT, phần dư rất nhỏ khớp ở giá 0.8 có
+		// thể bị làm tròn về 0 USDT
+		{name: "Bid limited by second hop", sell: true, quote: "ETH",
+			wantRoute: []string{"KNC", "USDT", "ETH"}, wantMin: "100", wantMax: "100.000000000001"},
+		{name: "Bid exact quote", sell: true, quote: "USDT", opts: []QueryOption{WithExactQuote()},
+			amountOut: true, wantRoute: []string{"KNC", "USDT"}, wantMin: "330", wantMax: "330"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := g.BestAskRoute
+			if tt.sell {
+				query = g.BestBidRoute
+			}
+			got, err := query("KNC", tt.quote, decimal.Zero, append(tt.opts, WithMaxAmount())...)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			amount := got.AmountIn
+			if tt.amountOut {
+				amount = got.AmountOut
+			}
+			if amount.LessThan(d(tt.wantMin)) || amount.GreaterThan(d(tt.wantMax)) {
+				t.Errorf("amount = %v, want between %s and %s", amount, tt.wantMin, tt.wantMax)
+			}
+			if !slices.Equal(got.Route, tt.wantRoute) {
+				t.Errorf("Route = %v, want %v", got.Route, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func TestGraph_MaxAmount_Errors(t *testing.T) {
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("0.9"), AskPrice: d("1.1")}
+	ethBTC := newDepthTestEdge()
+	ethBTC.BaseToken, ethBTC.QuoteToken = "ETH", "BTC"
+	g := NewGraphWithEdges([]Edge{kncUSDT, kncUSDT.GetReverseEdge(), ethBTC, ethBTC.GetReverseEdge()})
+
+	if _, err := g.BestBidRoute("KNC", "USDT", decimal.Zero, WithMaxAmount()); !errors.Is(err, ErrUnlimitedLiquidity) {
+		t.Errorf("BestBidRoute(KNC, USDT, WithMaxAmount) error = %v, want ErrUnlimitedLiquidity", err)
+	}
+
+	_, err := g.BestBidRoute("KNC", "ETH", decimal.Zero, WithMaxAmount())
+	if !errors.Is(err, ErrNoRoute) || errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("BestBidRoute(KNC, ETH, WithMaxAmount) error = %v, want ErrNoRoute only", err)
+	}
+
+	// Không kết nối, hoặc chỉ kết nối với nhiều chặng hơn MaxHops, thì không
+	// phải lỗi thiếu thanh khoản
+	_, err = g.BestBidRoute("KNC", "ETH", d("1"))
+	if !errors.Is(err, ErrNoRoute) || errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("BestBidRoute(KNC, ETH) error = %v, want ErrNoRoute only", err)
+	}
+	chain := NewGraphWithEdges([]Edge{kncUSDT, kncUSDT.GetReverseEdge(), ethBTC, ethBTC.GetReverseEdge(),
+		SimpleEdge{BaseToken: "USDT", QuoteToken: "ETH", BidPrice: d("0.0003"), AskPrice: d("0.00031")}})
+	_, err = chain.BestBidRoute("KNC", "ETH", d("1"), WithMaxHops(1))
+	if !errors.Is(err, ErrNoRoute) || errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("BestBidRoute(KNC, ETH, WithMaxHops(1)) error = %v, want ErrNoRoute only", err)
+	}
+}
+
+// countingFinder đếm số lần tìm đường của RouteFinder bên trong.
+type countingFinder struct {
+	RouteFinder
+	count *int
+}
+
+func (f countingFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	*f.count++
+	return f.RouteFinder.find(ctx, g, base, quote, amount, sell, maxHops)
+}
+
+func TestGraph_MaxAmount_Tolerance(t *testing.T) {
+	depth := d("123.456789123456789")
+	edge := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		AskOrders:  []Order{{Price: d("1.1"), Quantity: d("150")}},
+		BidOrders:  []Order{{Price: d("0.9"), Quantity: depth}},
+	}
+	g := NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+
+	var searches int
+	finder := countingFinder{RouteFinder: BellmanFord(), count: &searches}
+	got, err := g.BestBidRoute("KNC", "USDT", decimal.Zero, WithMaxAmount(), WithFinder(finder))
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithMaxAmount) error = %v", err)
+	}
+	if got.AmountIn.GreaterThan(depth) ||
+		got.AmountIn.LessThan(depth.Sub(depth.Mul(maxAmountTolerance))) {
+		t.Errorf("AmountIn = %v, want within relative %v below %v", got.AmountIn, maxAmountTolerance, depth)
+	}
+	// 7 lần nhân đôi từ 1 tới 128, khoảng 30 lần binary search tới sai số
+	// 1e-9, một lần thử amount tròn và một lần tìm route của kết quả
+	if searches > 45 {
+		t.Errorf("BestBidRoute(WithMaxAmount) ran %d searches, want at most 45", searches)
+	}
+}
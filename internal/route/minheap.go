@@ -1,8 +1,10 @@
 package route
 
+import "github.com/nkngn/kyber-homework/internal/decimal"
+
 type TokenInfo struct {
 	Token       string
-	MinRequired float64
+	MinRequired decimal.Decimal
 }
 
 // Heap (Min Heap dựa trên MinRequired)
@@ -15,7 +17,7 @@ func (h TokenMinHeap) Len() int {
 
 func (h TokenMinHeap) Less(i, j int) bool {
 	// Min Heap: phần tử có MinRequired nhỏ hơn sẽ lên đầu
-	return h[i].MinRequired < h[j].MinRequired
+	return h[i].MinRequired.LessThan(h[j].MinRequired)
 }
 
 func (h TokenMinHeap) Swap(i, j int) {
@@ -33,3 +35,14 @@ func (h *TokenMinHeap) Pop() any {
 	*h = old[0 : n-1]
 	return item
 }
+
+// TokenMaxHeap là max heap dựa trên MinRequired, khi đó MinRequired là lượng
+// token tối đa thu được tại đỉnh. Sử dụng cho Uniform Cost Search khi bán.
+type TokenMaxHeap struct {
+	TokenMinHeap
+}
+
+func (h TokenMaxHeap) Less(i, j int) bool {
+	// Max Heap: phần tử có MinRequired lớn hơn sẽ lên đầu
+	return h.TokenMinHeap[i].MinRequired.GreaterThan(h.TokenMinHeap[j].MinRequired)
+}
@@ -10,10 +10,10 @@ func TestTokenMinHeap_PushAndPop(t *testing.T) {
 	heap.Init(h)
 
 	tokens := []TokenInfo{
-		{"A", 5.0},
-		{"B", 2.0},
-		{"C", 8.0},
-		{"D", 1.0},
+		{"A", d("5")},
+		{"B", d("2")},
+		{"C", d("8")},
+		{"D", d("1")},
 	}
 
 	for _, token := range tokens {
@@ -21,15 +21,15 @@ func TestTokenMinHeap_PushAndPop(t *testing.T) {
 	}
 
 	expectedOrder := []TokenInfo{
-		{"D", 1.0},
-		{"B", 2.0},
-		{"A", 5.0},
-		{"C", 8.0},
+		{"D", d("1")},
+		{"B", d("2")},
+		{"A", d("5")},
+		{"C", d("8")},
 	}
 
 	for i, expected := range expectedOrder {
 		item := heap.Pop(h).(TokenInfo)
-		if item != expected {
+		if item.Token != expected.Token || !item.MinRequired.Equal(expected.MinRequired) {
 			t.Errorf("Pop %d: got %+v, want %+v", i, item, expected)
 		}
 	}
@@ -41,8 +41,8 @@ func TestTokenMinHeap_Len(t *testing.T) {
 	if h.Len() != 0 {
 		t.Errorf("Expected length 0, got %d", h.Len())
 	}
-	heap.Push(h, TokenInfo{"A", 1.0})
-	heap.Push(h, TokenInfo{"B", 2.0})
+	heap.Push(h, TokenInfo{"A", d("1")})
+	heap.Push(h, TokenInfo{"B", d("2")})
 	if h.Len() != 2 {
 		t.Errorf("Expected length 2, got %d", h.Len())
 	}
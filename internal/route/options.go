@@ -0,0 +1,291 @@
+package route
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// QueryOptions là các tùy chọn của một route query.
+//   - Context: context của query, nil là context.Background(). Tìm đường dừng
+//     lại khi context kết thúc, xem WithContext
+//   - CycleResilient: khi phát hiện arbitrage loop, vẫn trả về đường đi đơn
+//     (không lặp token) tốt nhất từ base đến quote thay vì trả về lỗi, các
+//     chu trình phát hiện được ghi vào RouteResult.Warnings
+//   - CycleTolerance: bỏ qua các chu trình có lợi nhuận (Profit - 1) không
+//     vượt quá giá trị này, ví dụ 0.001 để bỏ qua các chu trình lời dưới
+//     0.1%, thường nhỏ hơn phí giao dịch thực tế. Chu trình bị bỏ qua không
+//     gây lỗi và không xuất hiện trong Warnings
+//   - Finder: thuật toán tìm đường, mặc định BellmanFord, hoặc BranchAndBound
+//     với đồ thị không quá ExactSearchLimit token
+//   - ExactSearchLimit: số token tối đa của đồ thị (sau khi lọc) để dùng
+//     BranchAndBound thay cho BellmanFord khi không chọn Finder, 0 là luôn
+//     dùng BellmanFord. Xem WithExactSearchLimit
+//   - Race: các thuật toán chạy song song, kết quả tốt nhất được chọn và
+//     Finder bị bỏ qua. Xem WithRace
+//   - RaceTimeout: thời gian tối đa chờ các thuật toán trong Race, 0 là chờ
+//     tới khi tất cả kết thúc
+//   - PartialResult: khi context của query kết thúc trước khi tìm xong, trả
+//     về route tốt nhất tìm được tới lúc đó với RouteResult.Partial = true
+//     thay vì lỗi của context
+//   - MaxHops: số cạnh (số chặng giao dịch) tối đa của route, 0 là không
+//     giới hạn
+//   - ExcludedTokens: route không đi qua các token này
+//   - ExcludedPairs: route không dùng các trading pair này, theo cả hai chiều.
+//     Venue rỗng nghĩa là pair trên mọi exchange
+//   - RequiredTokens: route phải đi qua tất cả các token này, theo thứ tự bất
+//     kỳ
+//   - AllowedVenues: nếu khác rỗng, route chỉ dùng cạnh của các exchange này
+//   - ExactQuote: amount của query là lượng quote token thay vì base token,
+//     xem WithExactQuote
+//   - TopRoutes: số route khác nhau tốt nhất cần tìm, kể cả route tốt nhất,
+//     0 là chỉ tìm route tốt nhất, xem WithTopRoutes
+//   - MaxAmount: giao dịch lượng token lớn nhất có thể, không quá amount, thay
+//     vì trả về lỗi khi không đủ thanh khoản, xem WithMaxAmount
+//
+// Các tùy chọn lọc token, pair và venue được áp dụng khi duyệt cạnh trong lúc
+// tìm đường, đồ thị không bị sao chép hay thay đổi.
+type QueryOptions struct {
+	Context          context.Context
+	CycleResilient   bool
+	CycleTolerance   decimal.Decimal
+	Finder           RouteFinder
+	ExactSearchLimit int
+	Race             []RouteFinder
+	RaceTimeout      time.Duration
+	PartialResult    bool
+	MaxHops          int
+	ExcludedTokens   []string
+	ExcludedPairs    []PairKey
+	RequiredTokens   []string
+	AllowedVenues    []string
+	ExactQuote       bool
+	TopRoutes        int
+	MaxAmount        bool
+}
+
+// QueryOption thay đổi một tùy chọn của QueryOptions.
+type QueryOption func(*QueryOptions)
+
+// WithContext đặt context của query: tìm đường dừng lại và trả về ctx.Err()
+// khi ctx bị hủy hoặc hết deadline, hoặc route tốt nhất tìm được tới lúc đó
+// với RouteResult.Partial = true nếu dùng WithPartialResult. BestBidPrice và
+// BestAskPrice bỏ qua WithPartialResult vì kết quả không đánh dấu được là
+// chưa hoàn chỉnh.
+func WithContext(ctx context.Context) QueryOption {
+	return func(o *QueryOptions) {
+		o.Context = ctx
+	}
+}
+
+// WithCycleResilience bật chế độ tìm đường bỏ qua arbitrage loop, xem
+// QueryOptions.CycleResilient.
+func WithCycleResilience() QueryOption {
+	return func(o *QueryOptions) {
+		o.CycleResilient = true
+	}
+}
+
+// WithCycleTolerance đặt ngưỡng lợi nhuận của các chu trình được bỏ qua, xem
+// QueryOptions.CycleTolerance.
+func WithCycleTolerance(tolerance decimal.Decimal) QueryOption {
+	return func(o *QueryOptions) {
+		o.CycleTolerance = tolerance
+	}
+}
+
+// WithFinder chọn thuật toán tìm đường, xem RouteFinder.
+func WithFinder(finder RouteFinder) QueryOption {
+	return func(o *QueryOptions) {
+		o.Finder = finder
+	}
+}
+
+// WithExactSearchLimit dùng BranchAndBound làm thuật toán mặc định khi đồ thị
+// có không quá limit token, ví dụ 10. Với đồ thị nhỏ, tìm route tối ưu chính
+// xác trên mọi đường đi đơn đủ nhanh, trong khi BellmanFord chỉ là heuristic
+// với cạnh có giá phụ thuộc amount. BranchAndBound không phát hiện arbitrage
+// loop. Không có tác dụng khi đã chọn thuật toán qua WithFinder hoặc WithRace.
+func WithExactSearchLimit(limit int) QueryOption {
+	return func(o *QueryOptions) {
+		o.ExactSearchLimit = max(limit, 0)
+	}
+}
+
+// WithRace chạy song song các finders và trả về kết quả tốt nhất trong số các
+// thuật toán kết thúc trước timeout (0 là không giới hạn). Khi hết thời gian,
+// các thuật toán chưa kết thúc bị dừng lại. Không truyền finders thì chạy
+// BellmanFord, UCS, SPFA và DFS với số cạnh mặc định.
+func WithRace(timeout time.Duration, finders ...RouteFinder) QueryOption {
+	return func(o *QueryOptions) {
+		if len(finders) == 0 {
+			finders = []RouteFinder{BellmanFord(), UCS(), SPFA(), DFS(0)}
+		}
+		o.Race = finders
+		o.RaceTimeout = timeout
+	}
+}
+
+// WithPartialResult cho phép trả về kết quả chưa hoàn chỉnh khi context kết
+// thúc, xem QueryOptions.PartialResult.
+func WithPartialResult() QueryOption {
+	return func(o *QueryOptions) {
+		o.PartialResult = true
+	}
+}
+
+// WithMaxHops giới hạn route có tối đa maxHops cạnh, ví dụ 3 để chỉ nhận các
+// route có tối đa 3 chặng giao dịch. Với Bellman-Ford (mặc định), kết quả là
+// route tốt nhất trong các route thỏa mãn giới hạn, xem boundedBellmanFord.
+// maxHops không dương nghĩa là không giới hạn.
+func WithMaxHops(maxHops int) QueryOption {
+	return func(o *QueryOptions) {
+		o.MaxHops = max(maxHops, 0)
+	}
+}
+
+// WithExcludedTokens thêm các token mà route không được đi qua, ví dụ USDC sau
+// một sự cố depeg.
+func WithExcludedTokens(tokens ...string) QueryOption {
+	return func(o *QueryOptions) {
+		o.ExcludedTokens = append(o.ExcludedTokens, tokens...)
+	}
+}
+
+// WithExcludedPairs thêm các trading pair mà route không được dùng, xem
+// QueryOptions.ExcludedPairs.
+func WithExcludedPairs(pairs ...PairKey) QueryOption {
+	return func(o *QueryOptions) {
+		o.ExcludedPairs = append(o.ExcludedPairs, pairs...)
+	}
+}
+
+// WithRequiredTokens thêm các token trung gian mà route phải đi qua, ví dụ
+// chỉ đi qua một stablecoin được chỉ định.
+func WithRequiredTokens(tokens ...string) QueryOption {
+	return func(o *QueryOptions) {
+		o.RequiredTokens = append(o.RequiredTokens, tokens...)
+	}
+}
+
+// WithAllowedVenues giới hạn route chỉ dùng cạnh của các exchange venues.
+func WithAllowedVenues(venues ...string) QueryOption {
+	return func(o *QueryOptions) {
+		o.AllowedVenues = append(o.AllowedVenues, venues...)
+	}
+}
+
+// WithExactQuote đổi amount của query thành lượng quote token. Mặc định amount
+// là lượng base token: bid là exact input (bán đúng amount base token), ask
+// là exact output (mua đúng amount base token). Với WithExactQuote:
+//   - bid là exact output: tìm lượng base token ít nhất cần bán để thu được
+//     amount quote token, ví dụ cần bán bao nhiêu KNC để nhận 0.5 ETH
+//   - ask là exact input: tìm lượng base token nhiều nhất mua được khi trả
+//     amount quote token, ví dụ trả 1000 USDT mua được bao nhiêu KNC
+//
+// Giá trả về vẫn là tỷ lệ quote/base. Chỉ các cạnh hỗ trợ mô phỏng ngược
+// (SimpleEdge, OrderEdge) được dùng. Do làm tròn, lượng quote token thực tế
+// có thể nhiều hơn amount (bid) hoặc ít hơn amount (ask), xem
+// RouteResult.AmountIn và AmountOut.
+func WithExactQuote() QueryOption {
+	return func(o *QueryOptions) {
+		o.ExactQuote = true
+	}
+}
+
+// WithTopRoutes tìm tối đa k route khác nhau tốt nhất thay vì chỉ route tốt
+// nhất: BestBidRoute và BestAskRoute trả về route tốt nhất, các route tiếp
+// theo từ tốt tới kém nằm trong RouteResult.Alternatives. Các route khác dùng
+// làm phương án dự phòng, ví dụ khi exchange của route tốt nhất không ổn định.
+// Hai route khác nhau nếu khác nhau ít nhất một token hoặc exchange của một
+// chặng, xem topPaths. k < 1 làm query trả về ErrInvalidRouteCount. Không có
+// tác dụng với các query khác.
+func WithTopRoutes(k int) QueryOption {
+	return func(o *QueryOptions) {
+		if k < 1 {
+			k = -1
+		}
+		o.TopRoutes = k
+	}
+}
+
+// WithMaxAmount cho BestBidRoute và BestAskRoute giao dịch lượng token lớn
+// nhất có thể nhưng không quá amount: nếu không route nào đủ thanh khoản cho
+// amount, kết quả là route của lượng tối đa (xem LiquidityError.Max) thay vì
+// *LiquidityError. amount bằng 0 là không giới hạn, khi đó lượng tối đa được
+// tìm từ một đơn vị token và lỗi là ErrUnlimitedLiquidity nếu có route không
+// giới hạn thanh khoản. Với WithExactQuote, lượng tối đa là lượng quote token.
+// Lượng tối đa được tìm bằng binary search trên amount, mỗi bước là một lần
+// tìm đường, nên chậm hơn khoảng vài chục lần khi amount không đủ thanh khoản.
+func WithMaxAmount() QueryOption {
+	return func(o *QueryOptions) {
+		o.MaxAmount = true
+	}
+}
+
+// newQueryOptions áp dụng lần lượt các opts lên tùy chọn mặc định.
+func newQueryOptions(opts []QueryOption) QueryOptions {
+	options := QueryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// context trả về Context của query, context.Background() nếu không đặt.
+func (o QueryOptions) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// ignores kiểm tra chu trình có lợi nhuận không vượt quá CycleTolerance hay
+// không. Chu trình không mô phỏng lại được (Profit bằng 0) không bị bỏ qua.
+func (o QueryOptions) ignores(cycle *ArbitrageError) bool {
+	if !cycle.Profit.IsPositive() {
+		return false
+	}
+	return cycle.Profit.Sub(decimal.One).LessThanOrEqual(o.CycleTolerance)
+}
+
+// edgeFilter trả về hàm kiểm tra một cạnh có được dùng theo ExcludedTokens,
+// ExcludedPairs và AllowedVenues hay không, nil nếu không có tùy chọn lọc nào.
+func (o QueryOptions) edgeFilter() func(Edge) bool {
+	if len(o.ExcludedTokens) == 0 && len(o.ExcludedPairs) == 0 &&
+		len(o.AllowedVenues) == 0 {
+		return nil
+	}
+
+	excluded := make(map[string]bool, len(o.ExcludedTokens))
+	for _, token := range o.ExcludedTokens {
+		excluded[token] = true
+	}
+	venues := make(map[string]bool, len(o.AllowedVenues))
+	for _, venue := range o.AllowedVenues {
+		venues[venue] = true
+	}
+	pairs := slices.Clone(o.ExcludedPairs)
+
+	return func(e Edge) bool {
+		if excluded[e.From()] || excluded[e.To()] {
+			return false
+		}
+		venue := VenueOf(e)
+		if len(venues) > 0 && !venues[venue] {
+			return false
+		}
+		for _, pair := range pairs {
+			if pair.Venue != "" && pair.Venue != venue {
+				continue
+			}
+			if (pair.From == e.From() && pair.To == e.To()) ||
+				(pair.From == e.To() && pair.To == e.From()) {
+				return false
+			}
+		}
+		return true
+	}
+}
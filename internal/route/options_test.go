@@ -0,0 +1,153 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// newOptionsTestGraph tạo đồ thị KNC/ETH với ba route: trực tiếp trên kraken
+// (giá tốt nhất), qua USDT và qua USDC trên binance (qua USDC kém nhất).
+func newOptionsTestGraph() Graph {
+	pair := func(base, quote, bid, ask, venue string) SimpleEdge {
+		return SimpleEdge{BaseToken: base, QuoteToken: quote, BidPrice: d(bid), AskPrice: d(ask), Venue: venue}
+	}
+	pairs := []SimpleEdge{
+		pair("KNC", "ETH", "0.0026", "0.0027", "kraken"),
+		pair("KNC", "USDT", "1", "1.1", "binance"),
+		pair("ETH", "USDT", "400", "410", "binance"),
+		pair("KNC", "USDC", "0.9", "1", "binance"),
+		pair("ETH", "USDC", "380", "410", "binance"),
+	}
+	edges := make([]Edge, 0, len(pairs)*2)
+	for _, p := range pairs {
+		edges = append(edges, p, p.GetReverseEdge())
+	}
+	return NewGraphWithEdges(edges)
+}
+
+func TestGraph_BestBidPrice_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []QueryOption
+		want []string
+	}{
+		{
+			name: "No options",
+			want: []string{"KNC", "ETH"},
+		},
+		{
+			name: "Allowed venues",
+			opts: []QueryOption{WithAllowedVenues("binance")},
+			want: []string{"KNC", "USDT", "ETH"},
+		},
+		{
+			name: "Excluded tokens",
+			opts: []QueryOption{WithAllowedVenues("binance"), WithExcludedTokens("USDT")},
+			want: []string{"KNC", "USDC", "ETH"},
+		},
+		{
+			name: "Excluded pairs in both directions",
+			opts: []QueryOption{WithExcludedPairs(
+				PairKey{From: "ETH", To: "KNC"},
+				PairKey{From: "USDT", To: "KNC", Venue: "binance"},
+			)},
+			want: []string{"KNC", "USDC", "ETH"},
+		},
+		{
+			name: "Excluded pair on another venue",
+			opts: []QueryOption{WithExcludedPairs(PairKey{From: "KNC", To: "ETH", Venue: "binance"})},
+			want: []string{"KNC", "ETH"},
+		},
+		{
+			name: "Required tokens",
+			opts: []QueryOption{WithRequiredTokens("USDC")},
+			want: []string{"KNC", "USDC", "ETH"},
+		},
+		{
+			// Chỉ thứ tự USDT trước ETH có route tới USDC
+			name: "Required tokens in any order",
+			opts: []QueryOption{WithRequiredTokens("ETH", "USDT", "KNC")},
+			want: []string{"KNC", "USDT", "ETH", "USDC"},
+		},
+	}
+
+	g := newOptionsTestGraph()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := tt.want[len(tt.want)-1]
+			_, got, err := g.BestBidPrice("KNC", quote, d("100"), tt.opts...)
+			if err != nil {
+				t.Fatalf("BestBidPrice() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("route = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_BestAskRoute_Options(t *testing.T) {
+	g := newOptionsTestGraph()
+
+	got, err := g.BestAskRoute("KNC", "ETH", d("100"),
+		WithAllowedVenues("binance"), WithExcludedTokens("USDT"))
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+	if want := []string{"ETH", "USDC", "KNC"}; !slices.Equal(got.Route, want) {
+		t.Errorf("Route = %v, want %v", got.Route, want)
+	}
+	for _, leg := range got.Legs {
+		if leg.Venue != "binance" {
+			t.Errorf("leg %s->%s venue = %q, want binance", leg.From, leg.To, leg.Venue)
+		}
+	}
+
+	_, err = g.BestAskRoute("KNC", "ETH", d("100"), WithRequiredTokens("BTC"))
+	if !errors.Is(err, ErrNoRoute) {
+		t.Errorf("BestAskRoute() error = %v, want ErrNoRoute", err)
+	}
+}
+
+// skippingFinder tìm đường giống RouteFinder, nhưng với đoạn xuất phát từ
+// from, prevs của quote được thay bằng edge nên truy vết ngược không về tới
+// from.
+type skippingFinder struct {
+	RouteFinder
+	from string
+	edge Edge
+}
+
+func (f skippingFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal, map[string]Edge, error) {
+	values, prevs, err := f.RouteFinder.find(ctx, g, base, quote, amount, sell, maxHops)
+	if err == nil && base == f.from {
+		prevs[quote] = f.edge
+	}
+	return values, prevs, err
+}
+
+func TestGraph_BestBidRoute_RequiredTokensValidatesSegments(t *testing.T) {
+	g := newOptionsTestGraph()
+	var kncETH Edge
+	for _, edge := range g.Neighbors("KNC") {
+		if edge.To() == "ETH" {
+			kncETH = edge
+		}
+	}
+
+	// Đoạn USDT->ETH trả về cạnh KNC->ETH, nếu không kiểm tra thì route ghép
+	// lại bỏ qua USDT
+	got, err := g.BestBidRoute("KNC", "ETH", d("100"), WithRequiredTokens("USDT"),
+		WithFinder(skippingFinder{RouteFinder: BellmanFord(), from: "USDT", edge: kncETH}))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if want := []string{"KNC", "USDT", "ETH"}; !slices.Equal(got.Route, want) || !got.Repaired {
+		t.Errorf("Route = %v (repaired %v), want repaired %v", got.Route, got.Repaired, want)
+	}
+}
@@ -0,0 +1,164 @@
+package route
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// oracleRoute là route tốt nhất tìm được bằng cách duyệt hết mọi đường đi đơn,
+// dùng làm chuẩn để kiểm tra các thuật toán tìm đường.
+type oracleRoute struct {
+	edges []Edge
+	value decimal.Decimal
+}
+
+// simplePaths liệt kê mọi đường đi đơn từ base tới quote có tối đa maxHops
+// cạnh (0 là không giới hạn), không cắt tỉa nhánh nào.
+func simplePaths(g *graph, base, quote string, maxHops int) [][]Edge {
+	var paths [][]Edge
+	visited := map[string]bool{base: true}
+	var visit func(token string, path []Edge)
+	visit = func(token string, path []Edge) {
+		if token == quote {
+			paths = append(paths, append([]Edge(nil), path...))
+			return
+		}
+		if maxHops > 0 && len(path) == maxHops {
+			return
+		}
+		for _, edge := range g.edges.from(token) {
+			if visited[edge.To()] {
+				continue
+			}
+			visited[edge.To()] = true
+			visit(edge.To(), append(path, edge))
+			visited[edge.To()] = false
+		}
+	}
+	visit(base, nil)
+	return paths
+}
+
+// bruteForceBest mô phỏng amount qua từng đường đi đơn của simplePaths và trả
+// về route tốt nhất: nhận về nhiều quote token nhất khi sell, trả ít quote
+// token nhất khi mua. false nếu không có route khả thi.
+func bruteForceBest(g *graph, base, quote string, amount decimal.Decimal,
+	sell bool, maxHops int) (oracleRoute, bool) {
+	var best oracleRoute
+	found := false
+	for _, path := range simplePaths(g, base, quote, maxHops) {
+		value, feasible := simulatePath(path, amount, sell)
+		if !feasible || (sell && !value.IsPositive()) {
+			continue
+		}
+		if !found || (sell && value.GreaterThan(best.value)) ||
+			(!sell && value.LessThan(best.value)) {
+			best, found = oracleRoute{edges: path, value: value}, true
+		}
+	}
+	return best, found
+}
+
+// simulatePath bán (sell = true) hoặc mua amount lần lượt qua các cạnh của
+// path, trả về lượng token ở cuối path.
+func simulatePath(path []Edge, amount decimal.Decimal, sell bool) (decimal.Decimal, bool) {
+	value := amount
+	for _, edge := range path {
+		var feasible bool
+		if value, feasible = simulate(edge, value, sell); !feasible {
+			return decimal.Zero, false
+		}
+	}
+	return value, true
+}
+
+// pathEdges trả về các cạnh nối lần lượt các token của path, mỗi cặp token có
+// tối đa một cạnh như đồ thị của randomGraph. false nếu thiếu cạnh.
+func pathEdges(g *graph, path []string) ([]Edge, bool) {
+	edges := make([]Edge, 0, len(path))
+	for i := 1; i < len(path); i++ {
+		index := slices.IndexFunc(g.edges.from(path[i-1]), func(e Edge) bool {
+			return e.To() == path[i]
+		})
+		if index < 0 {
+			return nil, false
+		}
+		edges = append(edges, g.edges.from(path[i-1])[index])
+	}
+	return edges, true
+}
+
+// randomGraph tạo đồ thị ngẫu nhiên có tokens token T0, T1, ... và tối đa
+// pairs trading pair, mỗi pair có cạnh hai chiều. Mỗi token có một giá tham
+// chiếu, giá mid của pair A/B là tỷ lệ giá tham chiếu của A và B, bid thấp
+// hơn và ask cao hơn mid nên đồ thị không có arbitrage loop. Pair là
+// SimpleEdge hoặc OrderEdge có 1 tới 3 mức giá, một số pair có phí theo bps.
+//
+// Với unitPrice, mọi token có cùng giá tham chiếu nên lượng token không bao
+// giờ tăng lên qua một cạnh, điều kiện để ucs cho kết quả tối ưu.
+func randomGraph(rng *rand.Rand, tokens, pairs int, unitPrice bool) (*graph, []string) {
+	names := make([]string, tokens)
+	prices := make([]decimal.Decimal, tokens)
+	for i := range names {
+		names[i] = fmt.Sprintf("T%d", i)
+		prices[i] = decimal.One
+		if !unitPrice {
+			prices[i] = decimal.NewFromInt(int64(rng.IntN(1000)+1)).
+				QuoRound(decimal.NewFromInt(100), decimal.RoundDown)
+		}
+	}
+
+	g := &graph{}
+	used := map[[2]int]bool{}
+	for range pairs {
+		i, j := rng.IntN(tokens), rng.IntN(tokens)
+		if i == j || used[[2]int{i, j}] || used[[2]int{j, i}] {
+			continue
+		}
+		used[[2]int{i, j}] = true
+
+		pair := randomPair(rng, names[i], names[j], prices[i].QuoRound(prices[j], decimal.RoundDown))
+		g.AddEdge(pair)
+		g.AddEdge(pair.GetReverseEdge())
+	}
+	return g, names
+}
+
+// randomPair tạo trading pair base/quote quanh giá mid, các mức giá làm tròn
+// 8 chữ số thập phân theo chiều bất lợi cho người giao dịch.
+func randomPair(rng *rand.Rand, base, quote string, mid decimal.Decimal) Edge {
+	bps := func(n int) decimal.Decimal {
+		return decimal.NewFromInt(int64(n)).QuoRound(bpsDenominator, decimal.RoundDown)
+	}
+	level := func(spread int, sell bool) decimal.Decimal {
+		if sell {
+			return mid.MulRound(decimal.One.Sub(bps(spread)), decimal.RoundDown).
+				Round(8, decimal.RoundDown)
+		}
+		return mid.MulRound(decimal.One.Add(bps(spread)), decimal.RoundUp).
+			Round(8, decimal.RoundUp)
+	}
+
+	var fee Fee
+	if rng.IntN(3) == 0 {
+		fee = Fee{Bps: decimal.NewFromInt(int64(rng.IntN(30) + 1)), Side: FeeSide(rng.IntN(2))}
+	}
+	spread := rng.IntN(100) + 5
+	if rng.IntN(2) == 0 {
+		return SimpleEdge{BaseToken: base, QuoteToken: quote, BidPrice: level(spread, true),
+			AskPrice: level(spread, false), Fee: fee}
+	}
+
+	edge := OrderEdge{BaseToken: base, QuoteToken: quote, Fee: fee}
+	for range rng.IntN(3) + 1 {
+		edge.BidOrders = append(edge.BidOrders, Order{Price: level(spread, true),
+			Quantity: decimal.NewFromInt(int64(rng.IntN(200) + 1))})
+		edge.AskOrders = append(edge.AskOrders, Order{Price: level(spread, false),
+			Quantity: decimal.NewFromInt(int64(rng.IntN(200) + 1))})
+		spread += rng.IntN(200) + 1
+	}
+	return edge
+}
@@ -1,8 +1,15 @@
 package route
 
+import (
+	"iter"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
 type Order struct {
-	Price    float64
-	Quantity float64
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
 }
 
 type OrderEdge struct {
@@ -10,34 +17,88 @@ type OrderEdge struct {
 	QuoteToken string
 	BidOrders  []Order
 	AskOrders  []Order
+
+	// BidLevels, AskLevels thay cho BidOrders, AskOrders khi khác nil, để cạnh
+	// dùng chung các chunk price level của order book thay vì sao chép cả
+	// order book, xem Levels.
+	BidLevels Levels
+	AskLevels Levels
+
+	// Precisions dùng để làm tròn lượng quote token sau khi mô phỏng, nil
+	// nghĩa là giữ nguyên precision decimal.Scale.
+	Precisions Precisions
+
+	// Fee là phí taker khi giao dịch qua cạnh này.
+	Fee Fee
+
+	// Venue là tên exchange (sàn) của trading pair, rỗng nếu không phân biệt.
+	Venue string
+
+	// Reversed là true nếu cạnh đi ngược chiều trading pair của exchange, tức
+	// trading pair là QuoteToken/BaseToken. GetReverseEdge đảo giá trị này.
+	Reversed bool
 }
 
 func (e OrderEdge) From() string { return e.BaseToken }
 func (e OrderEdge) To() string   { return e.QuoteToken }
 
+// orders trả về bid orders (sell = true) hoặc ask orders của cạnh, lấy từ
+// BidLevels hoặc AskLevels nếu khác nil.
+func (e OrderEdge) orders(sell bool) iter.Seq[Order] {
+	orders, levels := e.AskOrders, e.AskLevels
+	if sell {
+		orders, levels = e.BidOrders, e.BidLevels
+	}
+	if levels != nil {
+		return levels.All()
+	}
+	return slices.Values(orders)
+}
+
 // SimulateSell mô phỏng việc bán amount base token qua OrderEdge này.
 // Đối với OrderEdge, thực hiện walk qua bid orders xem có bán được
-// hết amount hay không?
+// hết amount hay không? Phí (Fee) được trừ vào base hoặc quote token tùy
+// theo Fee.Side.
 // Kết quả trả về:
-//   - acquiredQuote: Trả về lượng quote token thu được nếu bán được hết
-//     amount. Trả về 0 order book không đủ depth để fill hết amount.
+//   - acquiredQuote: Trả về lượng quote token thu được sau phí nếu bán được
+//     hết amount, làm tròn xuống theo precision của quote token. Trả về 0
+//     order book không đủ depth để fill hết amount.
 //   - isFeasible: true nếu order book không đủ depth để fill hết amount
 //     và ngược lại.
-func (e OrderEdge) SimulateSell(amount float64) (float64, bool) {
-	acquiredQuoteTotal := 0.0
-	for _, order := range e.BidOrders {
-		if order.Quantity < amount {
-			acquiredQuoteTotal += order.Price * order.Quantity
-			amount -= order.Quantity
+func (e OrderEdge) SimulateSell(amount decimal.Decimal) (decimal.Decimal, bool) {
+	acquiredQuote, _, isFeasible := e.simulateSellFee(amount)
+	return acquiredQuote, isFeasible
+}
+
+// simulateSellFee giống SimulateSell, trả về thêm lượng phí đã trả.
+func (e OrderEdge) simulateSellFee(amount decimal.Decimal) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	acquiredQuote, fee, isFeasible := e.Fee.sell(amount, e.fillBids)
+	if !isFeasible {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return e.Precisions.roundReceived(e.QuoteToken, acquiredQuote), fee, true
+}
+
+// fillBids walk qua bid orders để bán amount base token, trả về lượng quote
+// token thu được trước phí và false nếu order book không đủ depth.
+func (e OrderEdge) fillBids(amount decimal.Decimal) (decimal.Decimal, bool) {
+	acquiredQuoteTotal := decimal.Zero
+	for order := range e.orders(true) {
+		if order.Quantity.LessThan(amount) {
+			acquiredQuoteTotal = acquiredQuoteTotal.Add(
+				order.Price.MulRound(order.Quantity, decimal.RoundDown))
+			amount = amount.Sub(order.Quantity)
 		} else {
-			acquiredQuoteTotal += order.Price * amount
-			amount = 0
+			acquiredQuoteTotal = acquiredQuoteTotal.Add(
+				order.Price.MulRound(amount, decimal.RoundDown))
+			amount = decimal.Zero
 			break
 		}
 	}
 
-	if amount > 0 {
-		return 0.0, false
+	if amount.IsPositive() {
+		return decimal.Zero, false
 	}
 
 	return acquiredQuoteTotal, true
@@ -45,31 +106,144 @@ func (e OrderEdge) SimulateSell(amount float64) (float64, bool) {
 
 // SimulateBuy mô phỏng việc mua amount base token qua OrderEdge này.
 // Đối với OrderEdge, thực hiện walk qua ask orders để kiểm tra có đủ
-// thanh khoản (liquidity) để mua hết amount hay không.
+// thanh khoản (liquidity) để mua hết amount hay không. Phí (Fee) được cộng
+// vào quote token phải trả, hoặc trừ vào base token mua được tùy theo
+// Fee.Side.
 // Kết quả trả về:
-//   - requiredQuote: Lượng quote token cần thiết để mua được hết amount base token.
+//   - requiredQuote: Lượng quote token cần thiết (đã gồm phí) để nhận được
+//     hết amount base token, làm tròn lên theo precision của quote token.
 //     Trả về 0 nếu order book không đủ depth để fill hết amount.
 //   - isFeasible: true nếu order book đủ depth để fill hết amount, false nếu không.
-func (e OrderEdge) SimulateBuy(amount float64) (float64, bool) {
-	requiredQuoteTotal := 0.0
-	for _, order := range e.AskOrders {
-		if order.Quantity < amount {
-			amount -= order.Quantity
-			requiredQuoteTotal += order.Price * order.Quantity
+func (e OrderEdge) SimulateBuy(amount decimal.Decimal) (decimal.Decimal, bool) {
+	requiredQuote, _, isFeasible := e.simulateBuyFee(amount)
+	return requiredQuote, isFeasible
+}
+
+// simulateBuyFee giống SimulateBuy, trả về thêm lượng phí đã trả.
+func (e OrderEdge) simulateBuyFee(amount decimal.Decimal) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	requiredQuote, fee, isFeasible := e.Fee.buy(amount, e.fillAsks)
+	if !isFeasible {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return e.Precisions.roundPaid(e.QuoteToken, requiredQuote), fee, true
+}
+
+// feeToken trả về token dùng để trả phí của cạnh.
+func (e OrderEdge) feeToken() string {
+	return e.Fee.token(e.BaseToken, e.QuoteToken)
+}
+
+// venue trả về exchange của cạnh.
+func (e OrderEdge) venue() string { return e.Venue }
+
+// reversed cho biết cạnh có đi ngược chiều trading pair hay không.
+func (e OrderEdge) reversed() bool { return e.Reversed }
+
+// fillAsks walk qua ask orders để mua amount base token, trả về lượng quote
+// token phải trả trước phí và false nếu order book không đủ depth.
+func (e OrderEdge) fillAsks(amount decimal.Decimal) (decimal.Decimal, bool) {
+	requiredQuoteTotal := decimal.Zero
+	for order := range e.orders(false) {
+		if order.Quantity.LessThan(amount) {
+			amount = amount.Sub(order.Quantity)
+			requiredQuoteTotal = requiredQuoteTotal.Add(
+				order.Price.MulRound(order.Quantity, decimal.RoundUp))
 		} else {
-			requiredQuoteTotal += order.Price * amount
-			amount = 0
+			requiredQuoteTotal = requiredQuoteTotal.Add(
+				order.Price.MulRound(amount, decimal.RoundUp))
+			amount = decimal.Zero
 			break
 		}
 	}
 
-	if amount > 0 {
-		return 0.0, false
+	if amount.IsPositive() {
+		return decimal.Zero, false
 	}
 
 	return requiredQuoteTotal, true
 }
 
+// SimulateSellExactOut là phép ngược của SimulateSell: walk qua bid orders để
+// tìm lượng base token nhỏ nhất cần bán để thu được ít nhất amount quote token
+// sau phí.
+// Kết quả trả về:
+//   - requiredBase: lượng base token cần bán, làm tròn lên. Trả về 0 nếu
+//     order book không đủ depth để thu được amount quote token.
+//   - isFeasible: true nếu order book đủ depth, false nếu không.
+func (e OrderEdge) SimulateSellExactOut(amount decimal.Decimal) (decimal.Decimal, bool) {
+	if !amount.IsPositive() {
+		return decimal.Zero, false
+	}
+	return e.Fee.sellExactOut(e.Precisions.roundPaid(e.QuoteToken, amount), e.unfillBids)
+}
+
+// unfillBids là phép ngược của fillBids: trả về lượng base token nhỏ nhất cần
+// khớp với bid orders để thu được ít nhất amount quote token trước phí và
+// false nếu order book không đủ depth.
+func (e OrderEdge) unfillBids(amount decimal.Decimal) (decimal.Decimal, bool) {
+	requiredBaseTotal := decimal.Zero
+	for order := range e.orders(true) {
+		acquiredQuote := order.Price.MulRound(order.Quantity, decimal.RoundDown)
+		if acquiredQuote.LessThan(amount) {
+			requiredBaseTotal = requiredBaseTotal.Add(order.Quantity)
+			amount = amount.Sub(acquiredQuote)
+		} else {
+			requiredBaseTotal = requiredBaseTotal.Add(
+				amount.QuoRound(order.Price, decimal.RoundUp))
+			amount = decimal.Zero
+			break
+		}
+	}
+
+	if amount.IsPositive() {
+		return decimal.Zero, false
+	}
+
+	return requiredBaseTotal, true
+}
+
+// SimulateBuyExactIn là phép ngược của SimulateBuy: walk qua ask orders để
+// tìm lượng base token lớn nhất mua được sau phí khi trả tối đa amount quote
+// token (đã gồm phí).
+// Kết quả trả về:
+//   - acquiredBase: lượng base token mua được, làm tròn xuống. Trả về 0 nếu
+//     order book không đủ depth để dùng hết amount quote token.
+//   - isFeasible: true nếu order book đủ depth, false nếu không.
+func (e OrderEdge) SimulateBuyExactIn(amount decimal.Decimal) (decimal.Decimal, bool) {
+	acquiredBase, isFeasible := e.Fee.buyExactIn(
+		e.Precisions.roundReceived(e.QuoteToken, amount), e.unfillAsks)
+	if !isFeasible || !acquiredBase.IsPositive() {
+		return decimal.Zero, false
+	}
+	return acquiredBase, true
+}
+
+// unfillAsks là phép ngược của fillAsks: trả về lượng base token lớn nhất
+// khớp được với ask orders khi trả tối đa amount quote token trước phí và
+// false nếu order book không đủ depth để dùng hết amount.
+func (e OrderEdge) unfillAsks(amount decimal.Decimal) (decimal.Decimal, bool) {
+	acquiredBaseTotal := decimal.Zero
+	for order := range e.orders(false) {
+		requiredQuote := order.Price.MulRound(order.Quantity, decimal.RoundUp)
+		if requiredQuote.LessThanOrEqual(amount) {
+			acquiredBaseTotal = acquiredBaseTotal.Add(order.Quantity)
+			amount = amount.Sub(requiredQuote)
+		} else {
+			acquiredBaseTotal = acquiredBaseTotal.Add(
+				amount.QuoRound(order.Price, decimal.RoundDown))
+			amount = decimal.Zero
+			break
+		}
+	}
+
+	if amount.IsPositive() {
+		return decimal.Zero, false
+	}
+
+	return acquiredBaseTotal, true
+}
+
 // GetReverseEdge trả về một cạnh OrderEdge đảo ngược chiều giao dịch so với
 // cạnh hiện tại. Các lệnh ask và bid của cạnh đảo ngược được tính toán lại:
 //   - AskOrders mới được tạo từ BidOrders cũ, với giá và khối lượng đảo nghịch:
@@ -78,28 +252,157 @@ func (e OrderEdge) SimulateBuy(amount float64) (float64, bool) {
 //   - BidOrders mới được tạo từ AskOrders cũ, với công thức tương tự.
 //
 // Điều này đảm bảo khi đảo chiều, order book vẫn phản ánh đúng thanh khoản
-// và giá trị chuyển đổi giữa hai token.
+// và giá trị chuyển đổi giữa hai token. Giá ask mới được làm tròn lên, giá bid
+// mới và khối lượng mới được làm tròn xuống, để sai số làm tròn không bao giờ
+// tạo ra arbitrage loop giả. Các order có giá không dương bị bỏ qua. Phí được
+// giữ nguyên và vẫn trả bằng cùng token như cạnh gốc.
 func (e OrderEdge) GetReverseEdge() Edge {
-	reverseEdge := OrderEdge{
+	reversed := e.ReverseWithOrders(nil, nil)
+	reversed.AskOrders, reversed.AskLevels = reverseSide(e.BidOrders, e.BidLevels, true)
+	reversed.BidOrders, reversed.BidLevels = reverseSide(e.AskOrders, e.AskLevels, false)
+	return reversed
+}
+
+// reverseSide đảo các order của một phía của cạnh gốc theo GetReverseEdge,
+// bid là true nếu đó là phía bids. Nếu levels khác nil, kết quả là Levels có
+// cùng cách chia chunk, ngược lại là slice các order đảo ngược.
+func reverseSide(orders []Order, levels Levels, bid bool) ([]Order, Levels) {
+	if levels == nil {
+		return reverseOrders(orders, bid), nil
+	}
+	reversed := make(Levels, len(levels))
+	for i, chunk := range levels {
+		reversed[i] = reverseOrders(chunk, bid)
+	}
+	return nil, reversed
+}
+
+// reverseOrders đảo từng order có giá dương của orders bằng ReverseOrder.
+func reverseOrders(orders []Order, bid bool) []Order {
+	reversed := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		if order.Price.IsPositive() {
+			reversed = append(reversed, ReverseOrder(order, bid))
+		}
+	}
+	return reversed
+}
+
+// ReverseOrder trả về order của cạnh đảo ngược tạo từ một order có giá dương
+// của cạnh gốc, theo công thức của GetReverseEdge. bid là true nếu order là
+// bid của cạnh gốc, tức trở thành ask của cạnh đảo ngược.
+func ReverseOrder(order Order, bid bool) Order {
+	rounding := decimal.RoundDown
+	if bid {
+		rounding = decimal.RoundUp
+	}
+	return Order{
+		Price:    inversePrice(order.Price, rounding),
+		Quantity: order.Price.MulRound(order.Quantity, decimal.RoundDown),
+	}
+}
+
+// ReverseWithOrders giống GetReverseEdge nhưng dùng askOrders và bidOrders đã
+// được đảo sẵn bằng ReverseOrder, theo thứ tự của BidOrders và AskOrders. Nơi
+// giữ order book có thể cập nhật các order đảo ngược theo từng thay đổi thay vì
+// đảo lại toàn bộ order book mỗi lần tạo cạnh.
+func (e OrderEdge) ReverseWithOrders(askOrders, bidOrders []Order) OrderEdge {
+	return OrderEdge{
 		BaseToken:  e.QuoteToken,
 		QuoteToken: e.BaseToken,
-		AskOrders:  make([]Order, 0, len(e.BidOrders)),
-		BidOrders:  make([]Order, 0, len(e.AskOrders)),
+		AskOrders:  askOrders,
+		BidOrders:  bidOrders,
+		Precisions: e.Precisions,
+		Fee:        e.Fee.reverse(),
+		Venue:      e.Venue,
+		Reversed:   !e.Reversed,
 	}
+}
 
-	for _, order := range e.BidOrders {
-		reverseEdge.AskOrders = append(reverseEdge.AskOrders, Order{
-			Price:    1.0 / order.Price,
-			Quantity: order.Price * order.Quantity,
-		})
+// ReverseWithLevels giống ReverseWithOrders nhưng các order đảo ngược được chia
+// thành chunk theo cùng cách chia của BidLevels và AskLevels, xem Levels.
+func (e OrderEdge) ReverseWithLevels(askLevels, bidLevels Levels) OrderEdge {
+	reversed := e.ReverseWithOrders(nil, nil)
+	reversed.AskLevels, reversed.BidLevels = askLevels, bidLevels
+	return reversed
+}
+
+// afterSell trả về OrderEdge còn lại sau khi đã bán amount base token, tức
+// là các bid orders đã bị khớp hết sẽ bị loại bỏ, order khớp một phần được
+// giảm khối lượng. Order book gốc không bị thay đổi.
+func (e OrderEdge) afterSell(amount decimal.Decimal) Edge {
+	e.BidOrders = consumeOrders(e.orders(true), e.Fee.sellBookAmount(amount))
+	e.BidLevels = nil
+	return e
+}
+
+// afterBuy trả về OrderEdge còn lại sau khi đã mua amount base token, tương
+// tự afterSell nhưng thực hiện trên ask orders.
+func (e OrderEdge) afterBuy(amount decimal.Decimal) Edge {
+	e.AskOrders = consumeOrders(e.orders(false), e.Fee.buyBookAmount(amount))
+	e.AskLevels = nil
+	return e
+}
+
+// afterReverse trả về OrderEdge còn lại khi cạnh đảo ngược của nó (cùng order
+// book, xem GetReverseEdge) đã được khớp từ before thành after bằng việc bán
+// (sell = true) hoặc mua. Bán qua cạnh đảo ngược khớp bid orders của nó, tức
+// ask orders của e, và ngược lại. Các order tương ứng với order đã bị khớp
+// hết bị loại bỏ, order tương ứng với order khớp một phần được giảm khối
+// lượng theo cùng tỷ lệ, làm tròn xuống. Nếu các order của hai cạnh không
+// tương ứng một-một, e được tạo lại từ after bằng GetReverseEdge.
+func (e OrderEdge) afterReverse(before, after Edge, sell bool) Edge {
+	b, ok := before.(OrderEdge)
+	a, ok2 := after.(OrderEdge)
+	if !ok || !ok2 {
+		return e
+	}
+	orders := slices.Collect(e.orders(!sell))
+	consumed := slices.Collect(b.orders(sell))
+	if len(orders) != len(consumed) {
+		return a.GetReverseEdge()
+	}
+	remaining := consumeAligned(orders, consumed, slices.Collect(a.orders(sell)))
+	if sell {
+		e.AskOrders, e.AskLevels = remaining, nil
+	} else {
+		e.BidOrders, e.BidLevels = remaining, nil
 	}
+	return e
+}
 
-	for _, order := range e.AskOrders {
-		reverseEdge.BidOrders = append(reverseEdge.BidOrders, Order{
-			Price:    1.0 / order.Price,
-			Quantity: order.Price * order.Quantity,
-		})
+// consumeAligned trả về các order còn lại của orders khi các order tương ứng
+// (cùng vị trí) before đã bị khớp thành after bằng consumeOrders, xem
+// afterReverse.
+func consumeAligned(orders, before, after []Order) []Order {
+	filled := len(before) - len(after)
+	remaining := slices.Clone(orders[filled:])
+	if len(remaining) > 0 && !after[0].Quantity.Equal(before[filled].Quantity) {
+		remaining[0].Quantity = remaining[0].Quantity.
+			MulRound(after[0].Quantity, decimal.RoundDown).
+			QuoRound(before[filled].Quantity, decimal.RoundDown)
 	}
+	return remaining
+}
 
-	return reverseEdge
+// consumeOrders trả về danh sách orders còn lại sau khi khớp amount base
+// token từ đầu danh sách. Slice kết quả là bản sao, không dùng chung mảng
+// với orders truyền vào.
+func consumeOrders(orders iter.Seq[Order], amount decimal.Decimal) []Order {
+	remaining := []Order{}
+	for order := range orders {
+		switch {
+		case !amount.IsPositive():
+			remaining = append(remaining, order)
+		case order.Quantity.LessThanOrEqual(amount):
+			amount = amount.Sub(order.Quantity)
+		default:
+			remaining = append(remaining, Order{
+				Price:    order.Price,
+				Quantity: order.Quantity.Sub(amount),
+			})
+			amount = decimal.Zero
+		}
+	}
+	return remaining
 }
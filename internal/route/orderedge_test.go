@@ -2,70 +2,77 @@ package route
 
 import (
 	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
 )
 
+// d là hàm tiện ích để tạo Decimal từ hằng số trong test.
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
 func Test_SimulateSell(t *testing.T) {
 	tests := []struct {
 		name   string
 		orders []Order
-		amount float64
-		want   float64
+		amount decimal.Decimal
+		want   decimal.Decimal
 		wantOk bool
 	}{
 		{
 			name: "Sell less than first order",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 1.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("1.5"), Quantity: d("5")},
 			},
-			amount: 5,
-			want:   10, // 5 * 2
+			amount: d("5"),
+			want:   d("10"), // 5 * 2
 			wantOk: true,
 		},
 		{
 			name: "Sell exactly first order",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 1.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("1.5"), Quantity: d("5")},
 			},
-			amount: 10,
-			want:   20, // 10 * 2
+			amount: d("10"),
+			want:   d("20"), // 10 * 2
 			wantOk: true,
 		},
 		{
 			name: "Sell across multiple orders",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 1.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("1.5"), Quantity: d("5")},
 			},
-			amount: 12,   // needs both orders
-			want:   23.0, // 10 * 2 + 2 * 1.5
+			amount: d("12"),   // needs both orders
+			want:   d("23.0"), // 10 * 2 + 2 * 1.5
 			wantOk: true,
 		},
 		{
 			name: "Sell more than available",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 1.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("1.5"), Quantity: d("5")},
 			},
-			amount: 30, // only 15 available
-			want:   0.0,
+			amount: d("30"), // only 15 available
+			want:   d("0.0"),
 			wantOk: false,
 		},
 		{
 			name:   "No orders",
 			orders: []Order{},
-			amount: 10,
-			want:   0.0,
+			amount: d("10"),
+			want:   d("0.0"),
 			wantOk: false,
 		},
 		{
 			name: "Zero amount",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
+				{Price: d("2"), Quantity: d("10")},
 			},
-			amount: 0,
-			want:   0,
+			amount: d("0"),
+			want:   d("0"),
 			wantOk: true,
 		},
 	}
@@ -76,7 +83,7 @@ func Test_SimulateSell(t *testing.T) {
 				BidOrders: tt.orders,
 			}
 			got, ok := edge.SimulateSell(tt.amount)
-			if got != tt.want || ok != tt.wantOk {
+			if !got.Equal(tt.want) || ok != tt.wantOk {
 				t.Errorf("SimulateSell(%v) = (%v, %v), want (%v, %v)", tt.amount, got, ok, tt.want, tt.wantOk)
 			}
 		})
@@ -87,64 +94,64 @@ func Test_SimulateBuy(t *testing.T) {
 	tests := []struct {
 		name   string
 		orders []Order
-		amount float64
-		want   float64
+		amount decimal.Decimal
+		want   decimal.Decimal
 		wantOk bool
 	}{
 		{
 			name: "Buy less than first order",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 2.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("2.5"), Quantity: d("5")},
 			},
-			amount: 5,
-			want:   10, // 5 * 2
+			amount: d("5"),
+			want:   d("10"), // 5 * 2
 			wantOk: true,
 		},
 		{
 			name: "Buy exactly first order",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 2.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("2.5"), Quantity: d("5")},
 			},
-			amount: 10,
-			want:   20, // 10 * 2
+			amount: d("10"),
+			want:   d("20"), // 10 * 2
 			wantOk: true,
 		},
 		{
 			name: "Buy across multiple orders",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 2.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("2.5"), Quantity: d("5")},
 			},
-			amount: 12,           // needs both orders
-			want:   10*2 + 2*2.5, // 20 + 5 = 25
+			amount: d("12"), // needs both orders
+			want:   d("25"), // 10*2 + 2*2.5 = 20 + 5
 			wantOk: true,
 		},
 		{
 			name: "Buy more than available",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
-				{Price: 2.5, Quantity: 5},
+				{Price: d("2"), Quantity: d("10")},
+				{Price: d("2.5"), Quantity: d("5")},
 			},
-			amount: 20, // only 15 available
-			want:   0.0,
+			amount: d("20"), // only 15 available
+			want:   d("0.0"),
 			wantOk: false,
 		},
 		{
 			name:   "No orders",
 			orders: []Order{},
-			amount: 10,
-			want:   0.0,
+			amount: d("10"),
+			want:   d("0.0"),
 			wantOk: false,
 		},
 		{
 			name: "Zero amount",
 			orders: []Order{
-				{Price: 2, Quantity: 10},
+				{Price: d("2"), Quantity: d("10")},
 			},
-			amount: 0,
-			want:   0,
+			amount: d("0"),
+			want:   d("0"),
 			wantOk: true,
 		},
 	}
@@ -155,9 +162,59 @@ func Test_SimulateBuy(t *testing.T) {
 				AskOrders: tt.orders,
 			}
 			got, ok := edge.SimulateBuy(tt.amount)
-			if got != tt.want || ok != tt.wantOk {
+			if !got.Equal(tt.want) || ok != tt.wantOk {
 				t.Errorf("SimulateBuy(%v) = (%v, %v), want (%v, %v)", tt.amount, got, ok, tt.want, tt.wantOk)
 			}
 		})
 	}
 }
+
+func Test_GetReverseEdge_NoRoundingArbitrage(t *testing.T) {
+	// 1/6 và 1/3 không biểu diễn chính xác được, đi vòng A->B->A không được
+	// phép thu về nhiều hơn lượng ban đầu
+	edge := OrderEdge{
+		BaseToken:  "A",
+		QuoteToken: "B",
+		BidOrders:  []Order{{Price: d("6"), Quantity: d("100")}},
+		AskOrders:  []Order{{Price: d("6"), Quantity: d("100")}},
+	}
+	reverse := edge.GetReverseEdge()
+
+	acquired, ok := edge.SimulateSell(d("3"))
+	if !ok {
+		t.Fatalf("SimulateSell() not feasible")
+	}
+	back, ok := reverse.SimulateSell(acquired)
+	if !ok {
+		t.Fatalf("reverse SimulateSell() not feasible")
+	}
+	if back.GreaterThan(d("3")) {
+		t.Errorf("round trip sell returned %v, want <= 3", back)
+	}
+
+	required, ok := reverse.SimulateBuy(d("1"))
+	if !ok {
+		t.Fatalf("reverse SimulateBuy() not feasible")
+	}
+	if paid, _ := edge.SimulateBuy(required); paid.LessThan(d("1")) {
+		t.Errorf("round trip buy paid %v, want >= 1", paid)
+	}
+}
+
+func Test_Simulate_Precisions(t *testing.T) {
+	edge := OrderEdge{
+		BaseToken:  "KNC",
+		QuoteToken: "USDT",
+		BidOrders:  []Order{{Price: d("0.123456"), Quantity: d("100")}},
+		AskOrders:  []Order{{Price: d("0.123456"), Quantity: d("100")}},
+		Precisions: Precisions{"USDT": 2},
+	}
+
+	// 10 * 0.123456 = 1.23456
+	if got, _ := edge.SimulateSell(d("10")); !got.Equal(d("1.23")) {
+		t.Errorf("SimulateSell() = %v, want 1.23 (rounded down)", got)
+	}
+	if got, _ := edge.SimulateBuy(d("10")); !got.Equal(d("1.24")) {
+		t.Errorf("SimulateBuy() = %v, want 1.24 (rounded up)", got)
+	}
+}
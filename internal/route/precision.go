@@ -0,0 +1,29 @@
+package route
+
+import "github.com/nkngn/kyber-homework/internal/decimal"
+
+// Precisions lưu số chữ số thập phân (decimals) của từng token, dùng để làm
+// tròn lượng token sau mỗi lần mô phỏng giao dịch. Token không có trong map
+// giữ nguyên precision decimal.Scale.
+//
+// Quy tắc làm tròn luôn bất lợi cho người giao dịch để kết quả không bao giờ
+// lạc quan hơn thực tế:
+//   - Lượng token nhận về được làm tròn xuống (decimal.RoundDown)
+//   - Lượng token phải trả được làm tròn lên (decimal.RoundUp)
+type Precisions map[string]int32
+
+// roundReceived làm tròn xuống lượng token nhận về theo precision của token.
+func (p Precisions) roundReceived(token string, amount decimal.Decimal) decimal.Decimal {
+	if places, ok := p[token]; ok {
+		return amount.Round(places, decimal.RoundDown)
+	}
+	return amount
+}
+
+// roundPaid làm tròn lên lượng token phải trả theo precision của token.
+func (p Precisions) roundPaid(token string, amount decimal.Decimal) decimal.Decimal {
+	if places, ok := p[token]; ok {
+		return amount.Round(places, decimal.RoundUp)
+	}
+	return amount
+}
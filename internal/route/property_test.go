@@ -0,0 +1,223 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// propertySeeds là số đồ thị ngẫu nhiên được kiểm tra trong mỗi property test.
+const propertySeeds = 200
+
+// propertyAmounts là các amount của query, tăng dần, các amount lớn vượt quá
+// depth của order book ở nhiều route.
+var propertyAmounts = []string{"1", "10", "50", "100", "250", "500", "2000"}
+
+// roundingTolerance là sai số tương đối cho phép khi so sánh giá của các
+// amount khác nhau: lượng token được làm tròn tới decimal.Scale chữ số ở mỗi
+// chặng nên giá của amount nhỏ có thể kém hơn vài đơn vị ở chữ số cuối.
+var roundingTolerance = d("0.000000000001")
+
+// randomQuery là một query ngẫu nhiên trên đồ thị của randomGraph.
+type randomQuery struct {
+	g           *graph
+	base, quote string
+	amount      decimal.Decimal
+}
+
+// forEachRandomQuery chạy check với một query ngẫu nhiên trên mỗi đồ thị có 3
+// tới 6 token. Seed được dùng làm tên subtest để tái hiện lỗi.
+func forEachRandomQuery(t *testing.T, unitPrice bool, check func(t *testing.T, q randomQuery)) {
+	t.Helper()
+	for seed := range uint64(propertySeeds) {
+		rng := rand.New(rand.NewPCG(seed, 0))
+		tokens := rng.IntN(4) + 3
+		g, names := randomGraph(rng, tokens, 2*tokens, unitPrice)
+		base := names[rng.IntN(tokens)]
+		quote := names[(slices.Index(names, base)+rng.IntN(tokens-1)+1)%tokens]
+		amount := d(propertyAmounts[rng.IntN(len(propertyAmounts))])
+
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			check(t, randomQuery{g: g, base: base, quote: quote, amount: amount})
+		})
+	}
+}
+
+// oraclePrice trả về giá của route tốt nhất theo bruteForceBest, làm tròn như
+// BestBidPrice (sell = true) và BestAskPrice.
+func oraclePrice(q randomQuery, sell bool) (decimal.Decimal, bool) {
+	best, ok := bruteForceBest(q.g, q.base, q.quote, q.amount, sell, 0)
+	if !ok {
+		return decimal.Zero, false
+	}
+	if sell {
+		return best.value.QuoRound(q.amount, decimal.RoundDown), true
+	}
+	return best.value.QuoRound(q.amount, decimal.RoundUp), true
+}
+
+func TestGraph_BestPrice_MatchesOracle(t *testing.T) {
+	finders := []RouteFinder{BellmanFord(), BranchAndBound(0)}
+
+	forEachRandomQuery(t, false, func(t *testing.T, q randomQuery) {
+		for _, sell := range []bool{true, false} {
+			want, feasible := oraclePrice(q, sell)
+			for _, finder := range finders {
+				best := q.g.BestAskPrice
+				if sell {
+					best = q.g.BestBidPrice
+				}
+				got, path, err := best(q.base, q.quote, q.amount, WithFinder(finder))
+				if !feasible {
+					if !errors.Is(err, ErrNoRoute) {
+						t.Errorf("%s sell=%v: error = %v, want ErrNoRoute", finder.Name(), sell, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s sell=%v: error = %v, want price %s", finder.Name(), sell, err, want)
+				}
+				if !got.Equal(want) {
+					t.Errorf("%s sell=%v: price = %s %v, want %s", finder.Name(), sell, got, path, want)
+				}
+			}
+		}
+	})
+}
+
+func TestGraph_UCS_MatchesOracle(t *testing.T) {
+	ctx := context.Background()
+
+	for _, unitPrice := range []bool{true, false} {
+		t.Run(fmt.Sprintf("unit=%v", unitPrice), func(t *testing.T) {
+			forEachRandomQuery(t, unitPrice, func(t *testing.T, q randomQuery) {
+				for _, sell := range []bool{true, false} {
+					best, feasible := bruteForceBest(q.g, q.base, q.quote, q.amount, sell, 0)
+					values, prevs, err := q.g.ucs(ctx, q.base, q.quote, q.amount, sell, 0)
+					if !feasible {
+						if err == nil {
+							t.Errorf("sell=%v: ucs found %s, want no route", sell, values[q.quote])
+						}
+						continue
+					}
+					if err != nil {
+						// ucs có thể bỏ lỡ route khả thi duy nhất
+						if unitPrice {
+							t.Errorf("sell=%v: ucs error = %v, want %s", sell, err, best.value)
+						}
+						continue
+					}
+
+					got := values[q.quote]
+					edges, ok := getEdges(prevs, q.base, q.quote)
+					if replayed, _ := simulatePath(edges, q.amount, sell); !ok || !replayed.Equal(got) {
+						t.Errorf("sell=%v: ucs route %v does not replay to %s",
+							sell, getPath(prevs, q.base, q.quote), got)
+					}
+					// Khi lượng token không tăng qua cạnh nào, ucs tối ưu. Ngược
+					// lại, ucs không được tốt hơn route tối ưu
+					if unitPrice && !got.Equal(best.value) {
+						t.Errorf("sell=%v: ucs = %s, want %s", sell, got, best.value)
+					}
+					if (sell && got.GreaterThan(best.value)) || (!sell && got.LessThan(best.value)) {
+						t.Errorf("sell=%v: ucs = %s better than oracle %s", sell, got, best.value)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestGraph_BestPrice_MonotoneInAmount(t *testing.T) {
+	forEachRandomQuery(t, false, func(t *testing.T, q randomQuery) {
+		for _, sell := range []bool{true, false} {
+			best := q.g.BestAskPrice
+			if sell {
+				best = q.g.BestBidPrice
+			}
+
+			// Bán hoặc mua nhiều hơn không bao giờ được giá tốt hơn, và khi
+			// một amount không khả thi thì các amount lớn hơn cũng vậy
+			var previous decimal.Decimal
+			exhausted := false
+			for i, amount := range propertyAmounts {
+				price, _, err := best(q.base, q.quote, d(amount))
+				if err != nil {
+					if !errors.Is(err, ErrNoRoute) {
+						t.Fatalf("sell=%v amount=%s: error = %v", sell, amount, err)
+					}
+					exhausted = true
+					continue
+				}
+				if exhausted {
+					t.Errorf("sell=%v amount=%s: found price %s after a smaller amount failed",
+						sell, amount, price)
+				}
+				slack := previous.Mul(roundingTolerance)
+				if i > 0 && !exhausted && ((sell && price.GreaterThan(previous.Add(slack))) ||
+					(!sell && price.LessThan(previous.Sub(slack)))) {
+					t.Errorf("sell=%v amount=%s: price = %s, better than %s at %s",
+						sell, amount, price, previous, propertyAmounts[i-1])
+				}
+				previous = price
+			}
+		}
+	})
+}
+
+func TestGraph_BestRoute_Replays(t *testing.T) {
+	forEachRandomQuery(t, false, func(t *testing.T, q randomQuery) {
+		for _, sell := range []bool{true, false} {
+			bestPrice, bestRoute := q.g.BestAskPrice, q.g.BestAskRoute
+			if sell {
+				bestPrice, bestRoute = q.g.BestBidPrice, q.g.BestBidRoute
+			}
+
+			price, path, err := bestPrice(q.base, q.quote, q.amount)
+			if err != nil {
+				continue
+			}
+			if !sell {
+				path = slices.Clone(path)
+				slices.Reverse(path)
+			}
+			edges, ok := pathEdges(q.g, path)
+			value, feasible := simulatePath(edges, q.amount, sell)
+			replayed := value.QuoRound(q.amount, decimal.RoundDown)
+			if !sell {
+				replayed = value.QuoRound(q.amount, decimal.RoundUp)
+			}
+			if !ok || !feasible || !replayed.Equal(price) {
+				t.Errorf("sell=%v: route %v replays to %s, reported %s", sell, path, replayed, price)
+			}
+
+			result, err := bestRoute(q.base, q.quote, q.amount)
+			if err != nil {
+				t.Fatalf("sell=%v: route error = %v, price error = nil", sell, err)
+			}
+			if !result.Price.Equal(price) {
+				t.Errorf("sell=%v: route price = %s, want %s", sell, result.Price, price)
+			}
+			routeEdges := make([]Edge, 0, len(result.Legs))
+			for _, leg := range result.Legs {
+				routeEdges = append(routeEdges, leg.Edge)
+			}
+			if !sell {
+				slices.Reverse(routeEdges)
+			}
+			value, feasible = simulatePath(routeEdges, q.amount, sell)
+			want := result.AmountOut
+			if !sell {
+				want = result.AmountIn
+			}
+			if !feasible || !value.Equal(want) {
+				t.Errorf("sell=%v: legs replay to %s, reported %s", sell, value, want)
+			}
+		}
+	})
+}
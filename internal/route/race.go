@@ -0,0 +1,125 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ErrRaceTimeout được trả về khi không thuật toán nào trong WithRace kết thúc
+// trước timeout.
+var ErrRaceTimeout = errors.New("no route found before race deadline")
+
+// raceEntry là kết quả của một thuật toán trong cuộc đua, index là vị trí của
+// thuật toán trong QueryOptions.Race.
+type raceEntry struct {
+	index  int
+	result searchResult
+	err    error
+}
+
+// race chạy song song các thuật toán của options.Race trên cùng snapshot, mỗi
+// thuật toán một goroutine, và chọn kết quả tốt nhất tại quote (lớn nhất khi
+// sell, nhỏ nhất khi mua). Kết quả của mỗi thuật toán được kiểm tra và sửa
+// bằng validatedEdges trước khi so sánh, nên thuật toán báo sai lượng token
+// không thắng được nhờ con số sai đó; kết quả không sửa được coi như không
+// tìm được route. Hai kết quả bằng nhau thì kết quả không phải sửa thắng, sau
+// đó tới thuật toán kết thúc trước.
+//
+// Cuộc đua kết thúc khi tất cả thuật toán trả về, hết options.RaceTimeout
+// hoặc ctx kết thúc. Khi đó context của các thuật toán bị hủy (done channel
+// được đóng) để các thuật toán còn chạy dừng lại, kết quả của chúng bị bỏ qua.
+// Nếu chưa có thuật toán nào tìm được route, trả về ErrRaceTimeout hoặc
+// ctx.Err().
+//
+// Nếu một thuật toán phát hiện arbitrage loop (hoặc lỗi khác ErrNoRoute), lỗi
+// đó được trả về kể cả khi thuật toán khác tìm được route, vì route của các
+// thuật toán không phát hiện arbitrage loop (UCS, DFS) không còn đáng tin.
+// Nhiều lỗi thì chọn lỗi của thuật toán đứng trước trong options.Race.
+func (g *graph) race(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (searchResult, error) {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffer đủ cho tất cả thuật toán để goroutine của thuật toán về sau
+	// không bị chặn khi cuộc đua đã kết thúc
+	// race thuộc searchDirect nên bỏ qua options.RequiredTokens, kể cả khi
+	// kiểm tra kết quả
+	direct := options
+	direct.RequiredTokens = nil
+	entries := make(chan raceEntry, len(options.Race))
+	for i, finder := range options.Race {
+		go func() {
+			result, err := g.searchWith(raceCtx, finder, base, quote, amount, sell,
+				direct)
+			if err == nil {
+				_, err = g.validatedEdges(raceCtx, base, quote, amount, sell, direct, &result)
+			}
+			entries <- raceEntry{index: i, result: result, err: err}
+		}()
+	}
+
+	var deadline <-chan time.Time
+	if options.RaceTimeout > 0 {
+		timer := time.NewTimer(options.RaceTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// err là lỗi khác ErrNoRoute của thuật toán đứng trước nhất, noRoute là
+	// ErrNoRoute hoặc lỗi kiểm tra route nếu có thuật toán không tìm được
+	// route. Lỗi của context chỉ
+	// xảy ra khi cuộc đua đã kết thúc nên được bỏ qua
+	var (
+		best     searchResult
+		found    bool
+		errIndex = len(options.Race)
+		err      error
+		noRoute  error
+	)
+collect:
+	for range options.Race {
+		select {
+		case <-deadline:
+			break collect
+		case <-ctx.Done():
+			break collect
+		case entry := <-entries:
+			switch {
+			case isContextError(entry.err):
+				continue
+			case errors.Is(entry.err, ErrNoRoute) || errors.Is(entry.err, ErrInvalidRoute):
+				noRoute = entry.err
+				continue
+			case entry.err != nil:
+				if entry.index < errIndex {
+					errIndex, err = entry.index, entry.err
+				}
+				continue
+			}
+
+			value := entry.result.values[quote]
+			current := best.values[quote]
+			if !found || (sell && value.GreaterThan(current)) ||
+				(!sell && value.LessThan(current)) ||
+				(value.Equal(current) && best.repaired && !entry.result.repaired) {
+				best, found = entry.result, true
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		return searchResult{}, err
+	case found:
+		return best, nil
+	case ctx.Err() != nil:
+		return searchResult{}, ctx.Err()
+	case noRoute != nil:
+		return searchResult{}, noRoute
+	default:
+		return searchResult{}, ErrRaceTimeout
+	}
+}
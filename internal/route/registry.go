@@ -0,0 +1,239 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+var (
+	ErrUnknownExchange = errors.New("unknown exchange")
+	ErrExchangeLoading = errors.New("exchange is still loading")
+)
+
+// Registry quản lý đồ thị của từng exchange và tìm route tốt nhất trên tất cả
+// các exchange. Registry an toàn khi dùng đồng thời.
+type Registry struct {
+	mu        sync.RWMutex
+	exchanges map[string]*exchangeGraph
+}
+
+// exchangeGraph là đồ thị của một exchange, ready là false khi exchange còn
+// đang tải order book.
+type exchangeGraph struct {
+	graph Graph
+	ready bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{exchanges: map[string]*exchangeGraph{}}
+}
+
+// Register thêm (hoặc thay thế) đồ thị của exchange. Exchange mới ở trạng thái
+// đang tải và không được dùng cho query cho tới khi SetReady được gọi.
+func (r *Registry) Register(exchange string, g Graph) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges[exchange] = &exchangeGraph{graph: g}
+}
+
+// SetReady đánh dấu exchange đã tải xong (ready = true) hoặc cần tải lại.
+func (r *Registry) SetReady(exchange string, ready bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.exchanges[exchange]
+	if !ok {
+		return ErrUnknownExchange
+	}
+	entry.ready = ready
+	return nil
+}
+
+// Graph trả về đồ thị của exchange, false nếu exchange chưa được đăng ký.
+func (r *Registry) Graph(exchange string) (Graph, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.exchanges[exchange]
+	if !ok {
+		return nil, false
+	}
+	return entry.graph, true
+}
+
+// Exchanges trả về tên các exchange đã đăng ký, sắp xếp theo tên.
+func (r *Registry) Exchanges() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.exchanges))
+	for name := range r.exchanges {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ExchangeResult là kết quả query trên đồ thị của một exchange, Err khác nil
+// nếu exchange không tìm được route hoặc còn đang tải (ErrExchangeLoading).
+type ExchangeResult struct {
+	Exchange string
+	Result   RouteResult
+	Err      error
+}
+
+// MultiResult là kết quả query trên tất cả các exchange.
+//   - Best: kết quả tốt nhất, giá bid cao nhất hoặc giá ask thấp nhất
+//   - Exchange: exchange cho kết quả tốt nhất
+//   - Results: kết quả của từng exchange để so sánh, sắp xếp theo tên exchange
+type MultiResult struct {
+	Best     RouteResult
+	Exchange string
+	Results  []ExchangeResult
+}
+
+// BestBidRoute chạy BestBidRoute trên đồ thị của các exchange đã sẵn sàng một
+// cách song song và chọn kết quả có giá cao nhất. Exchange còn đang tải không
+// được chờ, kết quả của nó có Err là ErrExchangeLoading. opts được dùng cho
+// query trên từng exchange, ví dụ WithContext dừng query trên tất cả exchange
+// khi context kết thúc, xem Graph.BestBidRoute.
+//
+// Nếu không exchange nào tìm được route, Results vẫn chứa lỗi của từng
+// exchange và lỗi trả về theo thứ tự ưu tiên: lỗi khác ErrNoRoute của một
+// exchange (ví dụ arbitrage loop), ErrInsufficientLiquidity (exchange giao
+// dịch được nhiều nhất), ErrNoRoute, cuối cùng là ErrExchangeLoading nếu tất
+// cả exchange đều đang tải.
+func (r *Registry) BestBidRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (MultiResult, error) {
+	return r.fanOut(func(g Graph) (RouteResult, error) {
+		return g.BestBidRoute(base, quote, amount, opts...)
+	}, true)
+}
+
+// BestAskRoute giống BestBidRoute nhưng chọn kết quả có giá ask thấp nhất.
+func (r *Registry) BestAskRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (MultiResult, error) {
+	return r.fanOut(func(g Graph) (RouteResult, error) {
+		return g.BestAskRoute(base, quote, amount, opts...)
+	}, false)
+}
+
+// fanOut chạy query trên các exchange đã sẵn sàng, mỗi exchange một goroutine,
+// và chọn kết quả tốt nhất theo giá (cao nhất nếu sell, thấp nhất nếu mua).
+func (r *Registry) fanOut(query func(g Graph) (RouteResult, error),
+	sell bool) (MultiResult, error) {
+	// Sao chép trạng thái các exchange để không giữ khóa trong khi tìm đường
+	r.mu.RLock()
+	names := make([]string, 0, len(r.exchanges))
+	for name := range r.exchanges {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	entries := make([]exchangeGraph, len(names))
+	for i, name := range names {
+		entries[i] = *r.exchanges[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]ExchangeResult, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		results[i].Exchange = names[i]
+		if !entries[i].ready {
+			results[i].Err = ErrExchangeLoading
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].Result, results[i].Err = query(entries[i].graph)
+		}()
+	}
+	wg.Wait()
+
+	multi := MultiResult{Results: results}
+	found := false
+	var err error
+	for _, result := range results {
+		if result.Err != nil {
+			err = moreSevere(err, result.Err)
+			continue
+		}
+		price := result.Result.Price
+		if !found || (sell && price.GreaterThan(multi.Best.Price)) ||
+			(!sell && price.LessThan(multi.Best.Price)) {
+			multi.Best = result.Result
+			multi.Exchange = result.Exchange
+			found = true
+		}
+	}
+	if !found {
+		if err == nil {
+			err = ErrNoRoute
+		}
+		return multi, err
+	}
+	return multi, nil
+}
+
+// moreSevere trả về lỗi có mức ưu tiên cao hơn giữa current và err, theo thứ
+// tự: lỗi khác, ErrInsufficientLiquidity, ErrNoRoute, ErrExchangeLoading. Hai
+// lỗi cùng mức giữ current, trừ hai *LiquidityError thì được gộp lại, xem
+// mergeLiquidity.
+func moreSevere(current, err error) error {
+	severity := func(err error) int {
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, ErrExchangeLoading):
+			return 1
+		case errors.Is(err, ErrInsufficientLiquidity):
+			return 3
+		case errors.Is(err, ErrNoRoute):
+			return 2
+		default:
+			return 4
+		}
+	}
+	var currentLiq, errLiq *LiquidityError
+	if errors.As(current, &currentLiq) && errors.As(err, &errLiq) {
+		return mergeLiquidity(currentLiq, errLiq)
+	}
+	if severity(err) > severity(current) {
+		return err
+	}
+	return current
+}
+
+// mergeLiquidity gộp LiquidityError của hai exchange thành một, Max trả về
+// lượng token tối đa lớn hơn giữa hai exchange. Lượng tối đa của từng exchange
+// chỉ được tính khi gọi Max. Nếu ctx kết thúc khi đang tính b, kết quả đã tính
+// được của a được trả về cùng lỗi của ctx thay vì bị bỏ đi.
+func mergeLiquidity(a, b *LiquidityError) *LiquidityError {
+	return &LiquidityError{
+		Amount: a.Amount,
+		Sell:   a.Sell,
+		max: func(ctx context.Context) (decimal.Decimal, []string, error) {
+			aMax, aRoute, aErr := a.Max(ctx)
+			if isContextError(aErr) {
+				return aMax, aRoute, aErr
+			}
+			bMax, bRoute, bErr := b.Max(ctx)
+			switch {
+			case aErr != nil:
+				return bMax, bRoute, bErr
+			case isContextError(bErr):
+				if bMax.GreaterThan(aMax) {
+					return bMax, bRoute, bErr
+				}
+				return aMax, aRoute, bErr
+			case bErr != nil || aMax.GreaterThanOrEqual(bMax):
+				return aMax, aRoute, nil
+			default:
+				return bMax, bRoute, nil
+			}
+		},
+	}
+}
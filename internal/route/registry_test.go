@@ -0,0 +1,146 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func newExchangeGraph(venue, bid, ask string) Graph {
+	edge := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d(bid), AskPrice: d(ask), Venue: venue}
+	return NewGraphWithEdges([]Edge{edge, edge.GetReverseEdge()})
+}
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("binance", newExchangeGraph("binance", "0.9", "1.2"))
+	r.Register("kraken", newExchangeGraph("kraken", "0.95", "1.1"))
+	r.Register("okx", newExchangeGraph("okx", "2", "2.1"))
+	r.SetReady("binance", true)
+	r.SetReady("kraken", true)
+	// okx đang tải, không được dùng dù có giá bid tốt hơn
+	return r
+}
+
+func TestRegistry_BestBidRoute(t *testing.T) {
+	got, err := newTestRegistry().BestBidRoute("KNC", "USDT", d("10"))
+	if err != nil {
+		t.Fatalf("BestBidRoute() error = %v", err)
+	}
+	if got.Exchange != "kraken" || !got.Best.Price.Equal(d("0.95")) {
+		t.Errorf("best = %s %v, want kraken 0.95", got.Exchange, got.Best.Price)
+	}
+	if len(got.Best.Legs) != 1 || got.Best.Legs[0].Venue != "kraken" {
+		t.Errorf("legs = %+v, want one leg on kraken", got.Best.Legs)
+	}
+
+	wantExchanges := []string{"binance", "kraken", "okx"}
+	if len(got.Results) != len(wantExchanges) {
+		t.Fatalf("got %d results, want %d", len(got.Results), len(wantExchanges))
+	}
+	for i, name := range wantExchanges {
+		if got.Results[i].Exchange != name {
+			t.Errorf("Results[%d].Exchange = %s, want %s", i, got.Results[i].Exchange, name)
+		}
+	}
+	if !got.Results[0].Result.Price.Equal(d("0.9")) {
+		t.Errorf("binance price = %v, want 0.9", got.Results[0].Result.Price)
+	}
+	if !errors.Is(got.Results[2].Err, ErrExchangeLoading) {
+		t.Errorf("okx error = %v, want ErrExchangeLoading", got.Results[2].Err)
+	}
+}
+
+func TestRegistry_BestAskRoute(t *testing.T) {
+	r := newTestRegistry()
+	r.SetReady("okx", true)
+
+	got, err := r.BestAskRoute("KNC", "USDT", d("10"))
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+	if got.Exchange != "kraken" || !got.Best.Price.Equal(d("1.1")) {
+		t.Errorf("best = %s %v, want kraken 1.1", got.Exchange, got.Best.Price)
+	}
+}
+
+func TestRegistry_NoRoute(t *testing.T) {
+	r := newTestRegistry()
+	if err := r.SetReady("bybit", true); !errors.Is(err, ErrUnknownExchange) {
+		t.Errorf("SetReady(unknown) error = %v, want ErrUnknownExchange", err)
+	}
+
+	got, err := r.BestBidRoute("KNC", "ETH", d("1"))
+	if !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("BestBidRoute() error = %v, want ErrNoRoute", err)
+	}
+	for _, result := range got.Results[:2] {
+		if !errors.Is(result.Err, ErrNoRoute) {
+			t.Errorf("%s error = %v, want ErrNoRoute", result.Exchange, result.Err)
+		}
+	}
+}
+
+func TestRegistry_AllLoading(t *testing.T) {
+	r := NewRegistry()
+	r.Register("binance", newExchangeGraph("binance", "0.9", "1.2"))
+
+	if _, err := r.BestBidRoute("KNC", "USDT", d("1")); !errors.Is(err, ErrExchangeLoading) {
+		t.Errorf("BestBidRoute() error = %v, want ErrExchangeLoading", err)
+	}
+}
+
+func TestRegistry_InsufficientLiquidity(t *testing.T) {
+	thin, full := newDepthTestEdge(), newDepthTestEdge()
+	thin.QuoteToken, full.QuoteToken = "ETH", "ETH"
+	thin.BidOrders = thin.BidOrders[:1]
+
+	// binance bán được tối đa 100 KNC, kraken 400 KNC, okx không có KNC/ETH
+	r := NewRegistry()
+	r.Register("binance", NewGraphWithEdges([]Edge{thin, thin.GetReverseEdge()}))
+	r.Register("kraken", NewGraphWithEdges([]Edge{full, full.GetReverseEdge()}))
+	r.Register("okx", newExchangeGraph("okx", "0.9", "1.1"))
+	for _, name := range []string{"binance", "kraken", "okx"} {
+		r.SetReady(name, true)
+	}
+
+	_, err := r.BestBidRoute("KNC", "ETH", d("1000"))
+	var liqErr *LiquidityError
+	if !errors.As(err, &liqErr) {
+		t.Fatalf("BestBidRoute() error = %v, want *LiquidityError", err)
+	}
+	if maxAmount, _, err := liqErr.Max(context.Background()); err != nil || !maxAmount.Equal(d("400")) {
+		t.Errorf("Max() = (%v, %v), want 400", maxAmount, err)
+	}
+}
+
+func TestMergeLiquidity_KeepsResultOnContextError(t *testing.T) {
+	a := NewLiquidityError(d("1000"), d("400"), []string{"KNC", "ETH"}, true)
+	b := &LiquidityError{
+		Amount: d("1000"),
+		Sell:   true,
+		max: func(ctx context.Context) (decimal.Decimal, []string, error) {
+			return decimal.Zero, nil, context.DeadlineExceeded
+		},
+	}
+
+	// b hết thời gian: kết quả của a vẫn được trả về cùng lỗi của ctx và
+	// không được lưu lại
+	merged := mergeLiquidity(a, b)
+	maxAmount, route, err := merged.Max(context.Background())
+	if err != context.DeadlineExceeded || !maxAmount.Equal(d("400")) ||
+		!slices.Equal(route, []string{"KNC", "ETH"}) {
+		t.Errorf("Max() = (%v, %v, %v), want (400, KNC->ETH, %v)",
+			maxAmount, route, err, context.DeadlineExceeded)
+	}
+
+	b.max = func(ctx context.Context) (decimal.Decimal, []string, error) {
+		return d("500"), []string{"KNC", "USDT", "ETH"}, nil
+	}
+	if maxAmount, _, err := merged.Max(context.Background()); err != nil || !maxAmount.Equal(d("500")) {
+		t.Errorf("Max() after retry = (%v, %v), want 500", maxAmount, err)
+	}
+}
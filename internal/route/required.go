@@ -0,0 +1,145 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// requiredStops trả về các token trong required cần đi qua, bỏ qua token trùng
+// lặp và token trùng với base hoặc quote vì route luôn đi qua hai token này.
+func requiredStops(base, quote string, required []string) []string {
+	stops := []string{}
+	for _, token := range required {
+		if token != base && token != quote && !slices.Contains(stops, token) {
+			stops = append(stops, token)
+		}
+	}
+	return stops
+}
+
+// searchThrough tìm route tốt nhất từ base đến quote đi qua tất cả các token
+// required. Với mỗi thứ tự của required, route được ghép từ các đoạn
+// base->required[0]->...->quote: mỗi đoạn tìm bằng searchDirect với lượng
+// token thu được (sell) hoặc cần thiết (mua) ở cuối đoạn trước. Một đoạn không
+// được đi qua token của các đoạn trước và các token dừng phía sau để route
+// ghép lại là đường đi đơn.
+//
+// Do ghép tham lam theo từng đoạn, kết quả là route tốt nhất tìm được theo
+// cách này, không đảm bảo tối ưu tuyệt đối. Số thứ tự cần thử là
+// len(required)! nên required chỉ nên gồm vài token. Với options.MaxHops, mỗi
+// đoạn chỉ được dùng số cạnh còn lại sau khi chừa mỗi đoạn phía sau một cạnh.
+//
+// Mỗi đoạn được kiểm tra và sửa bằng validatedEdges, thứ tự có đoạn không sửa
+// được hoặc đi lại token của đoạn trước bị bỏ qua, nên route ghép lại luôn là
+// đường đi đơn. Lỗi khác của một đoạn (ví dụ arbitrage loop hoặc lỗi của ctx)
+// được trả về ngay, ErrNoRoute được trả về nếu không thứ tự nào tìm được route.
+func (g *graph) searchThrough(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, required []string,
+	options QueryOptions) (searchResult, error) {
+	var best searchResult
+	found := false
+	for order := range permutations(required) {
+		stops := append(append([]string{base}, order...), quote)
+		result, err := g.searchStops(ctx, stops, amount, sell, options)
+		if errors.Is(err, ErrNoRoute) || errors.Is(err, ErrInvalidRoute) {
+			continue
+		}
+		if err != nil {
+			return searchResult{}, err
+		}
+
+		value, current := result.values[quote], best.values[quote]
+		if !found || (sell && value.GreaterThan(current)) ||
+			(!sell && value.LessThan(current)) {
+			best, found = result, true
+		}
+	}
+
+	if !found {
+		return searchResult{}, ErrNoRoute
+	}
+	return best, nil
+}
+
+// searchStops tìm route đi qua lần lượt các token trong stops, xem
+// searchThrough. values của kết quả chỉ gồm lượng token tại các token dừng.
+func (g *graph) searchStops(ctx context.Context, stops []string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (searchResult, error) {
+	combined := searchResult{
+		values: map[string]decimal.Decimal{stops[0]: amount},
+		prevs:  map[string]Edge{},
+	}
+	visited := map[string]bool{stops[0]: true}
+	hops := 0
+	current := amount
+
+	for i := 1; i < len(stops); i++ {
+		from, to := stops[i-1], stops[i]
+
+		// Không quay lại token đã đi qua, không đi qua các token dừng phía sau
+		allowed := func(e Edge) bool {
+			return !visited[e.To()] && !slices.Contains(stops[i+1:], e.To())
+		}
+		segmentOptions := options
+		if options.MaxHops > 0 {
+			segmentOptions.MaxHops = options.MaxHops - hops - (len(stops) - 1 - i)
+			if segmentOptions.MaxHops < 1 {
+				return searchResult{}, ErrNoRoute
+			}
+		}
+
+		view := g.withFilter(allowed)
+		result, err := view.searchDirect(ctx, from, to, current, sell, segmentOptions)
+		if err != nil {
+			return searchResult{}, err
+		}
+		// Đoạn không có token bắt buộc nên được sửa giống route thông thường
+		segmentOptions.RequiredTokens = nil
+		edges, err := view.validatedEdges(ctx, from, to, current, sell, segmentOptions, &result)
+		if err != nil {
+			return searchResult{}, err
+		}
+
+		for _, edge := range edges {
+			if visited[edge.To()] {
+				return searchResult{}, ErrNoRoute
+			}
+			combined.prevs[edge.To()] = edge
+			visited[edge.To()] = true
+		}
+		hops += len(edges)
+		current = result.values[to]
+		combined.values[to] = current
+		combined.warnings = append(combined.warnings, result.warnings...)
+		combined.algorithm = result.algorithm
+		combined.partial = combined.partial || result.partial
+		combined.repaired = combined.repaired || result.repaired
+	}
+	return combined, nil
+}
+
+// permutations duyệt tất cả các hoán vị của tokens.
+func permutations(tokens []string) iter.Seq[[]string] {
+	return func(yield func([]string) bool) {
+		order := slices.Clone(tokens)
+		var permute func(k int) bool
+		permute = func(k int) bool {
+			if k == len(order) {
+				return yield(slices.Clone(order))
+			}
+			for i := k; i < len(order); i++ {
+				order[k], order[i] = order[i], order[k]
+				if !permute(k + 1) {
+					return false
+				}
+				order[k], order[i] = order[i], order[k]
+			}
+			return true
+		}
+		permute(0)
+	}
+}
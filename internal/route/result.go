@@ -0,0 +1,332 @@
+package route
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// Leg là một chặng giao dịch trong route, theo đúng thứ tự thực hiện.
+//   - From, To: token đưa vào và token nhận về ở chặng này
+//   - AmountIn, AmountOut: lượng token đưa vào và nhận về, đã tính phí
+//   - Fee, FeeToken: phí đã trả ở chặng này và token dùng để trả phí, FeeToken
+//     rỗng nếu cạnh không tính phí
+//   - Venue: exchange thực hiện chặng này, rỗng nếu cạnh không gắn exchange
+//   - Symbol, Side: trading pair trên exchange dạng BASE/QUOTE và chiều giao
+//     dịch trên trading pair đó, ví dụ bán KNC lấy USDT là KNC/USDT SideSell,
+//     bán USDT lấy KNC là KNC/USDT SideBuy. Cùng Venue, hai trường này định
+//     danh lệnh cần đặt ở chặng này
+//   - Edge: cạnh đã dùng ở chặng này, phân biệt được các cạnh song song nối
+//     cùng hai token
+//   - AveragePrice, WorstPrice: giá khớp trung bình và giá của mức giá sâu
+//     nhất đã khớp trên order book, trước phí
+//   - MidPrice: trung bình của best bid và best ask của trading pair, 0 nếu
+//     order book thiếu một phía
+//   - Levels: số mức giá đã khớp trên order book
+//   - ImpactBps: độ trượt giá của AveragePrice so với giá tốt nhất của order
+//     book (top of book), tính bằng basis points, dương nghĩa là kém hơn
+//
+// Các giá tính theo Symbol giống giá niêm yết trên exchange, tức lượng quote
+// token cho một base token của trading pair, bất kể chiều giao dịch Side. Ví
+// dụ chặng ETH->USDT hay USDT->ETH trên ETH/USDT đều có giá khoảng 3500 USDT
+// cho một ETH. Các giá bằng 0 và Levels = 0 nếu cạnh không cho biết chi tiết
+// khớp lệnh.
+type Leg struct {
+	From         string
+	To           string
+	AmountIn     decimal.Decimal
+	AmountOut    decimal.Decimal
+	Fee          decimal.Decimal
+	FeeToken     string
+	Venue        string
+	Symbol       string
+	Side         Side
+	Edge         Edge
+	AveragePrice decimal.Decimal
+	WorstPrice   decimal.Decimal
+	MidPrice     decimal.Decimal
+	Levels       int
+	ImpactBps    decimal.Decimal
+}
+
+// RouteResult là kết quả chi tiết của một route query.
+//   - Price: giá thực tế (effective price) của cả route, tỷ lệ quote/base đã
+//     tính phí, giống BestBidPrice/BestAskPrice
+//   - MidPrice: tích MidPrice của các chặng, tức giá quote/base nếu giao dịch
+//     ở giữa spread của mọi trading pair, 0 nếu không xác định được
+//   - ImpactBps: độ chênh của Price so với MidPrice tính bằng basis points,
+//     dương nghĩa là kém hơn, bao gồm spread, độ sâu order book và phí
+//   - Route: đường đi theo thứ tự giao dịch, giống BestBidPrice/BestAskPrice
+//   - AmountIn, AmountOut: lượng token đưa vào đầu route và nhận về cuối
+//     route. Với bid là base token bán ra và quote token thu được, với ask là
+//     quote token phải trả và base token mua được
+//   - Legs: chi tiết từng chặng, theo thứ tự của Route
+//   - Warnings: các arbitrage loop đã bị bỏ qua khi tìm đường ở chế độ
+//     QueryOptions.CycleResilient, rỗng nếu không có
+//   - Algorithm: tên thuật toán tìm ra route, với WithRace là thuật toán cho
+//     kết quả tốt nhất
+//   - Partial: kết quả là route tốt nhất tìm được trước khi context của query
+//     kết thúc, có thể chưa tối ưu, xem WithPartialResult
+//   - Repaired: đường đi của thuật toán không mô phỏng lại được đúng giá đã
+//     tính nên route được tìm lại bằng thuật toán đường đi đơn, xem
+//     ErrInvalidRoute
+//   - Version: version của snapshot đồ thị dùng để tính kết quả
+//   - Alternatives: các route tiếp theo sau route này, từ tốt tới kém, khi
+//     dùng WithTopRoutes, rỗng nếu không có
+type RouteResult struct {
+	Price     decimal.Decimal
+	MidPrice  decimal.Decimal
+	ImpactBps decimal.Decimal
+	Route     []string
+	AmountIn  decimal.Decimal
+	AmountOut decimal.Decimal
+	Legs      []Leg
+	Warnings  []*ArbitrageError
+	Algorithm string
+	Partial   bool
+	Repaired  bool
+	Version   uint64
+
+	Alternatives []RouteResult
+}
+
+// BestBidRoute giống BestBidPrice nhưng trả về kết quả chi tiết từng chặng,
+// bao gồm phí đã trả ở mỗi chặng. Các chặng được mô phỏng lại qua đúng cạnh
+// mà thuật toán tìm đường đã chọn, xem Leg.Edge.
+//
+// Với WithContext, tìm đường dừng lại khi ctx bị hủy hoặc hết deadline, khi
+// đó trả về ctx.Err(), hoặc route tốt nhất tìm được tới lúc đó với Partial =
+// true nếu dùng WithPartialResult. Xem thêm WithTopRoutes và WithMaxAmount.
+func (g *graph) BestBidRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (RouteResult, error) {
+	options := newQueryOptions(opts)
+	return g.routeQuery(options.context(), base, quote, amount, true, options)
+}
+
+// BestAskRoute giống BestAskPrice nhưng trả về kết quả chi tiết từng chặng,
+// bao gồm phí đã trả ở mỗi chặng. Route và Legs đi từ quote về base. Các
+// option có tác dụng giống BestBidRoute.
+func (g *graph) BestAskRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (RouteResult, error) {
+	options := newQueryOptions(opts)
+	return g.routeQuery(options.context(), base, quote, amount, false, options)
+}
+
+// routeQuery chạy BestBidRoute (sell = true) hoặc BestAskRoute: với
+// WithMaxAmount, amount được thay bằng lượng lớn nhất giao dịch được nếu không
+// đủ thanh khoản (xem maxRoute), rồi tìm route tốt nhất, hoặc nhiều route với
+// WithTopRoutes (xem topRoutes).
+func (g *graph) routeQuery(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (RouteResult, error) {
+	if options.MaxAmount {
+		result, maxAmount, err := g.maxRoute(ctx, base, quote, amount, sell, options)
+		if err != nil || options.TopRoutes == 0 {
+			return result, err
+		}
+		amount, options.PartialResult = maxAmount, false
+	}
+	if options.TopRoutes == 0 {
+		return g.bestRoute(ctx, base, quote, amount, sell, options)
+	}
+
+	results, err := g.topRoutes(ctx, base, quote, amount, options.TopRoutes, sell, options)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	best := results[0]
+	best.Alternatives = results[1:]
+	return best, nil
+}
+
+// bestRoute tìm route tốt nhất khi bán (sell = true) hoặc mua theo options và
+// mô phỏng lại từng chặng của route. Khi không route nào đủ thanh khoản cho
+// amount, lỗi trả về là *LiquidityError, xem liquidityError.
+func (g *graph) bestRoute(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (RouteResult, error) {
+	result, err := g.findRoute(ctx, base, quote, amount, sell, options)
+	if err != nil {
+		return RouteResult{}, g.liquidityError(base, quote, amount, sell, options, err)
+	}
+	return result, nil
+}
+
+// findRoute giống bestRoute nhưng trả về nguyên lỗi của việc tìm đường.
+func (g *graph) findRoute(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (RouteResult, error) {
+	view := g.view(options)
+	if options.ExactQuote {
+		return view.bestRouteExactQuote(ctx, base, quote, amount, sell, options)
+	}
+
+	result, err := view.search(ctx, base, quote, amount, sell, options)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	edges, err := view.validatedEdges(ctx, base, quote, amount, sell, options, &result)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	return view.newRouteResult(base, edges, amount, sell, result)
+}
+
+// bestRouteExactQuote giống findRoute với amount là lượng quote token, xem
+// WithExactQuote. Route được tìm ngược từ quote về base trên đồ thị inverse:
+// với bid là lượng base token tối thiểu cần bán (như tìm giá ask), với ask là
+// lượng base token tối đa mua được (như tìm giá bid). Sau đó route được mô
+// phỏng lại theo chiều xuôi qua đúng các cạnh gốc với lượng base token tìm
+// được.
+func (g *graph) bestRouteExactQuote(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (RouteResult, error) {
+	inverse := g.inverse()
+	result, err := inverse.search(ctx, quote, base, amount, !sell, options)
+	if err != nil {
+		return RouteResult{}, uninvert(err)
+	}
+	for i, warning := range result.warnings {
+		result.warnings[i] = uninvertCycle(warning)
+	}
+
+	edges, err := inverse.validatedEdges(ctx, quote, base, amount, !sell, options, &result)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	return g.newRouteResult(base, uninvertEdges(edges), result.values[base], sell, result)
+}
+
+// newRouteResult mô phỏng lại việc bán (sell = true) hoặc mua amount base token
+// qua các cạnh edges (từ base đến quote) và tạo RouteResult từ kết quả tìm
+// đường.
+func (g *graph) newRouteResult(base string, edges []Edge, amount decimal.Decimal,
+	sell bool, result searchResult) (RouteResult, error) {
+	legs, value, ok := replay(edges, amount, sell)
+	if !ok {
+		return RouteResult{}, ErrNoRoute
+	}
+
+	// value là lượng quote token thu được với bid, phải trả với ask
+	path := edgesPath(base, edges)
+	route := RouteResult{
+		Route:     path,
+		AmountIn:  amount,
+		AmountOut: value,
+		Legs:      legs,
+		MidPrice:  legsMidPrice(legs),
+		Warnings:  result.warnings,
+		Algorithm: result.algorithm,
+		Partial:   result.partial,
+		Repaired:  result.repaired,
+		Version:   g.version,
+	}
+	if sell {
+		route.Price = value.QuoRound(amount, decimal.RoundDown)
+	} else {
+		slices.Reverse(path)
+		route.Price = value.QuoRound(amount, decimal.RoundUp)
+		route.AmountIn, route.AmountOut = value, amount
+	}
+	route.ImpactBps = impactBps(route.MidPrice, route.Price, sell)
+	return route, nil
+}
+
+// replay mô phỏng lại việc bán (sell = true) hoặc mua amount qua từng cạnh của
+// edges, theo chiều của cạnh từ base đến quote. Với ask, các chặng trả về
+// được đảo lại theo thứ tự giao dịch từ quote về base.
+//
+// Kết quả trả về danh sách chặng, lượng token cuối cùng ở quote (thu được với
+// bid, phải trả với ask) và false nếu không mô phỏng được hết edges.
+func replay(edges []Edge, amount decimal.Decimal, sell bool) ([]Leg, decimal.Decimal, bool) {
+	legs := make([]Leg, 0, len(edges))
+	current := amount
+	for _, edge := range edges {
+		value, fee, feeToken, ok := simulateWithFee(edge, current, sell)
+		if !ok {
+			return nil, decimal.Zero, false
+		}
+
+		leg := Leg{
+			From:      edge.From(),
+			To:        edge.To(),
+			AmountIn:  current,
+			AmountOut: value,
+			Fee:       fee,
+			FeeToken:  feeToken,
+			Venue:     VenueOf(edge),
+			Symbol:    SymbolOf(edge),
+			Edge:      edge,
+		}
+		if de, ok := edge.(depthEdge); ok {
+			if stats, ok := de.fillStats(current, sell); ok {
+				reversed := isReversed(edge)
+				leg.AveragePrice = pairPrice(stats.average, reversed)
+				leg.WorstPrice = pairPrice(stats.worst, reversed)
+				leg.MidPrice = stats.mid
+				if reversed {
+					leg.MidPrice = reversedMidPrice(edge)
+				}
+				leg.Levels = stats.levels
+				leg.ImpactBps = impactBps(stats.top, stats.average, sell)
+			}
+		}
+		if !sell {
+			// Mua current token edge.From() bằng value token edge.To()
+			leg.From, leg.To = leg.To, leg.From
+			leg.AmountIn, leg.AmountOut = leg.AmountOut, leg.AmountIn
+		}
+		leg.Side = sideOf(edge, leg.From)
+		legs = append(legs, leg)
+		current = value
+	}
+
+	if !sell {
+		slices.Reverse(legs)
+	}
+	return legs, current, true
+}
+
+// legsMidPrice trả về mid price của route theo chiều từ base tới quote: tích
+// MidPrice của các chặng, đảo lại MidPrice của chặng có cạnh ngược chiều với
+// trading pair. Trả về 0 nếu có chặng không xác định được MidPrice.
+func legsMidPrice(legs []Leg) decimal.Decimal {
+	if len(legs) == 0 {
+		return decimal.Zero
+	}
+	mid := decimal.One
+	for _, leg := range legs {
+		switch {
+		case !leg.MidPrice.IsPositive():
+			return decimal.Zero
+		case isReversed(leg.Edge):
+			mid = mid.QuoRound(leg.MidPrice, decimal.RoundHalfEven)
+		default:
+			mid = mid.Mul(leg.MidPrice)
+		}
+	}
+	return mid
+}
+
+// reversedMidPrice trả về mid price theo trading pair của cạnh ngược chiều
+// edge: trung bình của best bid và best ask của trading pair, tức nghịch đảo
+// của best ask và best bid của edge. Trả về 0 nếu edge không cho biết top of
+// book hoặc thiếu một phía.
+func reversedMidPrice(edge Edge) decimal.Decimal {
+	top, ok := edge.(topOfBookEdge)
+	if !ok {
+		return decimal.Zero
+	}
+	ask, askOK := top.topPrice(false)
+	bid, bidOK := top.topPrice(true)
+	if !askOK || !bidOK {
+		return decimal.Zero
+	}
+	return midPrice(pairPrice(ask, true), pairPrice(bid, true))
+}
+
+// pairPrice chuyển giá theo chiều của cạnh thành giá theo trading pair: nghịch
+// đảo nếu cạnh ngược chiều với trading pair. Giá 0 được giữ nguyên.
+func pairPrice(price decimal.Decimal, reversed bool) decimal.Decimal {
+	if !reversed || !price.IsPositive() {
+		return price
+	}
+	return decimal.One.QuoRound(price, decimal.RoundHalfEven)
+}
@@ -1,10 +1,26 @@
 package route
 
+import "github.com/nkngn/kyber-homework/internal/decimal"
+
 type SimpleEdge struct {
 	BaseToken  string
 	QuoteToken string
-	BidPrice   float64
-	AskPrice   float64
+	BidPrice   decimal.Decimal
+	AskPrice   decimal.Decimal
+
+	// Precisions dùng để làm tròn lượng quote token sau khi mô phỏng, nil
+	// nghĩa là giữ nguyên precision decimal.Scale.
+	Precisions Precisions
+
+	// Fee là phí taker khi giao dịch qua cạnh này.
+	Fee Fee
+
+	// Venue là tên exchange (sàn) của trading pair, rỗng nếu không phân biệt.
+	Venue string
+
+	// Reversed là true nếu cạnh đi ngược chiều trading pair của exchange, tức
+	// trading pair là QuoteToken/BaseToken. GetReverseEdge đảo giá trị này.
+	Reversed bool
 }
 
 func (e SimpleEdge) From() string { return e.BaseToken }
@@ -13,29 +29,134 @@ func (e SimpleEdge) To() string   { return e.QuoteToken }
 // SimulateSell mô phỏng việc bán amount base token qua SimpleEdge này.
 // Đối với SimpleEdge, giả định thanh khoản (liquidity) là vô hạn nên luôn
 // bán được bất kỳ amount nào, không cần kiểm tra order book.
-// Trả về lượng quote token thu được và luôn trả về true.
-func (e SimpleEdge) SimulateSell(amount float64) (float64, bool) {
-	return amount * e.BidPrice, true
+// Trả về lượng quote token thu được sau phí (làm tròn xuống) và true, trả về
+// false nếu BidPrice không dương, tức không có ai mua, hoặc phí lớn hơn lượng
+// token giao dịch.
+func (e SimpleEdge) SimulateSell(amount decimal.Decimal) (decimal.Decimal, bool) {
+	acquired, _, isFeasible := e.simulateSellFee(amount)
+	return acquired, isFeasible
+}
+
+// simulateSellFee giống SimulateSell, trả về thêm lượng phí đã trả.
+func (e SimpleEdge) simulateSellFee(amount decimal.Decimal) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	if !e.BidPrice.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	acquired, fee, isFeasible := e.Fee.sell(amount,
+		func(amount decimal.Decimal) (decimal.Decimal, bool) {
+			return amount.MulRound(e.BidPrice, decimal.RoundDown), true
+		})
+	if !isFeasible {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return e.Precisions.roundReceived(e.QuoteToken, acquired), fee, true
 }
 
 // SimulateBuy mô phỏng việc mua amount base token qua SimpleEdge này.
 // Đối với SimpleEdge, giả định thanh khoản (liquidity) là vô hạn nên luôn
 // mua được bất kỳ amount nào, không cần kiểm tra order book.
-// Trả về lượng quote token cần thiết để mua amount base token và luôn trả về true.
-func (e SimpleEdge) SimulateBuy(amount float64) (float64, bool) {
-	return amount * e.AskPrice, true
+// Trả về lượng quote token cần thiết đã gồm phí (làm tròn lên) để nhận được
+// amount base token và true, trả về false nếu AskPrice không dương, tức không
+// có ai bán.
+func (e SimpleEdge) SimulateBuy(amount decimal.Decimal) (decimal.Decimal, bool) {
+	required, _, isFeasible := e.simulateBuyFee(amount)
+	return required, isFeasible
 }
 
+// simulateBuyFee giống SimulateBuy, trả về thêm lượng phí đã trả.
+func (e SimpleEdge) simulateBuyFee(amount decimal.Decimal) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	if !e.AskPrice.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	required, fee, isFeasible := e.Fee.buy(amount,
+		func(amount decimal.Decimal) (decimal.Decimal, bool) {
+			return amount.MulRound(e.AskPrice, decimal.RoundUp), true
+		})
+	if !isFeasible {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return e.Precisions.roundPaid(e.QuoteToken, required), fee, true
+}
+
+// SimulateSellExactOut là phép ngược của SimulateSell: trả về lượng base token
+// nhỏ nhất (làm tròn lên) cần bán để thu được ít nhất amount quote token sau
+// phí và true. Trả về false nếu amount hoặc BidPrice không dương.
+func (e SimpleEdge) SimulateSellExactOut(amount decimal.Decimal) (decimal.Decimal, bool) {
+	if !e.BidPrice.IsPositive() || !amount.IsPositive() {
+		return decimal.Zero, false
+	}
+	return e.Fee.sellExactOut(e.Precisions.roundPaid(e.QuoteToken, amount),
+		func(acquired decimal.Decimal) (decimal.Decimal, bool) {
+			return acquired.QuoRound(e.BidPrice, decimal.RoundUp), true
+		})
+}
+
+// SimulateBuyExactIn là phép ngược của SimulateBuy: trả về lượng base token
+// lớn nhất (làm tròn xuống) mua được sau phí khi trả tối đa amount quote token
+// và true. Trả về false nếu AskPrice không dương hoặc amount không đủ mua
+// được base token nào.
+func (e SimpleEdge) SimulateBuyExactIn(amount decimal.Decimal) (decimal.Decimal, bool) {
+	if !e.AskPrice.IsPositive() {
+		return decimal.Zero, false
+	}
+	acquired, isFeasible := e.Fee.buyExactIn(e.Precisions.roundReceived(e.QuoteToken, amount),
+		func(budget decimal.Decimal) (decimal.Decimal, bool) {
+			return budget.QuoRound(e.AskPrice, decimal.RoundDown), true
+		})
+	if !isFeasible || !acquired.IsPositive() {
+		return decimal.Zero, false
+	}
+	return acquired, true
+}
+
+// feeToken trả về token dùng để trả phí của cạnh.
+func (e SimpleEdge) feeToken() string {
+	return e.Fee.token(e.BaseToken, e.QuoteToken)
+}
+
+// venue trả về exchange của cạnh.
+func (e SimpleEdge) venue() string { return e.Venue }
+
+// reversed cho biết cạnh có đi ngược chiều trading pair hay không.
+func (e SimpleEdge) reversed() bool { return e.Reversed }
+
 // GetReverseEdge trả về một cạnh SimpleEdge đảo ngược chiều giao dịch so với
 // cạnh hiện tại. Giá Bid/Ask của cạnh đảo ngược sẽ là nghịch đảo của Ask/Bid
 // của cạnh gốc.
 // Ví dụ: Nếu cạnh gốc là A->B với BidPrice/AskPrice thì cạnh đảo ngược là
 // B->A với BidPrice = 1/AskPrice, AskPrice = 1/BidPrice.
+//
+// BidPrice mới được làm tròn xuống, AskPrice mới được làm tròn lên để việc
+// đi vòng A->B->A không bao giờ sinh lời do sai số làm tròn. Phí được giữ
+// nguyên và vẫn trả bằng cùng token như cạnh gốc.
 func (e SimpleEdge) GetReverseEdge() Edge {
 	return &SimpleEdge{
 		BaseToken:  e.QuoteToken,
 		QuoteToken: e.BaseToken,
-		BidPrice:   1.0 / e.AskPrice,
-		AskPrice:   1.0 / e.BidPrice,
+		BidPrice:   inversePrice(e.AskPrice, decimal.RoundDown),
+		AskPrice:   inversePrice(e.BidPrice, decimal.RoundUp),
+		Precisions: e.Precisions,
+		Fee:        e.Fee.reverse(),
+		Venue:      e.Venue,
+		Reversed:   !e.Reversed,
+	}
+}
+
+// afterSell trả về cạnh còn lại sau khi bán amount base token. SimpleEdge có
+// thanh khoản vô hạn nên cạnh không thay đổi.
+func (e SimpleEdge) afterSell(amount decimal.Decimal) Edge { return e }
+
+// afterBuy trả về cạnh còn lại sau khi mua amount base token. SimpleEdge có
+// thanh khoản vô hạn nên cạnh không thay đổi.
+func (e SimpleEdge) afterBuy(amount decimal.Decimal) Edge { return e }
+
+// inversePrice trả về 1/price làm tròn theo mode, trả về 0 nếu price không
+// dương (cạnh đảo ngược cũng không có thanh khoản).
+func inversePrice(price decimal.Decimal, mode decimal.RoundingMode) decimal.Decimal {
+	if !price.IsPositive() {
+		return decimal.Zero
 	}
+	return decimal.One.QuoRound(price, mode)
 }
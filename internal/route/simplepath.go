@@ -0,0 +1,262 @@
+package route
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// searchResult là kết quả của search.
+//   - values, prevs: giống propagateBellmanFord/bellmanFord
+//   - warnings: các arbitrage loop được bỏ qua ở chế độ CycleResilient
+//   - algorithm: tên thuật toán cho kết quả, xem RouteFinder.Name
+//   - partial: kết quả tốt nhất tìm được trước khi ctx kết thúc, xem
+//     QueryOptions.PartialResult
+//   - repaired: route đã được sửa bởi validatedEdges
+type searchResult struct {
+	values    map[string]decimal.Decimal
+	prevs     map[string]Edge
+	warnings  []*ArbitrageError
+	algorithm string
+	partial   bool
+	repaired  bool
+}
+
+// search tìm đường từ base đến quote bằng thuật toán của options (mặc định
+// Bellman-Ford hoặc branch-and-bound với đồ thị nhỏ, hoặc chạy song song các thuật toán của options.Race) và xử lý
+// arbitrage loop theo options, xem searchWith. Route đi qua các token của
+// options.RequiredTokens nếu có, xem searchThrough. Việc tìm đường dừng lại
+// khi ctx kết thúc.
+//
+// g thường là view của snapshot theo options, xem graph.view.
+func (g *graph) search(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (searchResult, error) {
+	if required := requiredStops(base, quote, options.RequiredTokens); len(required) > 0 {
+		return g.searchThrough(ctx, base, quote, amount, sell, required, options)
+	}
+	return g.searchDirect(ctx, base, quote, amount, sell, options)
+}
+
+// searchDirect giống search nhưng bỏ qua options.RequiredTokens.
+func (g *graph) searchDirect(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) (searchResult, error) {
+	if len(options.Race) > 0 {
+		return g.race(ctx, base, quote, amount, sell, options)
+	}
+
+	finder := options.Finder
+	if finder == nil {
+		finder = BellmanFord()
+		if g.vertexCount() <= options.ExactSearchLimit {
+			finder = BranchAndBound(0)
+		}
+	}
+	return g.searchWith(ctx, finder, base, quote, amount, sell, options)
+}
+
+// searchWith tìm đường từ base đến quote bằng finder và xử lý arbitrage loop
+// theo options:
+//   - các chu trình có lợi nhuận không vượt quá CycleTolerance bị bỏ qua
+//   - nếu còn chu trình và không bật CycleResilient, trả về lỗi arbitrage loop
+//   - ngược lại, kết quả của finder không còn đúng nên tìm lại route tối ưu
+//     chính xác trong các đường đi đơn bằng BranchAndBound (tối đa
+//     options.MaxHops cạnh hoặc số token trừ 1), các chu trình còn lại được
+//     trả về dưới dạng warnings
+//
+// Khi ctx kết thúc, trả về ctx.Err(), hoặc kết quả tốt nhất tới lúc đó với
+// partial = true nếu bật options.PartialResult.
+func (g *graph) searchWith(ctx context.Context, finder RouteFinder,
+	base, quote string, amount decimal.Decimal, sell bool,
+	options QueryOptions) (searchResult, error) {
+	values, prevs, err := finder.find(ctx, g, base, quote, amount, sell,
+		options.MaxHops)
+	if !errors.Is(err, ErrArbitrageLoop) {
+		return partialResult(values, prevs, nil, finder.Name(), quote, sell,
+			options, err)
+	}
+
+	cycles := arbitrageCycles(err)
+	warnings := []*ArbitrageError{}
+	for _, cycle := range cycles {
+		if !options.ignores(cycle) {
+			warnings = append(warnings, cycle)
+		}
+	}
+
+	// Không truy vết được chu trình nào thì không thể đánh giá lợi nhuận,
+	// coi như chu trình không bị bỏ qua
+	if !options.CycleResilient && (len(warnings) > 0 || len(cycles) == 0) {
+		return searchResult{}, err
+	}
+
+	values, prevs, err = BranchAndBound(0).find(ctx, g, base, quote, amount, sell,
+		options.MaxHops)
+	return partialResult(values, prevs, warnings, finder.Name(), quote, sell,
+		options, err)
+}
+
+// partialResult tạo searchResult từ kết quả của một thuật toán. err là lỗi
+// của ctx thì kết quả được giữ lại với partial = true nếu bật
+// options.PartialResult và thuật toán đã tìm được đường tới quote, ngược lại
+// trả về err.
+func partialResult(values map[string]decimal.Decimal, prevs map[string]Edge,
+	warnings []*ArbitrageError, algorithm, quote string, sell bool,
+	options QueryOptions, err error) (searchResult, error) {
+	result := searchResult{values: values, prevs: prevs, warnings: warnings,
+		algorithm: algorithm}
+	if err == nil {
+		return result, nil
+	}
+	if !options.PartialResult || !isContextError(err) {
+		return searchResult{}, err
+	}
+
+	value, ok := values[quote]
+	if !ok || (sell && value.IsZero()) {
+		return searchResult{}, err
+	}
+	result.partial = true
+	return result, nil
+}
+
+// isContextError kiểm tra err có phải lỗi do ctx bị hủy hoặc hết deadline.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// pathLabel là một đường đi đơn từ base tới một token, lưu dưới dạng danh
+// sách liên kết ngược để các đường đi dùng chung phần đầu mà không cần sao
+// chép.
+//   - edge: cạnh cuối cùng của đường đi, nil với đường đi chỉ gồm base
+//   - prev: đường đi tới edge.From()
+//   - value: lượng token thu được (sell) hoặc cần thiết (mua) tại token cuối
+//   - hops: số cạnh của đường đi
+type pathLabel struct {
+	edge  Edge
+	prev  *pathLabel
+	value decimal.Decimal
+	hops  int
+}
+
+// visits kiểm tra token có nằm trên đường đi hay không.
+func (l *pathLabel) visits(token string) bool {
+	for label := l; label != nil; label = label.prev {
+		if label.edge == nil {
+			return false
+		}
+		if label.edge.To() == token {
+			return true
+		}
+	}
+	return false
+}
+
+// result trả về values và prevs của đường đi từ base, chỉ chứa các token trên
+// đường đi.
+func (l *pathLabel) result(base string) (map[string]decimal.Decimal, map[string]Edge) {
+	values := map[string]decimal.Decimal{}
+	prevs := map[string]Edge{}
+	for label := l; ; label = label.prev {
+		if label.edge == nil {
+			values[base] = label.value
+			break
+		}
+		values[label.edge.To()] = label.value
+		prevs[label.edge.To()] = label.edge
+	}
+	return values, prevs
+}
+
+// simplePathSearch là biến thể của Bellman-Ford chỉ lan truyền theo các đường
+// đi đơn: mỗi token giữ đường đi tốt nhất tới nó và một cạnh chỉ được nới
+// (relax) nếu token đích chưa nằm trên đường đi đó. Vì vậy thuật toán luôn
+// dừng và cho kết quả là đường đi đơn kể cả khi đồ thị có arbitrage loop.
+//
+// Khi đồ thị không có arbitrage loop, kết quả giống propagateBellmanFord
+// (sell = true) hoặc bellmanFord (sell = false). Khi có, kết quả là đường đi
+// đơn tốt nhất mà thuật toán lan truyền được, không đảm bảo tối ưu tuyệt đối
+// vì mỗi token chỉ giữ một đường đi. Thuật toán chỉ dùng để sửa route không
+// hợp lệ (xem validatedEdges), route của chế độ CycleResilient được tìm chính
+// xác bằng BranchAndBound, xem searchWith.
+//
+// maxHops lớn hơn 0 thì chỉ lan truyền theo các đường đi có tối đa maxHops
+// cạnh.
+//
+// Kết quả trả về giống propagateBellmanFord/bellmanFord, prevs chỉ chứa các
+// cạnh trên đường đi tới quote. Khi ctx kết thúc, trả về ctx.Err() cùng với
+// kết quả tốt nhất tới lúc đó.
+func (g *graph) simplePathSearch(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (map[string]decimal.Decimal,
+	map[string]Edge, error) {
+	if !g.edges.has(base) {
+		return nil, nil, ErrNoRoute
+	}
+	if !g.edges.has(quote) {
+		return nil, nil, ErrNoRoute
+	}
+
+	labels := map[string]*pathLabel{base: {value: amount}}
+
+	// Đường đi đơn có tối đa n-1 cạnh, với n là số đỉnh
+	var err error
+	for range g.vertexCount() - 1 {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		updated := false
+		for baseToken := range g.edges.tokens() {
+			label, ok := labels[baseToken]
+			if !ok || (maxHops > 0 && label.hops >= maxHops) {
+				continue
+			}
+
+			for edge := range g.outgoing(baseToken) {
+				if edge.To() == base || label.visits(edge.To()) {
+					continue
+				}
+
+				var value decimal.Decimal
+				var isFeasible bool
+				if sell {
+					value, isFeasible = edge.SimulateSell(label.value)
+				} else {
+					value, isFeasible = edge.SimulateBuy(label.value)
+				}
+				if !isFeasible {
+					continue
+				}
+
+				current, ok := labels[edge.To()]
+				if !ok || (sell && value.GreaterThan(current.value)) ||
+					(!sell && value.LessThan(current.value)) {
+					labels[edge.To()] = &pathLabel{edge: edge, prev: label,
+						value: value, hops: label.hops + 1}
+					updated = true
+				}
+			}
+		}
+		if !updated {
+			break
+		}
+	}
+
+	label, ok := labels[quote]
+	if !ok || (sell && label.value.IsZero()) {
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrNoRoute
+	}
+
+	values := make(map[string]decimal.Decimal, len(labels))
+	for token, label := range labels {
+		values[token] = label.value
+	}
+	prevs := map[string]Edge{}
+	for ; label.edge != nil; label = label.prev {
+		prevs[label.edge.To()] = label.edge
+	}
+	return values, prevs, err
+}
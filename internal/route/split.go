@@ -0,0 +1,295 @@
+package route
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// consumableEdge là cạnh có thể trả về phần thanh khoản còn lại sau khi đã
+// khớp một lượng base token. Cạnh không cài đặt interface này được coi như có
+// thanh khoản vô hạn.
+type consumableEdge interface {
+	afterSell(amount decimal.Decimal) Edge
+	afterBuy(amount decimal.Decimal) Edge
+
+	// afterReverse trả về cạnh còn lại khi cạnh đảo ngược dùng chung order
+	// book đã được khớp từ before thành after bằng việc bán (sell = true)
+	// hoặc mua.
+	afterReverse(before, after Edge, sell bool) Edge
+}
+
+// SplitRoute là một route trong kết quả chia lệnh.
+//   - Route: đường đi, cùng thứ tự với BestBidPrice/BestAskPrice
+//   - Fraction: tỷ lệ lượng base token được phân bổ cho route này
+//   - AmountIn: lượng token đưa vào route. Với bid là base token bán ra,
+//     với ask là quote token phải trả
+//   - AmountOut: lượng token nhận về từ route. Với bid là quote token thu
+//     được, với ask là base token mua được
+//   - Price: tỷ lệ quote/base của riêng route này
+type SplitRoute struct {
+	Route     []string
+	Fraction  decimal.Decimal
+	AmountIn  decimal.Decimal
+	AmountOut decimal.Decimal
+	Price     decimal.Decimal
+}
+
+// SplitResult là kết quả chia một lệnh lớn qua nhiều route. AmountIn,
+// AmountOut có cùng ý nghĩa với SplitRoute, Price là giá blended quote/base
+// của toàn bộ lệnh, Version là version của snapshot đồ thị dùng để tính kết
+// quả.
+type SplitResult struct {
+	Routes    []SplitRoute
+	AmountIn  decimal.Decimal
+	AmountOut decimal.Decimal
+	Price     decimal.Decimal
+	Version   uint64
+}
+
+// SplitBidPrice tìm cách bán amount base token lấy quote token, cho phép chia
+// lệnh qua nhiều route để tận dụng thanh khoản của nhiều order book.
+//
+// Ý tưởng: chia amount thành parts phần bằng nhau (phần cuối nhận thêm phần dư
+// do làm tròn để tổng các phần đúng bằng amount), mỗi phần được tìm đường trên
+// đồ thị "còn lại" (residual), tức order book đã bị trừ đi các orders mà các
+// phần trước đã khớp, ở cả hai chiều của trading pair. Do lợi nhuận biên của
+// order book giảm dần, cách phân bổ tham lam này cho kết quả tối ưu với độ mịn
+// amount/parts. Nếu bán cả amount qua một route đơn lẻ tốt hơn thì trả về
+// route đó.
+//
+// Mỗi lần tìm đường (cả route đơn lẻ) dùng opts giống BestBidRoute: thuật
+// toán, bộ lọc, MaxHops, arbitrage loop, và route được kiểm tra, sửa bằng
+// validatedEdges, WithContext dừng việc tìm đường khi ctx kết thúc.
+// WithExactQuote, WithPartialResult, WithTopRoutes và WithMaxAmount không được
+// hỗ trợ và bị bỏ qua.
+//
+// Kết quả trả về lỗi ErrNoRoute nếu tổng thanh khoản không đủ để bán hết
+// amount, các lỗi khác giống BestBidRoute.
+func (g *graph) SplitBidPrice(base, quote string, amount decimal.Decimal,
+	parts int, opts ...QueryOption) (SplitResult, error) {
+	options := newQueryOptions(opts)
+	return g.splitPrice(options.context(), base, quote, amount, parts, true, options)
+}
+
+// SplitAskPrice tìm cách mua amount base token tốn ít quote token nhất, cho
+// phép chia lệnh qua nhiều route. Cách làm tương tự SplitBidPrice nhưng mô
+// phỏng việc mua (SimulateBuy) và route trả về đi từ quote về base như
+// BestAskPrice.
+func (g *graph) SplitAskPrice(base, quote string, amount decimal.Decimal,
+	parts int, opts ...QueryOption) (SplitResult, error) {
+	options := newQueryOptions(opts)
+	return g.splitPrice(options.context(), base, quote, amount, parts, false, options)
+}
+
+// splitPrice là phần chung của SplitBidPrice (sell = true) và SplitAskPrice.
+func (g *graph) splitPrice(ctx context.Context, base, quote string,
+	amount decimal.Decimal, parts int, sell bool, options QueryOptions) (SplitResult, error) {
+	options.ExactQuote, options.PartialResult = false, false
+	view := g.view(options)
+	result, err := view.split(ctx, base, quote, amount, parts, sell, options)
+	if err != nil {
+		return SplitResult{}, err
+	}
+
+	// So sánh với việc đi toàn bộ qua một route
+	edges, err := view.splitRoute(ctx, base, quote, amount, sell, options)
+	if err == nil {
+		_, value, ok := replay(edges, amount, sell)
+		if ok && ((sell && value.GreaterThan(result.AmountOut)) ||
+			(!sell && value.LessThan(result.AmountIn))) {
+			result = singleSplitResult(splitPath(base, edges, sell), amount, value, sell)
+		}
+	}
+
+	result.Version = g.version
+	return result, nil
+}
+
+// splitRoute tìm route cho amount trên g bằng search và trả về các cạnh từ
+// base tới quote đã được kiểm tra bằng validatedEdges.
+func (g *graph) splitRoute(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions) ([]Edge, error) {
+	result, err := g.search(ctx, base, quote, amount, sell, options)
+	if err != nil {
+		return nil, err
+	}
+	return g.validatedEdges(ctx, base, quote, amount, sell, options, &result)
+}
+
+// splitPath trả về đường đi của edges theo thứ tự của SplitRoute.
+func splitPath(base string, edges []Edge, sell bool) []string {
+	path := edgesPath(base, edges)
+	if !sell {
+		slices.Reverse(path)
+	}
+	return path
+}
+
+// split thực hiện phân bổ tham lam amount thành parts phần cho cả hai chiều
+// bid (sell = true) và ask (sell = false), g là view theo options.
+func (g *graph) split(ctx context.Context, base, quote string, amount decimal.Decimal,
+	parts int, sell bool, options QueryOptions) (SplitResult, error) {
+	if parts < 1 {
+		parts = 1
+	}
+	chunk := amount.QuoRound(decimal.NewFromInt(int64(parts)), decimal.RoundDown)
+
+	// residual là bản sao nông của đồ thị (giữ nguyên bộ lọc của view), các
+	// cạnh bị khớp sẽ được thay thế bằng cạnh còn lại, đồ thị gốc không bị
+	// thay đổi
+	residual := *g
+	residual.edges = g.edges.clone()
+
+	result := SplitResult{}
+	index := map[string]int{}
+	for i := range parts {
+		if i == parts-1 {
+			chunk = amount.Sub(chunk.Mul(decimal.NewFromInt(int64(parts - 1))))
+		}
+
+		edges, err := residual.splitRoute(ctx, base, quote, chunk, sell, options)
+		if err != nil {
+			return SplitResult{}, err
+		}
+		in, out, ok := residual.consume(edges, chunk, sell)
+		if !ok {
+			return SplitResult{}, ErrNoRoute
+		}
+
+		path := splitPath(base, edges, sell)
+		key := strings.Join(path, "->")
+		j, ok := index[key]
+		if !ok {
+			j = len(result.Routes)
+			index[key] = j
+			result.Routes = append(result.Routes, SplitRoute{Route: path})
+		}
+		r := &result.Routes[j]
+		r.AmountIn = r.AmountIn.Add(in)
+		r.AmountOut = r.AmountOut.Add(out)
+		result.AmountIn = result.AmountIn.Add(in)
+		result.AmountOut = result.AmountOut.Add(out)
+	}
+
+	for i := range result.Routes {
+		r := &result.Routes[i]
+		r.Fraction, r.Price = splitRatios(r.AmountIn, r.AmountOut, amount, sell)
+	}
+	_, result.Price = splitRatios(result.AmountIn, result.AmountOut, amount, sell)
+
+	return result, nil
+}
+
+// splitRatios tính tỷ lệ phân bổ và giá quote/base từ lượng token vào/ra
+// theo quy ước của SplitRoute. Giá bid được làm tròn xuống, giá ask được làm
+// tròn lên như BestBidPrice/BestAskPrice.
+func splitRatios(in, out, amount decimal.Decimal, sell bool) (
+	decimal.Decimal, decimal.Decimal) {
+	if sell {
+		return in.Quo(amount), out.QuoRound(in, decimal.RoundDown)
+	}
+	return out.Quo(amount), in.QuoRound(out, decimal.RoundUp)
+}
+
+// consume mô phỏng lại việc bán (hoặc mua) amount qua đúng các cạnh edges mà
+// thuật toán tìm đường đã chọn, theo chiều của cạnh từ base đến quote, sau đó
+// thay mỗi cạnh bằng cạnh còn lại sau khi khớp. Với các cạnh song song (nhiều
+// exchange), thanh khoản được trừ trên đúng order book đã dùng để tính giá.
+// Cạnh đảo ngược dùng chung order book (xem pairIndex) cũng được cập nhật để
+// các phần sau không khớp lại thanh khoản đã dùng theo chiều ngược lại.
+//
+// Kết quả trả về lượng token đưa vào và nhận về theo quy ước của SplitRoute,
+// ok = false nếu không mô phỏng được hết edges.
+func (g *graph) consume(edges []Edge, amount decimal.Decimal, sell bool) (
+	decimal.Decimal, decimal.Decimal, bool) {
+	current := amount
+	for _, edge := range edges {
+		value, _, _, ok := simulateWithFee(edge, current, sell)
+		if !ok {
+			return decimal.Zero, decimal.Zero, false
+		}
+
+		if c, ok := edge.(consumableEdge); ok {
+			i := g.edgeIndex(edge, current, value, sell)
+			if i == -1 {
+				return decimal.Zero, decimal.Zero, false
+			}
+			after := c.afterBuy(current)
+			if sell {
+				after = c.afterSell(current)
+			}
+			g.setEdge(edge.From(), i, after)
+			if j := g.pairIndex(edge); j != -1 {
+				pair := g.edges.from(edge.To())[j].(consumableEdge)
+				g.setEdge(edge.To(), j, pair.afterReverse(edge, after, sell))
+			}
+		}
+		current = value
+	}
+
+	if sell {
+		return amount, current, true
+	}
+	return current, amount, true
+}
+
+// setEdge thay cạnh thứ i của token bằng edge trên bản sao của slice
+// cạnh, các snapshot dùng chung slice cũ không bị thay đổi.
+func (g *graph) setEdge(token string, i int, edge Edge) {
+	outgoing := slices.Clone(g.edges.from(token))
+	outgoing[i] = edge
+	g.edges.set(token, outgoing)
+}
+
+// pairIndex trả về vị trí trong g.edges.from(edge.To()) của cạnh đảo ngược dùng
+// chung order book với edge: cùng exchange, ngược chiều và ngược giá trị
+// Reversed, -1 nếu không có.
+func (g *graph) pairIndex(edge Edge) int {
+	key := KeyOf(edge)
+	reverse := PairKey{From: key.To, To: key.From, Venue: key.Venue}
+	return slices.IndexFunc(g.edges.from(edge.To()), func(e Edge) bool {
+		_, ok := e.(consumableEdge)
+		return ok && KeyOf(e) == reverse && isReversed(e) != isReversed(edge)
+	})
+}
+
+// edgeIndex trả về vị trí của edge trong g.edges.from(edge.From()): cạnh cùng
+// PairKey và cho cùng kết quả value khi bán (hoặc mua) amount, -1 nếu không
+// tìm thấy. Cạnh không so sánh được bằng == vì OrderEdge chứa slice.
+func (g *graph) edgeIndex(edge Edge, amount, value decimal.Decimal, sell bool) int {
+	key := KeyOf(edge)
+	return slices.IndexFunc(g.edges.from(edge.From()), func(e Edge) bool {
+		if KeyOf(e) != key {
+			return false
+		}
+		got, _, _, ok := simulateWithFee(e, amount, sell)
+		return ok && got.Equal(value)
+	})
+}
+
+// singleSplitResult đóng gói kết quả của một route đơn lẻ thành SplitResult,
+// amount là lượng base token của lệnh, quoteAmount là lượng quote token thu
+// được (bid) hoặc phải trả (ask).
+func singleSplitResult(path []string, amount, quoteAmount decimal.Decimal,
+	sell bool) SplitResult {
+	in, out := quoteAmount, amount
+	if sell {
+		in, out = amount, quoteAmount
+	}
+	_, price := splitRatios(in, out, amount, sell)
+	return SplitResult{
+		Routes: []SplitRoute{{
+			Route:     path,
+			Fraction:  decimal.One,
+			AmountIn:  in,
+			AmountOut: out,
+			Price:     price,
+		}},
+		AmountIn:  in,
+		AmountOut: out,
+		Price:     price,
+	}
+}
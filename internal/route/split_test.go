@@ -0,0 +1,212 @@
+package route
+
+import (
+	"slices"
+	"testing"
+)
+
+// newSplitTestGraph tạo đồ thị KNC/USDT, KNC/ETH, ETH/USDT trong đó order
+// book KNC/USDT có giá tốt nhưng depth mỏng, route qua ETH giá kém hơn nhưng
+// còn thanh khoản.
+func newSplitTestGraph() Graph {
+	pairs := []OrderEdge{
+		{
+			BaseToken:  "KNC",
+			QuoteToken: "USDT",
+			BidOrders:  []Order{{Price: d("0.9"), Quantity: d("100")}, {Price: d("0.5"), Quantity: d("1000")}},
+			AskOrders:  []Order{{Price: d("1.0"), Quantity: d("100")}, {Price: d("2.0"), Quantity: d("1000")}},
+		},
+		{
+			BaseToken:  "KNC",
+			QuoteToken: "ETH",
+			BidOrders:  []Order{{Price: d("0.25"), Quantity: d("100")}},
+			AskOrders:  []Order{{Price: d("0.26"), Quantity: d("100")}},
+		},
+		{
+			BaseToken:  "ETH",
+			QuoteToken: "USDT",
+			BidOrders:  []Order{{Price: d("3.5"), Quantity: d("100")}},
+			AskOrders:  []Order{{Price: d("4.5"), Quantity: d("100")}},
+		},
+	}
+
+	edges := make([]Edge, 0, len(pairs)*2)
+	for _, pair := range pairs {
+		edges = append(edges, pair, pair.GetReverseEdge())
+	}
+	return NewGraphWithEdges(edges)
+}
+
+func TestGraph_SplitBidPrice(t *testing.T) {
+	g := newSplitTestGraph()
+
+	got, err := g.SplitBidPrice("KNC", "USDT", d("150"), 3)
+	if err != nil {
+		t.Fatalf("SplitBidPrice() error = %v", err)
+	}
+
+	// 2 phần đầu bán trực tiếp: 100 * 0.9 = 90
+	// phần cuối qua ETH: 50 * 0.25 * 3.5 = 43.75
+	if !got.AmountOut.Equal(d("133.75")) {
+		t.Errorf("AmountOut = %v, want 133.75", got.AmountOut)
+	}
+	// 133.75 / 150 làm tròn xuống
+	if !got.Price.Equal(d("0.891666666666666666")) {
+		t.Errorf("Price = %v, want 0.891666666666666666", got.Price)
+	}
+
+	want := []SplitRoute{
+		{Route: []string{"KNC", "USDT"}, Fraction: d("0.666666666666666667"), AmountIn: d("100"), AmountOut: d("90"), Price: d("0.9")},
+		{Route: []string{"KNC", "ETH", "USDT"}, Fraction: d("0.333333333333333333"), AmountIn: d("50"), AmountOut: d("43.75"), Price: d("0.875")},
+	}
+	assertSplitRoutes(t, got.Routes, want)
+}
+
+func TestGraph_SplitAskPrice(t *testing.T) {
+	g := newSplitTestGraph()
+
+	got, err := g.SplitAskPrice("KNC", "USDT", d("150"), 3)
+	if err != nil {
+		t.Fatalf("SplitAskPrice() error = %v", err)
+	}
+
+	// 2 phần đầu mua trực tiếp: 100 * 1.0 = 100
+	// phần cuối qua ETH: 50 * 0.26 * 4.5 = 58.5
+	if !got.AmountIn.Equal(d("158.5")) {
+		t.Errorf("AmountIn = %v, want 158.5", got.AmountIn)
+	}
+
+	want := []SplitRoute{
+		{Route: []string{"USDT", "KNC"}, Fraction: d("0.666666666666666667"), AmountIn: d("100"), AmountOut: d("100"), Price: d("1")},
+		{Route: []string{"USDT", "ETH", "KNC"}, Fraction: d("0.333333333333333333"), AmountIn: d("58.5"), AmountOut: d("50"), Price: d("1.17")},
+	}
+	assertSplitRoutes(t, got.Routes, want)
+}
+
+func TestGraph_SplitBidPrice_PrefersSingleRoute(t *testing.T) {
+	g := newSplitTestGraph()
+
+	// Lượng nhỏ, route trực tiếp đủ depth nên không cần chia
+	got, err := g.SplitBidPrice("KNC", "USDT", d("60"), 4)
+	if err != nil {
+		t.Fatalf("SplitBidPrice() error = %v", err)
+	}
+	if len(got.Routes) != 1 || !slices.Equal(got.Routes[0].Route, []string{"KNC", "USDT"}) {
+		t.Fatalf("Routes = %+v, want single KNC->USDT route", got.Routes)
+	}
+	if !got.Routes[0].Fraction.Equal(d("1")) || !got.AmountOut.Equal(d("54")) {
+		t.Errorf("got %+v, want fraction 1 and amount out 54", got)
+	}
+}
+
+func TestGraph_SplitBidPrice_NotEnoughDepth(t *testing.T) {
+	g := newSplitTestGraph()
+
+	if _, err := g.SplitBidPrice("KNC", "USDT", d("5000"), 5); err != ErrNoRoute {
+		t.Errorf("SplitBidPrice() error = %v, want %v", err, ErrNoRoute)
+	}
+}
+
+func TestGraph_SplitBidPrice_Options(t *testing.T) {
+	g := newSplitTestGraph()
+
+	// Chỉ được đi tối đa 1 chặng: cả 3 phần bán trực tiếp,
+	// 100 * 0.9 + 50 * 0.5 = 115
+	got, err := g.SplitBidPrice("KNC", "USDT", d("150"), 3, WithMaxHops(1))
+	if err != nil {
+		t.Fatalf("SplitBidPrice(WithMaxHops(1)) error = %v", err)
+	}
+	if len(got.Routes) != 1 || !slices.Equal(got.Routes[0].Route, []string{"KNC", "USDT"}) ||
+		!got.AmountOut.Equal(d("115")) {
+		t.Errorf("SplitBidPrice(WithMaxHops(1)) = %+v, want KNC->USDT only with 115", got)
+	}
+
+	// Không dùng cặp KNC/USDT: 60 * 0.25 * 3.5 = 52.5 qua ETH
+	got, err = g.SplitBidPrice("KNC", "USDT", d("60"), 3,
+		WithExcludedPairs(PairKey{From: "KNC", To: "USDT"}))
+	if err != nil {
+		t.Fatalf("SplitBidPrice(WithExcludedPairs) error = %v", err)
+	}
+	if len(got.Routes) != 1 || !slices.Equal(got.Routes[0].Route, []string{"KNC", "ETH", "USDT"}) ||
+		!got.AmountOut.Equal(d("52.5")) {
+		t.Errorf("SplitBidPrice(WithExcludedPairs) = %+v, want KNC->ETH->USDT with 52.5", got)
+	}
+}
+
+func TestGraph_Consume_ReversePair(t *testing.T) {
+	forward := OrderEdge{
+		BaseToken: "KNC", QuoteToken: "USDT",
+		BidOrders: []Order{{Price: d("0.9"), Quantity: d("100")}},
+	}
+	reverse := forward.GetReverseEdge()
+	g := NewGraphWithEdges([]Edge{forward, reverse}).(*syncGraph).snapshot()
+	residual := &graph{edges: g.edges.clone()}
+
+	// Bán 60 KNC khớp bid orders của KNC/USDT, tức ask orders của cạnh đảo
+	// ngược: chỉ còn 40 * 0.9 = 36 USDT mua được theo chiều ngược lại
+	if _, _, ok := residual.consume([]Edge{forward}, d("60"), true); !ok {
+		t.Fatalf("consume(sell KNC) failed")
+	}
+	asks := residual.edges.from("USDT")[0].(OrderEdge).AskOrders
+	if len(asks) != 1 || !asks[0].Quantity.Equal(d("36")) {
+		t.Fatalf("reverse asks = %v, want 36 USDT left", asks)
+	}
+	if _, _, ok := residual.consume([]Edge{residual.edges.from("USDT")[0]}, d("50"), false); ok {
+		t.Errorf("consume(buy 50 USDT) succeeded on liquidity already used")
+	}
+
+	// Mua 30 USDT theo chiều ngược lại dùng tiếp order book đó, còn 6/36
+	if _, _, ok := residual.consume([]Edge{residual.edges.from("USDT")[0]}, d("30"), false); !ok {
+		t.Fatalf("consume(buy 30 USDT) failed")
+	}
+	bids := residual.edges.from("KNC")[0].(OrderEdge).BidOrders
+	if len(bids) != 1 || !bids[0].Quantity.Equal(d("6.666666666666666666")) {
+		t.Errorf("forward bids = %v, want 6.666666666666666666 KNC left", bids)
+	}
+}
+
+func assertSplitRoutes(t *testing.T, got, want []SplitRoute) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d routes %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !slices.Equal(g.Route, w.Route) || !g.Fraction.Equal(w.Fraction) ||
+			!g.AmountIn.Equal(w.AmountIn) ||
+			!g.AmountOut.Equal(w.AmountOut) || !g.Price.Equal(w.Price) {
+			t.Errorf("route %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestGraph_Consume_ChosenVenue(t *testing.T) {
+	binance := OrderEdge{
+		BaseToken: "KNC", QuoteToken: "USDT", Venue: "binance",
+		BidOrders: []Order{{Price: d("0.9"), Quantity: d("100")}},
+	}
+	kraken := binance
+	kraken.Venue = "kraken"
+	kraken.BidOrders = []Order{{Price: d("0.8"), Quantity: d("100")}}
+	g := NewGraphWithEdges([]Edge{binance, kraken}).(*syncGraph).current.Load()
+	residual := &graph{edges: g.edges.clone()}
+
+	// Khớp 60 KNC trên kraken dù binance có giá tốt hơn: chỉ order book của
+	// kraken bị trừ
+	in, out, ok := residual.consume([]Edge{kraken}, d("60"), true)
+	if !ok || !in.Equal(d("60")) || !out.Equal(d("48")) {
+		t.Fatalf("consume() = (%v, %v, %v), want (60, 48, true)", in, out, ok)
+	}
+	remaining := map[string]string{}
+	for _, edge := range residual.edges.from("KNC") {
+		orderEdge := edge.(OrderEdge)
+		remaining[orderEdge.Venue] = orderEdge.BidOrders[0].Quantity.String()
+	}
+	if remaining["binance"] != "100" || remaining["kraken"] != "40" {
+		t.Errorf("remaining bids = %v, want binance 100, kraken 40", remaining)
+	}
+	if len(g.edges.from("KNC")[1].(OrderEdge).BidOrders) != 1 ||
+		!g.edges.from("KNC")[1].(OrderEdge).BidOrders[0].Quantity.Equal(d("100")) {
+		t.Errorf("consume() changed the original graph")
+	}
+}
@@ -0,0 +1,207 @@
+package route
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// PairKey định danh một cạnh theo trading pair, chiều giao dịch và exchange:
+// cạnh đi từ token From đến token To trên exchange Venue. Cạnh gốc và cạnh đảo
+// ngược của cùng một trading pair có PairKey khác nhau.
+type PairKey struct {
+	From  string
+	To    string
+	Venue string
+}
+
+// KeyOf trả về PairKey của cạnh e.
+func KeyOf(e Edge) PairKey {
+	return PairKey{From: e.From(), To: e.To(), Venue: VenueOf(e)}
+}
+
+// syncGraph là Graph an toàn khi dùng đồng thời từ nhiều goroutine.
+//
+// Mỗi phiên bản của đồ thị là một graph bất biến (snapshot), được publish qua
+// atomic.Pointer. Các query chỉ đọc snapshot hiện tại nên không bao giờ bị
+// chặn và luôn thấy một trạng thái nhất quán trong suốt quá trình tìm đường.
+// Các thao tác ghi được tuần tự hóa bởi mu, mỗi lần ghi sao chép lớp thay đổi
+// của edges (xem edgeMap) và slice cạnh của token bị thay đổi (copy-on-write),
+// rồi publish snapshot mới với version tăng thêm 1.
+type syncGraph struct {
+	mu      sync.Mutex
+	current atomic.Pointer[graph]
+}
+
+func newSyncGraph(g *graph) *syncGraph {
+	s := &syncGraph{}
+	s.current.Store(g)
+	return s
+}
+
+// snapshot trả về phiên bản hiện tại của đồ thị, không được thay đổi.
+func (s *syncGraph) snapshot() *graph {
+	return s.current.Load()
+}
+
+// update áp dụng mutate lên bản sao của snapshot hiện tại và publish bản sao
+// đó nếu mutate trả về true. Kết quả trả về version của snapshot hiện tại sau
+// khi cập nhật.
+func (s *syncGraph) update(mutate func(next *graph) bool) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.current.Load()
+	next := &graph{
+		edges:   current.edges.clone(),
+		version: current.version + 1,
+	}
+	if !mutate(next) {
+		return current.version
+	}
+	s.current.Store(next)
+	return next.version
+}
+
+// AddEdge thêm cạnh e vào đồ thị, kể cả khi đã có cạnh cùng PairKey.
+func (s *syncGraph) AddEdge(e Edge) {
+	s.update(func(next *graph) bool {
+		next.edges.set(e.From(), append(slices.Clip(next.edges.from(e.From())), e))
+		return true
+	})
+}
+
+// UpdateEdge thay thế các cạnh có cùng PairKey với e bằng e, giữ nguyên vị trí
+// của cạnh đầu tiên, thêm e vào đồ thị nếu chưa có. Kết quả trả về version mới
+// của đồ thị.
+func (s *syncGraph) UpdateEdge(e Edge) uint64 {
+	return s.update(func(next *graph) bool {
+		next.replaceEdge(e)
+		return true
+	})
+}
+
+// RemoveEdge xóa các cạnh có PairKey bằng key. Kết quả trả về version của đồ
+// thị, không thay đổi nếu không có cạnh nào bị xóa.
+func (s *syncGraph) RemoveEdge(key PairKey) uint64 {
+	return s.update(func(next *graph) bool {
+		return next.removeEdges(func(e Edge) bool { return KeyOf(e) == key }, key.From)
+	})
+}
+
+// ReplacePair thay thế toàn bộ cạnh theo cả hai chiều giữa key.From (base) và
+// key.To (quote) trên exchange key.Venue bằng edges trong một lần cập nhật,
+// thường là cạnh gốc và cạnh đảo ngược của một trading pair. Cạnh của cùng
+// cặp token trên exchange khác không bị thay đổi. Query đồng thời chỉ thấy
+// toàn bộ cạnh cũ hoặc toàn bộ cạnh mới. Trả về ErrEdgeNotInPair và không
+// thay đổi đồ thị nếu có cạnh không nối base với quote hoặc không thuộc
+// exchange key.Venue.
+func (s *syncGraph) ReplacePair(key PairKey, edges ...Edge) (uint64, error) {
+	base, quote := key.From, key.To
+	for _, e := range edges {
+		if !(e.From() == base && e.To() == quote) &&
+			!(e.From() == quote && e.To() == base) || VenueOf(e) != key.Venue {
+			return s.Version(), ErrEdgeNotInPair
+		}
+	}
+
+	return s.update(func(next *graph) bool {
+		next.removeEdges(func(e Edge) bool { return e.To() == quote && VenueOf(e) == key.Venue }, base)
+		next.removeEdges(func(e Edge) bool { return e.To() == base && VenueOf(e) == key.Venue }, quote)
+		for _, e := range edges {
+			next.edges.set(e.From(), append(slices.Clip(next.edges.from(e.From())), e))
+		}
+		return true
+	}), nil
+}
+
+// Version trả về version của snapshot hiện tại, tăng thêm 1 sau mỗi lần cập
+// nhật đồ thị.
+func (s *syncGraph) Version() uint64 {
+	return s.snapshot().version
+}
+
+// Neighbors trả về danh sách các cạnh xuất phát từ token truyền vào tại
+// snapshot hiện tại. Slice trả về dùng chung với snapshot, không được thay đổi.
+func (s *syncGraph) Neighbors(token string) []Edge {
+	return s.snapshot().Neighbors(token)
+}
+
+func (s *syncGraph) BestBidPrice(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (decimal.Decimal, []string, error) {
+	return s.snapshot().BestBidPrice(base, quote, amount, opts...)
+}
+
+func (s *syncGraph) BestAskPrice(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (decimal.Decimal, []string, error) {
+	return s.snapshot().BestAskPrice(base, quote, amount, opts...)
+}
+
+func (s *syncGraph) BestBidRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (RouteResult, error) {
+	return s.snapshot().BestBidRoute(base, quote, amount, opts...)
+}
+
+func (s *syncGraph) BestAskRoute(base, quote string, amount decimal.Decimal,
+	opts ...QueryOption) (RouteResult, error) {
+	return s.snapshot().BestAskRoute(base, quote, amount, opts...)
+}
+
+func (s *syncGraph) BidCurve(base, quote string, amounts []decimal.Decimal,
+	opts ...QueryOption) (PriceCurve, error) {
+	return s.snapshot().BidCurve(base, quote, amounts, opts...)
+}
+
+func (s *syncGraph) AskCurve(base, quote string, amounts []decimal.Decimal,
+	opts ...QueryOption) (PriceCurve, error) {
+	return s.snapshot().AskCurve(base, quote, amounts, opts...)
+}
+
+func (s *syncGraph) SplitBidPrice(base, quote string, amount decimal.Decimal,
+	parts int, opts ...QueryOption) (SplitResult, error) {
+	return s.snapshot().SplitBidPrice(base, quote, amount, parts, opts...)
+}
+
+func (s *syncGraph) SplitAskPrice(base, quote string, amount decimal.Decimal,
+	parts int, opts ...QueryOption) (SplitResult, error) {
+	return s.snapshot().SplitAskPrice(base, quote, amount, parts, opts...)
+}
+
+// removeEdges xóa các cạnh xuất phát từ token from thỏa mãn match khỏi g, chỉ
+// dùng cho bản sao chưa được publish. Slice cạnh mới được cấp phát lại, không
+// ghi đè lên slice của snapshot cũ. Trả về false nếu không có cạnh nào bị xóa.
+func (g *graph) removeEdges(match func(e Edge) bool, from string) bool {
+	edges := g.edges.from(from)
+	remaining := slices.DeleteFunc(slices.Clone(edges), match)
+	if len(remaining) == len(edges) {
+		return false
+	}
+
+	// Giống AddEdge, g.edges chỉ gồm các token có cạnh đi ra, set xóa token
+	// nếu remaining rỗng
+	g.edges.set(from, remaining)
+	return true
+}
+
+// replaceEdge thay cạnh đầu tiên có cùng PairKey với e bằng e và xóa các cạnh
+// cùng PairKey còn lại, thêm e vào cuối nếu chưa có. Chỉ dùng cho bản sao
+// chưa được publish.
+func (g *graph) replaceEdge(e Edge) {
+	key := KeyOf(e)
+	edges := make([]Edge, 0, len(g.edges.from(key.From))+1)
+	replaced := false
+	for _, edge := range g.edges.from(key.From) {
+		if KeyOf(edge) != key {
+			edges = append(edges, edge)
+		} else if !replaced {
+			edges = append(edges, e)
+			replaced = true
+		}
+	}
+	if !replaced {
+		edges = append(edges, e)
+	}
+	g.edges.set(key.From, edges)
+}
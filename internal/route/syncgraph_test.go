@@ -0,0 +1,234 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func newSyncTestGraph() Graph {
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1.1")}
+	return NewGraphWithEdges([]Edge{kncUSDT, kncUSDT.GetReverseEdge()})
+}
+
+func TestGraph_UpdateEdge(t *testing.T) {
+	g := newSyncTestGraph()
+	if got := g.Version(); got != 0 {
+		t.Fatalf("Version() = %d, want 0", got)
+	}
+
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("2"), AskPrice: d("2.1")}
+	version := g.UpdateEdge(kncUSDT)
+	if version != 1 || g.Version() != 1 {
+		t.Errorf("UpdateEdge() = %d, Version() = %d, want 1", version, g.Version())
+	}
+	if got := len(g.Neighbors("KNC")); got != 1 {
+		t.Errorf("len(Neighbors(KNC)) = %d, want 1", got)
+	}
+
+	// Chỉ cập nhật một chiều, cạnh đảo ngược cũ tạo ra arbitrage loop
+	if _, err := g.BestBidRoute("KNC", "USDT", d("1")); !errors.Is(err, ErrArbitrageLoop) {
+		t.Errorf("BestBidRoute() error = %v, want ErrArbitrageLoop", err)
+	}
+
+	g.UpdateEdge(kncUSDT.GetReverseEdge())
+	got, err := g.BestBidRoute("KNC", "USDT", d("1"))
+	if err != nil || !got.Price.Equal(d("2")) || got.Version != 2 {
+		t.Errorf("BestBidRoute() = (%v, version %d, %v), want (2, version 2, nil)",
+			got.Price, got.Version, err)
+	}
+}
+
+func TestGraph_RemoveEdge(t *testing.T) {
+	g := newSyncTestGraph()
+
+	if version := g.RemoveEdge(PairKey{From: "KNC", To: "ETH"}); version != 0 {
+		t.Errorf("RemoveEdge(missing) = %d, want 0", version)
+	}
+	if version := g.RemoveEdge(PairKey{From: "KNC", To: "USDT"}); version != 1 {
+		t.Errorf("RemoveEdge() = %d, want 1", version)
+	}
+	if _, _, err := g.BestBidPrice("KNC", "USDT", d("1")); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("BestBidPrice() error = %v, want ErrNoRoute", err)
+	}
+}
+
+func TestGraph_ReplacePair(t *testing.T) {
+	g := newSyncTestGraph()
+
+	ethUSDT := SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("400"), AskPrice: d("400")}
+	if _, err := g.ReplacePair(PairKey{From: "KNC", To: "USDT"}, ethUSDT); !errors.Is(err, ErrEdgeNotInPair) {
+		t.Errorf("ReplacePair() error = %v, want ErrEdgeNotInPair", err)
+	}
+	if g.Version() != 0 {
+		t.Errorf("Version() = %d after failed ReplacePair, want 0", g.Version())
+	}
+
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("3"), AskPrice: d("4")}
+	version, err := g.ReplacePair(PairKey{From: "KNC", To: "USDT"}, kncUSDT, kncUSDT.GetReverseEdge())
+	if err != nil || version != 1 {
+		t.Fatalf("ReplacePair() = (%d, %v), want (1, nil)", version, err)
+	}
+	price, _, err := g.BestAskPrice("KNC", "USDT", d("1"))
+	if err != nil || !price.Equal(d("4")) {
+		t.Errorf("BestAskPrice() = (%v, %v), want (4, nil)", price, err)
+	}
+	if got := len(g.Neighbors("USDT")); got != 1 {
+		t.Errorf("len(Neighbors(USDT)) = %d, want 1", got)
+	}
+}
+
+func TestGraph_ReplacePair_KeepsOtherVenues(t *testing.T) {
+	binance := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1.1"),
+		Venue: "binance"}
+	kyber := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("0.9"), AskPrice: d("1.2"),
+		Venue: "kyber"}
+	g := NewGraphWithEdges([]Edge{binance, binance.GetReverseEdge(), kyber, kyber.GetReverseEdge()})
+
+	key := PairKey{From: "KNC", To: "USDT", Venue: "binance"}
+	if _, err := g.ReplacePair(key, kyber); !errors.Is(err, ErrEdgeNotInPair) {
+		t.Errorf("ReplacePair(other venue) error = %v, want ErrEdgeNotInPair", err)
+	}
+
+	binance.BidPrice, binance.AskPrice = d("1.05"), d("1.15")
+	if _, err := g.ReplacePair(key, binance, binance.GetReverseEdge()); err != nil {
+		t.Fatalf("ReplacePair() error = %v", err)
+	}
+	for _, token := range []string{"KNC", "USDT"} {
+		venues := map[string]int{}
+		for _, e := range g.Neighbors(token) {
+			venues[VenueOf(e)]++
+		}
+		if venues["binance"] != 1 || venues["kyber"] != 1 {
+			t.Errorf("Neighbors(%s) venues = %v, want one edge on each venue", token, venues)
+		}
+	}
+	price, _, err := g.BestBidPrice("KNC", "USDT", d("1"))
+	if err != nil || !price.Equal(d("1.05")) {
+		t.Errorf("BestBidPrice() = (%v, %v), want (1.05, nil) from the new binance edge", price, err)
+	}
+
+	// Xóa cặp trên binance, cạnh trên kyber vẫn được dùng
+	if _, err := g.ReplacePair(key); err != nil {
+		t.Fatalf("ReplacePair(no edges) error = %v", err)
+	}
+	price, _, err = g.BestBidPrice("KNC", "USDT", d("1"))
+	if err != nil || !price.Equal(d("0.9")) {
+		t.Errorf("BestBidPrice() = (%v, %v), want (0.9, nil) from kyber", price, err)
+	}
+}
+
+func TestGraph_ConcurrentQueriesAndUpdates(t *testing.T) {
+	g := newSyncTestGraph()
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				got, err := g.BestBidRoute("KNC", "USDT", d("1"))
+				if err != nil {
+					t.Errorf("BestBidRoute() error = %v", err)
+					return
+				}
+				// Giá luôn khớp với version của snapshot đã đọc
+				if !got.Price.Equal(d("1").Add(decimal.NewFromInt(int64(got.Version)))) {
+					t.Errorf("reader %d: price %v at version %d", i, got.Price, got.Version)
+					return
+				}
+			}
+		}()
+	}
+
+	for version := uint64(1); version <= 200; version++ {
+		price := d("1").Add(decimal.NewFromInt(int64(version)))
+		edge := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: price, AskPrice: price}
+		g.ReplacePair(PairKey{From: "KNC", To: "USDT"}, edge, edge.GetReverseEdge())
+	}
+	wg.Wait()
+}
+
+func TestGraph_ReplacePair_SharesOtherTokens(t *testing.T) {
+	edges := make([]Edge, 0, 2000)
+	for i := range 1000 {
+		edge := SimpleEdge{BaseToken: fmt.Sprintf("T%d", i), QuoteToken: "USDT", BidPrice: d("1"), AskPrice: d("1.1")}
+		edges = append(edges, edge, edge.GetReverseEdge())
+	}
+	g := NewGraphWithEdges(edges).(*syncGraph)
+
+	// Lần cập nhật đầu gộp các cạnh khởi tạo thành base, các lần sau chỉ sao
+	// chép lớp thay đổi, base dùng chung với snapshot trước
+	edge := SimpleEdge{BaseToken: "T0", QuoteToken: "USDT", BidPrice: d("2"), AskPrice: d("2.1")}
+	g.ReplacePair(PairKey{From: "T0", To: "USDT"}, edge, edge.GetReverseEdge())
+	for i := 1; i <= 10; i++ {
+		before := g.snapshot()
+		edge := SimpleEdge{BaseToken: fmt.Sprintf("T%d", i), QuoteToken: "USDT", BidPrice: d("2"), AskPrice: d("2.1")}
+		g.ReplacePair(PairKey{From: edge.BaseToken, To: "USDT"}, edge, edge.GetReverseEdge())
+		after := g.snapshot()
+
+		if reflect.ValueOf(after.edges.base).UnsafePointer() != reflect.ValueOf(before.edges.base).UnsafePointer() {
+			t.Fatalf("ReplacePair(%s) copied the base map", edge.BaseToken)
+		}
+		if n := len(after.edges.changes); n > 2*(i+1) {
+			t.Errorf("ReplacePair(%s) left %d changed tokens, want at most %d", edge.BaseToken, n, 2*(i+1))
+		}
+		if got := g.Neighbors(edge.BaseToken); len(got) != 1 || !got[0].(SimpleEdge).BidPrice.Equal(d("2")) {
+			t.Errorf("Neighbors(%s) = %v, want the new edge", edge.BaseToken, got)
+		}
+		if got := len(before.edges.from(edge.BaseToken)); got != 1 ||
+			!before.edges.from(edge.BaseToken)[0].(SimpleEdge).BidPrice.Equal(d("1")) {
+			t.Errorf("ReplacePair(%s) changed the previous snapshot", edge.BaseToken)
+		}
+	}
+	if got := g.snapshot().edges.len(); got != 1001 {
+		t.Errorf("edges.len() = %d, want 1001", got)
+	}
+}
+
+// TestEdgeMap_Random so sánh edgeMap với map thường qua các lần set và clone
+// ngẫu nhiên, kể cả khi lớp thay đổi được gộp vào base.
+func TestEdgeMap_Random(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var m edgeMap
+	want := map[string][]Edge{}
+	// Mỗi cạnh có BidPrice khác nhau để phân biệt
+	sameEdges := func(a, b []Edge) bool {
+		return slices.EqualFunc(a, b, func(x, y Edge) bool {
+			return x.(SimpleEdge).BidPrice.Equal(y.(SimpleEdge).BidPrice)
+		})
+	}
+	for i := range 2000 {
+		token := fmt.Sprintf("T%d", rng.IntN(50))
+		var edges []Edge
+		if rng.IntN(3) > 0 {
+			edges = []Edge{SimpleEdge{BaseToken: token, QuoteToken: "USDT", BidPrice: decimal.NewFromInt(int64(i))}}
+		}
+		if rng.IntN(4) == 0 {
+			m = m.clone()
+		}
+		m.set(token, edges)
+		if len(edges) == 0 {
+			delete(want, token)
+		} else {
+			want[token] = edges
+		}
+
+		got := maps.Collect(m.all())
+		if m.len() != len(want) || len(got) != len(want) {
+			t.Fatalf("len() = %d, all() has %d tokens, want %d", m.len(), len(got), len(want))
+		}
+		for token, edges := range want {
+			if !sameEdges(got[token], edges) || !sameEdges(m.from(token), edges) || !m.has(token) {
+				t.Fatalf("token %s = %v, want %v", token, m.from(token), edges)
+			}
+		}
+	}
+}
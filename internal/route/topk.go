@@ -0,0 +1,231 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ErrInvalidRouteCount nghĩa là số route yêu cầu của WithTopRoutes không
+// dương.
+var ErrInvalidRouteCount = errors.New("route count must be positive")
+
+// rankedPath là một đường đi đơn gồm các cạnh edges cùng lượng token thu được
+// (sell) hoặc cần thiết (mua) ở token cuối khi mô phỏng lại.
+type rankedPath struct {
+	edges []Edge
+	value decimal.Decimal
+}
+
+// key định danh đường đi theo PairKey của từng cạnh, nên hai đường đi qua cùng
+// các token trên các exchange khác nhau là hai đường đi khác nhau.
+func (p rankedPath) key() string {
+	var b strings.Builder
+	for _, edge := range p.edges {
+		key := KeyOf(edge)
+		fmt.Fprintf(&b, "%s>%s@%s|", key.From, key.To, key.Venue)
+	}
+	return b.String()
+}
+
+// better kiểm tra p có tốt hơn other hay không: thu được nhiều hơn khi bán, tốn
+// ít hơn khi mua. Khi bằng nhau, đường đi ít chặng hơn rồi đường đi có key nhỏ
+// hơn được ưu tiên để kết quả tái lập được.
+func (p rankedPath) better(other rankedPath, sell bool) bool {
+	if cmp := p.value.Cmp(other.value); cmp != 0 {
+		return (cmp > 0) == sell
+	}
+	if len(p.edges) != len(other.edges) {
+		return len(p.edges) < len(other.edges)
+	}
+	return p.key() < other.key()
+}
+
+// topRoutes trả về tối đa k route khác nhau tốt nhất khi bán (sell = true)
+// hoặc mua amount base token, sắp xếp từ tốt tới kém, route đầu tiên giống
+// bestRoute, xem WithTopRoutes. Trả về ErrInvalidRouteCount nếu k < 1, các lỗi
+// khác giống bestRoute. Ít hơn k route được trả về nếu đồ thị không có đủ
+// route khả thi.
+func (g *graph) topRoutes(ctx context.Context, base, quote string,
+	amount decimal.Decimal, k int, sell bool, options QueryOptions) ([]RouteResult, error) {
+	if k < 1 {
+		return nil, ErrInvalidRouteCount
+	}
+	options.PartialResult = false
+	view := g.view(options)
+
+	// Với WithExactQuote, route được tìm ngược trên đồ thị inverse giống
+	// bestRouteExactQuote, lượng base token của mỗi route là value của đường
+	// đi ngược
+	from, to, searchSell, space := base, quote, sell, view
+	if options.ExactQuote {
+		from, to, searchSell, space = quote, base, !sell, view.inverse()
+	}
+	paths, first, err := space.topPaths(ctx, from, to, amount, k, searchSell, options)
+	if err != nil {
+		if options.ExactQuote {
+			err = uninvert(err)
+		}
+		return nil, g.liquidityError(base, quote, amount, sell, options, err)
+	}
+	if options.ExactQuote {
+		for i, warning := range first.warnings {
+			first.warnings[i] = uninvertCycle(warning)
+		}
+	}
+
+	results := make([]RouteResult, 0, len(paths))
+	for _, ranked := range paths {
+		edges, baseAmount := ranked.edges, amount
+		if options.ExactQuote {
+			edges, baseAmount = uninvertEdges(edges), ranked.value
+		}
+		result, err := view.newRouteResult(base, edges, baseAmount, sell, first)
+		if err != nil {
+			continue
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, ErrNoRoute
+	}
+	return results, nil
+}
+
+// topPaths tìm tối đa k đường đi đơn khác nhau tốt nhất từ base tới quote bằng
+// thuật toán của Yen điều chỉnh cho cạnh có giá phụ thuộc amount. Đường đi tốt
+// nhất được tìm bằng search, mỗi đường đi tiếp theo được chọn trong các ứng
+// viên tạo từ đường đi trước đó: với mỗi token spur trên đường đi,
+//   - root là đoạn từ base tới spur, giữ nguyên
+//   - các cạnh tiếp theo sau root của các đường đi đã chọn có cùng root bị
+//     loại bỏ (theo PairKey, các cạnh bị lặp lại cùng PairKey bị loại bỏ cùng
+//     nhau), các token của root trước spur cũng bị loại bỏ
+//   - đoạn spur từ spur tới quote được tìm bằng search với lượng token thu
+//     được (sell) hoặc cần thiết (mua) ở spur khi mô phỏng root
+//
+// Ứng viên là root ghép với đoạn spur và được mô phỏng lại từ đầu. Vì giá của
+// cạnh phụ thuộc amount và search là heuristic, các route trả về là k route
+// tốt nhất tìm được theo cách này, không đảm bảo là k route tốt nhất tuyệt
+// đối.
+//
+// Kết quả trả về các đường đi theo thứ tự từ tốt tới kém và searchResult của
+// lần tìm đường đầu tiên. Lỗi của lần tìm đường đầu tiên được trả về nguyên
+// vẹn, lỗi khác lỗi của ctx khi tìm đoạn spur chỉ làm bỏ qua ứng viên đó.
+func (g *graph) topPaths(ctx context.Context, base, quote string,
+	amount decimal.Decimal, k int, sell bool, options QueryOptions) (
+	[]rankedPath, searchResult, error) {
+	first, err := g.search(ctx, base, quote, amount, sell, options)
+	if err != nil {
+		return nil, searchResult{}, err
+	}
+	edges, err := g.validatedEdges(ctx, base, quote, amount, sell, options, &first)
+	if err != nil {
+		return nil, searchResult{}, err
+	}
+
+	chosen := []rankedPath{{edges: edges, value: first.values[quote]}}
+	seen := map[string]bool{chosen[0].key(): true}
+	var candidates []rankedPath
+	for len(chosen) < k {
+		last := chosen[len(chosen)-1].edges
+		for i := range last {
+			candidate, ok, err := g.spurPath(ctx, chosen, base, last[:i], quote,
+				amount, sell, options)
+			if err != nil {
+				return nil, searchResult{}, err
+			}
+			if !ok || seen[candidate.key()] {
+				continue
+			}
+			seen[candidate.key()] = true
+			candidates = append(candidates, candidate)
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		best := 0
+		for j := range candidates {
+			if candidates[j].better(candidates[best], sell) {
+				best = j
+			}
+		}
+		chosen = append(chosen, candidates[best])
+		candidates = slices.Delete(candidates, best, best+1)
+	}
+	return chosen, first, nil
+}
+
+// spurPath tạo ứng viên của topPaths gồm các cạnh root từ base tới token spur
+// và đoạn spur tìm được từ spur tới quote, false nếu không tìm được. Giống
+// route tốt nhất, đoạn spur được kiểm tra và sửa bằng validatedEdges, ứng viên
+// phải là đường đi đơn từ base tới quote mô phỏng lại được. Chỉ lỗi của ctx
+// được trả về.
+func (g *graph) spurPath(ctx context.Context, chosen []rankedPath, base string,
+	root []Edge, quote string, amount decimal.Decimal, sell bool,
+	options QueryOptions) (rankedPath, bool, error) {
+	tokens := edgesPath(base, root)
+	spur := tokens[len(tokens)-1]
+	removedTokens := make(map[string]bool, len(root))
+	for _, token := range tokens[:len(root)] {
+		removedTokens[token] = true
+	}
+	rootKey := rankedPath{edges: root}.key()
+	removedNext := map[PairKey]bool{}
+	for _, ranked := range chosen {
+		if len(ranked.edges) > len(root) &&
+			(rankedPath{edges: ranked.edges[:len(root)]}).key() == rootKey {
+			removedNext[KeyOf(ranked.edges[len(root)])] = true
+		}
+	}
+	spurGraph := g.withFilter(func(e Edge) bool {
+		if removedTokens[e.From()] || removedTokens[e.To()] {
+			return false
+		}
+		return e.From() != spur || !removedNext[KeyOf(e)]
+	})
+
+	// Đoạn spur dùng số chặng còn lại và chỉ cần đi qua các token bắt buộc
+	// chưa có trong root
+	if options.MaxHops > 0 {
+		if options.MaxHops <= len(root) {
+			return rankedPath{}, false, nil
+		}
+		options.MaxHops -= len(root)
+	}
+	options.RequiredTokens = slices.DeleteFunc(slices.Clone(options.RequiredTokens),
+		func(token string) bool { return slices.Contains(tokens, token) })
+
+	_, start, ok := replay(root, amount, sell)
+	if !ok {
+		return rankedPath{}, false, nil
+	}
+	result, err := spurGraph.search(ctx, spur, quote, start, sell, options)
+	if err != nil {
+		if isContextError(err) {
+			return rankedPath{}, false, err
+		}
+		return rankedPath{}, false, nil
+	}
+	tail, err := spurGraph.validatedEdges(ctx, spur, quote, start, sell, options, &result)
+	if err != nil {
+		if isContextError(err) {
+			return rankedPath{}, false, err
+		}
+		return rankedPath{}, false, nil
+	}
+
+	edges := append(slices.Clone(root), tail...)
+	if simplePathError(edges, base, quote) != "" {
+		return rankedPath{}, false, nil
+	}
+	_, value, ok := replay(edges, amount, sell)
+	if !ok {
+		return rankedPath{}, false, nil
+	}
+	return rankedPath{edges: edges, value: value}, true, nil
+}
@@ -0,0 +1,144 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// newTopKTestGraph tạo đồ thị có 3 route từ KNC tới USDT: trực tiếp, qua ETH
+// và qua DAI.
+func newTopKTestGraph() Graph {
+	pairs := []SimpleEdge{
+		{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("0.9"), AskPrice: d("1.1")},
+		{BaseToken: "KNC", QuoteToken: "ETH", BidPrice: d("0.0024"), AskPrice: d("0.0026")},
+		{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("350"), AskPrice: d("360")},
+		{BaseToken: "KNC", QuoteToken: "DAI", BidPrice: d("0.85"), AskPrice: d("1.2")},
+		{BaseToken: "DAI", QuoteToken: "USDT", BidPrice: d("0.98"), AskPrice: d("1.02")},
+	}
+	edges := make([]Edge, 0, 2*len(pairs))
+	for _, pair := range pairs {
+		edges = append(edges, pair, pair.GetReverseEdge())
+	}
+	return NewGraphWithEdges(edges)
+}
+
+func TestGraph_TopRoutes(t *testing.T) {
+	g := newTopKTestGraph()
+
+	tests := []struct {
+		name       string
+		sell       bool
+		k          int
+		opts       []QueryOption
+		wantRoutes [][]string
+		wantPrices []string
+	}{
+		{name: "Bid", sell: true, k: 2,
+			wantRoutes: [][]string{{"KNC", "USDT"}, {"KNC", "ETH", "USDT"}},
+			wantPrices: []string{"0.9", "0.84"}},
+		{name: "Bid all routes", sell: true, k: 5,
+			wantRoutes: [][]string{{"KNC", "USDT"}, {"KNC", "ETH", "USDT"}, {"KNC", "DAI", "USDT"}},
+			wantPrices: []string{"0.9", "0.84", "0.833"}},
+		{name: "Ask", sell: false, k: 3,
+			wantRoutes: [][]string{{"USDT", "ETH", "KNC"}, {"USDT", "KNC"}, {"USDT", "DAI", "KNC"}},
+			wantPrices: []string{"0.936", "1.1", "1.224"}},
+		{name: "Max hops", sell: true, k: 3, opts: []QueryOption{WithMaxHops(1)},
+			wantRoutes: [][]string{{"KNC", "USDT"}},
+			wantPrices: []string{"0.9"}},
+		{name: "Exact quote", sell: true, k: 3, opts: []QueryOption{WithExactQuote()},
+			wantRoutes: [][]string{{"KNC", "USDT"}, {"KNC", "ETH", "USDT"}, {"KNC", "DAI", "USDT"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := g.BestAskRoute
+			if tt.sell {
+				query = g.BestBidRoute
+			}
+			best, err := query("KNC", "USDT", d("100"), append(tt.opts, WithTopRoutes(tt.k))...)
+			if err != nil {
+				t.Fatalf("top routes error = %v", err)
+			}
+			results := append([]RouteResult{best}, best.Alternatives...)
+			if len(results) != len(tt.wantRoutes) {
+				t.Fatalf("got %d routes, want %d", len(results), len(tt.wantRoutes))
+			}
+			for i, result := range results {
+				if !slices.Equal(result.Route, tt.wantRoutes[i]) {
+					t.Errorf("routes[%d] = %v, want %v", i, result.Route, tt.wantRoutes[i])
+				}
+				if tt.wantPrices != nil && !result.Price.Equal(d(tt.wantPrices[i])) {
+					t.Errorf("prices[%d] = %s, want %s", i, result.Price, tt.wantPrices[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGraph_TopRoutes_FirstMatchesBest(t *testing.T) {
+	g := newTopKTestGraph()
+
+	best, err := g.BestAskRoute("KNC", "USDT", d("100"))
+	if err != nil {
+		t.Fatalf("BestAskRoute() error = %v", err)
+	}
+	top, err := g.BestAskRoute("KNC", "USDT", d("100"), WithTopRoutes(2))
+	if err != nil {
+		t.Fatalf("BestAskRoute(WithTopRoutes(2)) error = %v", err)
+	}
+	if !slices.Equal(top.Route, best.Route) || !top.Price.Equal(best.Price) || len(top.Alternatives) != 1 {
+		t.Errorf("first route = %v %s with %d alternatives, want %v %s with 1",
+			top.Route, top.Price, len(top.Alternatives), best.Route, best.Price)
+	}
+
+	if _, err := g.BestBidRoute("KNC", "USDT", d("100"), WithTopRoutes(0)); !errors.Is(err, ErrInvalidRouteCount) {
+		t.Errorf("BestBidRoute(WithTopRoutes(0)) error = %v, want ErrInvalidRouteCount", err)
+	}
+	if _, err := g.BestBidRoute("KNC", "BTC", d("100"), WithTopRoutes(2)); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("BestBidRoute(no route, WithTopRoutes(2)) error = %v, want ErrNoRoute", err)
+	}
+}
+
+// inflatingFinder tìm đường giống RouteFinder ở lần gọi đầu tiên, các lần sau
+// tìm route không dùng pair và báo sai lượng token ở quote.
+type inflatingFinder struct {
+	RouteFinder
+	pair  PairKey
+	count *int
+}
+
+func (f inflatingFinder) find(ctx context.Context, g *graph, base, quote string,
+	amount decimal.Decimal, sell bool, maxHops int) (
+	map[string]decimal.Decimal, map[string]Edge, error) {
+	*f.count++
+	if *f.count == 1 {
+		return f.RouteFinder.find(ctx, g, base, quote, amount, sell, maxHops)
+	}
+	values, prevs, err := f.RouteFinder.find(ctx, g.view(QueryOptions{ExcludedPairs: []PairKey{f.pair}}),
+		base, quote, amount, sell, maxHops)
+	if value, ok := values[quote]; ok {
+		values[quote] = value.Add(d("1000"))
+	}
+	return values, prevs, err
+}
+
+func TestGraph_TopRoutes_ValidatesSpur(t *testing.T) {
+	g := newTopKTestGraph()
+	count := 0
+	kncETH := PairKey{From: "KNC", To: "ETH"}
+	finder := inflatingFinder{RouteFinder: BellmanFord(), pair: kncETH, count: &count}
+
+	// Đoạn spur qua DAI báo sai giá nên được tìm lại, route qua ETH tốt hơn
+	best, err := g.BestBidRoute("KNC", "USDT", d("100"), WithFinder(finder), WithTopRoutes(2))
+	if err != nil {
+		t.Fatalf("BestBidRoute(WithTopRoutes(2)) error = %v", err)
+	}
+	if len(best.Alternatives) != 1 || !slices.Equal(best.Alternatives[0].Route, []string{"KNC", "ETH", "USDT"}) ||
+		!best.Alternatives[0].Price.Equal(d("0.84")) {
+		t.Errorf("Alternatives = %v, want KNC -> ETH -> USDT at 0.84", best.Alternatives)
+	}
+}
@@ -0,0 +1,152 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+// ErrInvalidRoute nghĩa là route do thuật toán tìm đường trả về không hợp lệ
+// và không sửa được, xem RouteValidationError.
+var ErrInvalidRoute = errors.New("invalid route")
+
+// RouteValidationError cho biết route do thuật toán tìm đường trả về không
+// hợp lệ.
+//   - Route: các token truy vết được từ kết quả tìm đường, có thể không bắt
+//     đầu từ base nếu truy vết bị đứt
+//   - Reason: lý do route không hợp lệ
+//   - Reported: lượng token ở cuối route mà thuật toán báo cáo
+//   - Replayed: lượng token ở cuối route khi mô phỏng lại từng chặng, 0 nếu
+//     không mô phỏng lại được
+//
+// errors.Is(err, ErrInvalidRoute) trả về true với lỗi này.
+type RouteValidationError struct {
+	Route    []string
+	Reason   string
+	Reported decimal.Decimal
+	Replayed decimal.Decimal
+}
+
+func (e *RouteValidationError) Error() string {
+	return fmt.Sprintf("%s %s: %s (reported %s, replayed %s)", ErrInvalidRoute,
+		strings.Join(e.Route, "->"), e.Reason, e.Reported, e.Replayed)
+}
+
+// Is cho phép so sánh errors.Is(err, ErrInvalidRoute).
+func (e *RouteValidationError) Is(target error) bool {
+	return target == ErrInvalidRoute
+}
+
+// validatedEdges truy vết route từ base tới quote trong kết quả tìm đường và
+// kiểm tra route:
+//   - là đường đi đơn từ base tới quote qua các cạnh nối tiếp nhau
+//   - mô phỏng lại được qua từng cạnh với amount
+//   - lượng token ở quote khi mô phỏng lại đúng bằng result.values[quote]
+//
+// Với cạnh có giá phụ thuộc amount, Bellman-Ford có thể ghi đè prevs của một
+// token sau khi các token phía sau đã được cập nhật, nên đường đi truy vết
+// được có thể bị đứt, có chu trình hoặc không cho lại đúng giá đã báo cáo.
+// Khi đó route được sửa:
+//   - tìm lại bằng simplePathSearch, thuật toán giữ nguyên cả đường đi cho
+//     mỗi token nên luôn mô phỏng lại đúng
+//   - nếu route ban đầu chỉ sai giá, route nào tốt hơn khi mô phỏng lại được
+//     chọn, giá của route ban đầu là giá mô phỏng lại
+//
+// result được thay bằng kết quả đã sửa với repaired = true. Không sửa được
+// thì trả về *RouteValidationError. Route có options.RequiredTokens không được
+// tìm lại vì simplePathSearch không đảm bảo đi qua các token này.
+//
+// Kết quả partial chỉ được kiểm tra đường đi, không kiểm tra giá, vì thuật
+// toán bị dừng giữa chừng.
+func (g *graph) validatedEdges(ctx context.Context, base, quote string,
+	amount decimal.Decimal, sell bool, options QueryOptions, result *searchResult) (
+	[]Edge, error) {
+	edges, replayed, err := checkRoute(base, quote, amount, sell, *result)
+	if err == nil {
+		return edges, nil
+	}
+
+	if len(requiredStops(base, quote, options.RequiredTokens)) == 0 {
+		values, prevs, searchErr := g.simplePathSearch(ctx, base, quote, amount, sell,
+			options.MaxHops)
+		if searchErr == nil {
+			fixed := *result
+			fixed.values, fixed.prevs, fixed.partial, fixed.repaired = values, prevs, false, true
+			fixedEdges, _, fixedErr := checkRoute(base, quote, amount, sell, fixed)
+			value := values[quote]
+			if fixedErr == nil && (edges == nil || (sell && value.GreaterThan(replayed)) ||
+				(!sell && value.LessThan(replayed))) {
+				*result = fixed
+				return fixedEdges, nil
+			}
+		}
+	}
+	if edges == nil {
+		return nil, err
+	}
+
+	// Route ban đầu chỉ sai giá
+	result.values = maps.Clone(result.values)
+	result.values[quote] = replayed
+	result.repaired = true
+	return edges, nil
+}
+
+// checkRoute kiểm tra route của result, xem validatedEdges. Nếu route là đường
+// đi đơn mô phỏng lại được nhưng sai giá, các cạnh và lượng token mô phỏng lại
+// được trả về cùng lỗi.
+func checkRoute(base, quote string, amount decimal.Decimal, sell bool,
+	result searchResult) ([]Edge, decimal.Decimal, error) {
+	reported := result.values[quote]
+	invalid := func(path []string, reason string, replayed decimal.Decimal) error {
+		return &RouteValidationError{Route: path, Reason: reason,
+			Reported: reported, Replayed: replayed}
+	}
+
+	edges, ok := getEdges(result.prevs, base, quote)
+	if !ok {
+		return nil, decimal.Zero, invalid(getPath(result.prevs, base, quote),
+			"predecessors do not lead back to base", decimal.Zero)
+	}
+	path := edgesPath(base, edges)
+	if reason := simplePathError(edges, base, quote); reason != "" {
+		return nil, decimal.Zero, invalid(path, reason, decimal.Zero)
+	}
+
+	_, replayed, ok := replay(edges, amount, sell)
+	if !ok {
+		return nil, decimal.Zero, invalid(path, "route cannot be replayed", decimal.Zero)
+	}
+	if !result.partial && !replayed.Equal(reported) {
+		return edges, replayed, invalid(path, "replay does not match reported amount", replayed)
+	}
+	return edges, replayed, nil
+}
+
+// simplePathError trả về lý do edges không phải đường đi đơn từ base tới
+// quote, rỗng nếu hợp lệ.
+func simplePathError(edges []Edge, base, quote string) string {
+	if len(edges) == 0 {
+		return "route has no hops"
+	}
+	visited := map[string]bool{base: true}
+	current := base
+	for _, edge := range edges {
+		if edge.From() != current {
+			return "hops are not connected"
+		}
+		if visited[edge.To()] {
+			return "route visits " + edge.To() + " twice"
+		}
+		visited[edge.To()] = true
+		current = edge.To()
+	}
+	if current != quote {
+		return "route does not end at quote"
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nkngn/kyber-homework/internal/decimal"
+)
+
+func TestGraph_ValidatedEdges(t *testing.T) {
+	kncUSDT := SimpleEdge{BaseToken: "KNC", QuoteToken: "USDT", BidPrice: d("0.9"), AskPrice: d("1.1")}
+	kncETH := SimpleEdge{BaseToken: "KNC", QuoteToken: "ETH", BidPrice: d("0.0024"), AskPrice: d("0.0026")}
+	ethUSDT := SimpleEdge{BaseToken: "ETH", QuoteToken: "USDT", BidPrice: d("350"), AskPrice: d("360")}
+	g := &graph{}
+	for _, edge := range []SimpleEdge{kncUSDT, kncETH, ethUSDT} {
+		g.AddEdge(edge)
+		g.AddEdge(edge.GetReverseEdge())
+	}
+
+	values := func(usdt string) map[string]decimal.Decimal {
+		return map[string]decimal.Decimal{"KNC": d("100"), "USDT": d(usdt)}
+	}
+	tests := []struct {
+		name         string
+		result       searchResult
+		options      QueryOptions
+		wantRoute    []string
+		wantValue    string
+		wantRepaired bool
+		wantReason   string
+	}{
+		{name: "Valid",
+			result:    searchResult{values: values("90"), prevs: map[string]Edge{"USDT": kncUSDT}},
+			wantRoute: []string{"KNC", "USDT"}, wantValue: "90"},
+		{name: "Price mismatch keeps replayed price",
+			result:    searchResult{values: values("95"), prevs: map[string]Edge{"USDT": kncUSDT}},
+			wantRoute: []string{"KNC", "USDT"}, wantValue: "90", wantRepaired: true},
+		{name: "Price mismatch repaired by better route",
+			result: searchResult{values: values("95"),
+				prevs: map[string]Edge{"USDT": ethUSDT, "ETH": kncETH}},
+			wantRoute: []string{"KNC", "USDT"}, wantValue: "90", wantRepaired: true},
+		{name: "Broken predecessors repaired",
+			result:    searchResult{values: values("90"), prevs: map[string]Edge{"USDT": ethUSDT}},
+			wantRoute: []string{"KNC", "USDT"}, wantValue: "90", wantRepaired: true},
+		{name: "Cyclic predecessors repaired",
+			result: searchResult{values: values("90"),
+				prevs: map[string]Edge{"USDT": ethUSDT, "ETH": ethUSDT.GetReverseEdge()}},
+			wantRoute: []string{"KNC", "USDT"}, wantValue: "90", wantRepaired: true},
+		{name: "Broken predecessors with required tokens",
+			result:     searchResult{values: values("90"), prevs: map[string]Edge{"USDT": ethUSDT}},
+			options:    QueryOptions{RequiredTokens: []string{"ETH"}},
+			wantReason: "predecessors do not lead back to base"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result
+			edges, err := g.validatedEdges(context.Background(), "KNC", "USDT", d("100"),
+				true, tt.options, &result)
+			if tt.wantReason != "" {
+				var validationErr *RouteValidationError
+				if !errors.As(err, &validationErr) || !errors.Is(err, ErrInvalidRoute) ||
+					validationErr.Reason != tt.wantReason {
+					t.Fatalf("validatedEdges() error = %v, want reason %q", err, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validatedEdges() error = %v", err)
+			}
+			if got := edgesPath("KNC", edges); !slices.Equal(got, tt.wantRoute) {
+				t.Errorf("route = %v, want %v", got, tt.wantRoute)
+			}
+			if !result.values["USDT"].Equal(d(tt.wantValue)) || result.repaired != tt.wantRepaired {
+				t.Errorf("value = %s, repaired = %v, want %s, %v",
+					result.values["USDT"], result.repaired, tt.wantValue, tt.wantRepaired)
+			}
+		})
+	}
+}
+
+func TestSimplePathError(t *testing.T) {
+	ab := SimpleEdge{BaseToken: "A", QuoteToken: "B"}
+	bc := SimpleEdge{BaseToken: "B", QuoteToken: "C"}
+	ba := SimpleEdge{BaseToken: "B", QuoteToken: "A"}
+
+	tests := []struct {
+		name  string
+		edges []Edge
+		want  string
+	}{
+		{name: "Valid", edges: []Edge{ab, bc}, want: ""},
+		{name: "Empty", edges: nil, want: "route has no hops"},
+		{name: "Disconnected", edges: []Edge{ab, ab}, want: "hops are not connected"},
+		{name: "Revisits base", edges: []Edge{ab, ba}, want: "route visits A twice"},
+		{name: "Wrong end", edges: []Edge{ab}, want: "route does not end at quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := simplePathError(tt.edges, "A", "C"); got != tt.want {
+				t.Errorf("simplePathError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}